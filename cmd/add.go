@@ -4,68 +4,187 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/busyrockin/api-vault/core"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var addCmd = &cobra.Command{
 	Use:   "add <name>",
 	Short: "Store a new API credential",
-	Long:  "Store a credential with --secret and/or --public key, plus optional --url and --env.",
-	Args:  cobra.ExactArgs(1),
+	Long: `Store a credential with --secret (or --secret-file, or --stdin) and/or
+--public key, plus optional --url and --tag (both repeatable), --env, and an
+expiry (--expires 2025-12-31 or --ttl 90d). --rotate-every 90d sets a
+rotation schedule; see 'audit --rotation-due' and 'rotate --due'.
+
+--secret leaves the key in shell history. In scripts, pipe it in instead,
+with the master password in API_VAULT_PASSWORD:
+
+  echo -n "$KEY" | api-vault add openai --stdin
+
+A single trailing newline is trimmed from --stdin and --secret-file input.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		name := args[0]
 		apiType, _ := cmd.Flags().GetString("type")
 		secret, _ := cmd.Flags().GetString("secret")
 		public, _ := cmd.Flags().GetString("public")
-		url, _ := cmd.Flags().GetString("url")
+		urls, _ := cmd.Flags().GetStringArray("url")
 		env, _ := cmd.Flags().GetString("env")
+		tags, _ := cmd.Flags().GetStringArray("tag")
+		secretFile, _ := cmd.Flags().GetString("secret-file")
+		stdin, _ := cmd.Flags().GetBool("stdin")
+		icon, _ := cmd.Flags().GetString("icon")
+		extra, _ := cmd.Flags().GetBool("extra-passphrase")
+		upsert, _ := cmd.Flags().GetBool("upsert")
+		expires, _ := cmd.Flags().GetString("expires")
+		ttl, _ := cmd.Flags().GetString("ttl")
+		rotateEvery, _ := cmd.Flags().GetString("rotate-every")
+		maxSize := cfg.MaxSecretSize
+		if apiType == "" {
+			apiType = cfg.DefaultType
+		}
+
+		switch {
+		case secretFile != "":
+			s, err := readSecretFile(secretFile, maxSize)
+			if err != nil {
+				return err
+			}
+			secret = s
+		case stdin:
+			// The master password prompt would read the same pipe.
+			if os.Getenv("API_VAULT_PASSWORD") == "" && !term.IsTerminal(int(os.Stdin.Fd())) {
+				return fmt.Errorf("--stdin needs the master password in API_VAULT_PASSWORD")
+			}
+			s, err := readSecretStdin(maxSize)
+			if err != nil {
+				return err
+			}
+			if s == "" {
+				return fmt.Errorf("--stdin: no secret on standard input")
+			}
+			secret = s
+		}
 
 		if secret == "" && public == "" {
-			return fmt.Errorf("at least one of --secret or --public is required")
+			return fmt.Errorf("at least one of --secret, --secret-file, --stdin or --public is required")
 		}
 
-		cred := &core.Credential{Name: name, APIType: apiType}
+		cred := &core.Credential{Name: name, APIType: apiType, Tags: tags}
 		if secret != "" {
 			cred.SecretKey = &secret
 		}
 		if public != "" {
 			cred.PublicKey = &public
 		}
-		if url != "" {
-			cred.URL = &url
+		if len(urls) > 0 {
+			cred.URL = &urls[0]
+			cred.URLs = urls
 		}
 		if env != "" {
 			cred.Environment = &env
 		}
+		if expires != "" || ttl != "" {
+			t, err := expiryFromFlags(expires, ttl, time.Now())
+			if err != nil {
+				return err
+			}
+			cred.ExpiresAt = &t
+		}
+		if rotateEvery != "" {
+			d, err := core.ParseTTL(rotateEvery)
+			if err != nil {
+				return fmt.Errorf("--rotate-every: %w", err)
+			}
+			cred.RotationInterval = &d
+		}
+		if icon != "" {
+			cred.Config = map[string]string{"icon": icon}
+		}
+		if extra {
+			if secret == "" {
+				return fmt.Errorf("--extra-passphrase protects the secret; pass --secret, --secret-file or --stdin")
+			}
+			p, err := readCredentialPassphrase(name, true)
+			if err != nil {
+				return err
+			}
+			cred.Passphrase = p
+		}
 
 		db, err := openVault()
 		if err != nil {
 			return err
 		}
 		defer db.Close()
+		db.SetMaxSecretSize(maxSize)
 
-		if err := db.AddCredentialV2(cred); err != nil {
-			if errors.Is(err, core.ErrDuplicate) {
-				return fmt.Errorf("credential %q already exists", name)
+		if upsert {
+			created, err := db.AddOrUpdateCredentialV2(cred)
+			if err != nil {
+				return fmt.Errorf("add credential: %w", err)
+			}
+			if created {
+				fmt.Fprintf(os.Stderr, "Stored credential %q\n", name)
+			} else {
+				fmt.Fprintf(os.Stderr, "Updated credential %q\n", name)
+			}
+		} else {
+			if err := db.AddCredentialV2(cred); err != nil {
+				if errors.Is(err, core.ErrDuplicate) {
+					return userErr(err, "credential %q already exists (use --upsert to replace it)", name)
+				}
+				return fmt.Errorf("add credential: %w", err)
 			}
-			return fmt.Errorf("add credential: %w", err)
+			fmt.Fprintf(os.Stderr, "Stored credential %q\n", name)
 		}
-
-		fmt.Fprintf(os.Stderr, "Stored credential %q\n", name)
-		if secret != "" {
+		if cmd.Flags().Changed("secret") {
 			fmt.Fprintln(os.Stderr, "Warning: secret may be visible in shell history")
 		}
 		return nil
 	},
 }
 
+// expiryFromFlags resolves --expires (a date, or RFC 3339 time) or --ttl
+// (from now) to an expiry time. A bare date means the start of that day,
+// local time.
+func expiryFromFlags(expires, ttl string, now time.Time) (time.Time, error) {
+	if ttl != "" {
+		d, err := core.ParseTTL(ttl)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("--ttl: %w", err)
+		}
+		return now.Add(d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", expires, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, expires)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--expires must be a date (2025-12-31) or RFC 3339 time, got %q", expires)
+	}
+	return t, nil
+}
+
 func init() {
 	addCmd.Flags().StringP("type", "t", "", "API type (e.g., openai, supabase, github)")
 	addCmd.Flags().String("secret", "", "Secret/private API key")
+	addCmd.Flags().String("secret-file", "", "Read the secret key from a file")
+	addCmd.Flags().Bool("stdin", false, "Read the secret key from standard input")
 	addCmd.Flags().String("public", "", "Public/anon key")
-	addCmd.Flags().String("url", "", "Service URL")
+	addCmd.Flags().StringArray("url", nil, "Service URL (repeat for multiple endpoints; the first is primary)")
 	addCmd.Flags().StringP("env", "e", "", "Environment (e.g., prod, staging)")
+	addCmd.Flags().StringArray("tag", nil, "Tag the credential (repeatable; with --upsert, replaces its tags)")
+	addCmd.Flags().String("icon", "", "Icon shown in interactive mode (default: by api type)")
+	addCmd.Flags().Bool("extra-passphrase", false, "Also protect the secret with its own passphrase (prompted, or API_VAULT_EXTRA_PASSPHRASE)")
+	addCmd.Flags().Bool("upsert", false, "Replace the credential if it exists, keeping its history, expiry and schedule unless given")
+	addCmd.Flags().String("expires", "", "Expiry date (2025-12-31) or RFC 3339 time")
+	addCmd.Flags().String("ttl", "", "Expire after this long, e.g. 90d, 2w or 36h")
+	addCmd.Flags().String("rotate-every", "", "Rotation schedule, e.g. 90d; counted from the last rotation")
+	addCmd.MarkFlagsMutuallyExclusive("secret", "secret-file", "stdin")
+	addCmd.MarkFlagsMutuallyExclusive("expires", "ttl")
 	rootCmd.AddCommand(addCmd)
 }
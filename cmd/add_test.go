@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/busyrockin/api-vault/core"
+)
+
+// withStdin runs fn with standard input reading input.
+func withStdin(t *testing.T, input string, fn func()) {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(input); err != nil {
+		t.Fatal(err)
+	}
+	f.Seek(0, 0)
+	stdin := os.Stdin
+	os.Stdin = f
+	defer func() { os.Stdin = stdin }()
+	fn()
+}
+
+func TestAddSecretSources(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vault.db")
+	db, err := core.NewDatabase(path, "test-password")
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	t.Setenv("API_VAULT_PASSWORD", "test-password")
+	add := func(args ...string) error {
+		_, err := runCLI(t, append(append([]string{"add"}, args...), "--vault-path", path, "--insecure-permissions")...)
+		return err
+	}
+	secret := func(name string) string {
+		t.Helper()
+		v, err := db.GetCredential(name)
+		if err != nil {
+			t.Fatalf("GetCredential(%s): %v", name, err)
+		}
+		return v
+	}
+
+	// One trailing newline is trimmed; anything else is kept.
+	withStdin(t, "sk-piped \n", func() {
+		if err := add("piped", "--stdin"); err != nil {
+			t.Fatalf("add --stdin: %v", err)
+		}
+	})
+	if got := secret("piped"); got != "sk-piped " {
+		t.Errorf("--stdin stored %q", got)
+	}
+
+	file := filepath.Join(dir, "key.txt")
+	os.WriteFile(file, []byte("sk-from-file\r\n"), 0600)
+	if err := add("filed", "--secret-file", file); err != nil {
+		t.Fatalf("add --secret-file: %v", err)
+	}
+	if got := secret("filed"); got != "sk-from-file" {
+		t.Errorf("--secret-file stored %q", got)
+	}
+
+	withStdin(t, "", func() {
+		if err := add("empty", "--stdin"); err == nil || !strings.Contains(err.Error(), "no secret") {
+			t.Errorf("empty stdin: %v", err)
+		}
+	})
+	if err := add("missing", "--secret-file", filepath.Join(dir, "none")); err == nil {
+		t.Error("a missing --secret-file should fail")
+	}
+	os.WriteFile(file, []byte(strings.Repeat("x", 64)), 0600)
+	if err := add("big", "--secret-file", file, "--max-secret-size", "16"); !errors.Is(err, core.ErrTooLarge) {
+		t.Errorf("oversized --secret-file: %v, want ErrTooLarge", err)
+	}
+	if err := add("both", "--secret", "sk", "--secret-file", file); err == nil {
+		t.Error("--secret with --secret-file should be refused")
+	}
+	for _, name := range []string{"empty", "missing", "big", "both"} {
+		if _, err := db.GetCredential(name); !errors.Is(err, core.ErrNotFound) {
+			t.Errorf("%s was stored: %v", name, err)
+		}
+	}
+
+	// --stdin can't share the pipe with the password prompt.
+	t.Setenv("API_VAULT_PASSWORD", "")
+	withStdin(t, "test-password\nsk\n", func() {
+		if err := add("prompted", "--stdin"); err == nil || !strings.Contains(err.Error(), "API_VAULT_PASSWORD") {
+			t.Errorf("--stdin without API_VAULT_PASSWORD: %v", err)
+		}
+	})
+}
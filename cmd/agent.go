@@ -0,0 +1,369 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run an agent that keeps the vault open for other invocations",
+	Long: `Open the vault once and serve reads over HTTP on a Unix socket, so
+other api-vault processes don't each ask for the master password.
+
+The socket (default agent.sock in the vault directory) is created with mode
+0600. Every request must carry the agent's token as "Authorization: Bearer
+<token>"; the token is generated at startup and written to <socket>.token,
+also 0600, where the CLI picks it up. API_VAULT_AGENT_TOKEN overrides the
+file for clients that can't read it. Requests must also name the vault they
+expect in X-API-Vault-Path, as an absolute path; any other is refused.
+
+Clients look for the agent at API_VAULT_AGENT_SOCKET or the default path
+only, so an agent started with --socket elsewhere also needs
+API_VAULT_AGENT_SOCKET set to that path wherever it is used.
+
+While an agent serves the selected vault, 'get' asks it instead of opening
+the vault (--no-agent or API_VAULT_NO_AGENT=1 to skip it). Passphrase-
+protected credentials are still read locally.
+
+  GET /v1/credentials          names, types and environments
+  GET /v1/credentials/{name}   {"secret","public","url"}
+
+The agent exits after --idle-timeout without a request (default
+idle_timeout_seconds from config.json or API_VAULT_IDLE_TIMEOUT, else 30m;
+0 to never), or on SIGINT/SIGTERM, removing the socket and token file.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		socket, _ := cmd.Flags().GetString("socket")
+		idle, _ := cmd.Flags().GetDuration("idle-timeout")
+		if !cmd.Flags().Changed("idle-timeout") {
+			idle = time.Duration(cfg.IdleTimeout) * time.Second
+		}
+		if socket == "" {
+			socket = agentSocket()
+		}
+
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		vault, err := filepath.Abs(vaultPath)
+		if err != nil {
+			return err
+		}
+		ln, token, err := listenAgent(socket)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(socket + ".token")
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		a := &agent{db: db, vault: vault, token: token}
+		fmt.Fprintf(os.Stderr, "Agent listening on %s (Ctrl-C to stop)\n", socket)
+		if err := a.serve(ctx, ln, idle); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Agent stopped")
+		return nil
+	},
+}
+
+// agentSocket is the agent's socket path: API_VAULT_AGENT_SOCKET, or
+// agent.sock in the vault directory.
+func agentSocket() string {
+	return agentSocketIn(vaultDir)
+}
+
+// agentSocketIn is agentSocket for the vault directory dir, for callers
+// that run before root has resolved vaultDir.
+func agentSocketIn(dir string) string {
+	if s := os.Getenv("API_VAULT_AGENT_SOCKET"); s != "" {
+		return s
+	}
+	return filepath.Join(dir, "agent.sock")
+}
+
+// listenAgent listens on socket with mode 0600 and writes a fresh token to
+// socket+".token". A stale socket left by a crashed agent is replaced; a
+// live one is an error.
+func listenAgent(socket string) (net.Listener, string, error) {
+	if _, err := os.Stat(socket); err == nil {
+		if c, err := net.DialTimeout("unix", socket, time.Second); err == nil {
+			c.Close()
+			return nil, "", fmt.Errorf("an agent is already listening on %s", socket)
+		}
+		if err := os.Remove(socket); err != nil {
+			return nil, "", fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", err
+	}
+	token := hex.EncodeToString(buf)
+	if err := os.WriteFile(socket+".token", []byte(token+"\n"), 0600); err != nil {
+		return nil, "", fmt.Errorf("write token: %w", err)
+	}
+
+	// Created under umask 0177, the socket is 0600 from the moment it
+	// exists, even outside a private directory.
+	var ln net.Listener
+	err := withUmask(0177, func() (err error) {
+		ln, err = net.Listen("unix", socket)
+		return err
+	})
+	if err != nil {
+		os.Remove(socket + ".token")
+		return nil, "", err
+	}
+	return ln, token, nil
+}
+
+// agent serves one open vault to token holders.
+type agent struct {
+	db      *core.Database
+	vault   string // absolute path; requests for another vault are refused
+	token   string
+	lastUse atomic.Int64
+}
+
+// agentVaultHeader carries the client's absolute vault path, so a CLI
+// pointed at another vault doesn't get this one's secrets.
+const agentVaultHeader = "X-API-Vault-Path"
+
+// serve answers requests on ln until ctx is done or idle passes without a
+// request, then shuts the server down. Closing ln removes a Unix socket.
+func (a *agent) serve(ctx context.Context, ln net.Listener, idle time.Duration) error {
+	srv := &http.Server{Handler: a.handler(), ReadHeaderTimeout: 5 * time.Second}
+	a.lastUse.Store(time.Now().UnixNano())
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		var tick <-chan time.Time
+		if idle > 0 {
+			t := time.NewTicker(min(idle/4, time.Minute))
+			defer t.Stop()
+			tick = t.C
+		}
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+			case <-tick:
+				if time.Since(time.Unix(0, a.lastUse.Load())) < idle {
+					continue
+				}
+				fmt.Fprintf(os.Stderr, "Idle for %s, shutting down\n", idle)
+			}
+			shut, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			srv.Shutdown(shut)
+			cancel()
+			return
+		}
+	}()
+
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (a *agent) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/credentials", func(w http.ResponseWriter, r *http.Request) {
+		creds, err := a.db.ListCredentials()
+		if err != nil {
+			agentError(w, err)
+			return
+		}
+		type entry struct {
+			Name        string  `json:"name"`
+			APIType     string  `json:"api_type"`
+			Environment *string `json:"environment,omitempty"`
+		}
+		out := make([]entry, 0, len(creds))
+		for _, c := range creds {
+			out = append(out, entry{c.Name, c.APIType, c.Environment})
+		}
+		json.NewEncoder(w).Encode(out)
+	})
+	mux.HandleFunc("GET /v1/credentials/{name}", func(w http.ResponseWriter, r *http.Request) {
+		c, err := a.db.GetCredentialV2(r.PathValue("name"))
+		if err != nil {
+			agentError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(agentCredential{c.SecretKey, c.PublicKey, c.URL})
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.lastUse.Store(time.Now().UnixNano())
+		w.Header().Set("Content-Type", "application/json")
+		got, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(a.token)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(agentErrorBody{"invalid or missing agent token", "unauthorized"})
+			return
+		}
+		switch v := r.Header.Get(agentVaultHeader); v {
+		case a.vault:
+		case "":
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(agentErrorBody{"missing " + agentVaultHeader + " header", "other_vault"})
+			return
+		default:
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(agentErrorBody{"agent serves " + a.vault, "other_vault"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// agentCredential is the body of GET /v1/credentials/{name}; get --json
+// prints the same object.
+type agentCredential struct {
+	Secret *string `json:"secret,omitempty"`
+	Public *string `json:"public,omitempty"`
+	URL    *string `json:"url,omitempty"`
+}
+
+type agentErrorBody struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// agentError reports err with its errorCodes code, so the client can
+// rebuild the sentinel (and exit code) on its side.
+func agentError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, core.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, core.ErrDisabled), errors.Is(err, core.ErrPassphraseRequired):
+		status = http.StatusForbidden
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(agentErrorBody{err.Error(), errorCode(err)})
+}
+
+// agentGet asks the agent on socket for name. ok is false when no agent
+// answers for vault, the token is refused, or the credential needs its
+// passphrase; the caller then opens the vault itself. Otherwise err
+// matches the agent's sentinel under errors.Is.
+func agentGet(socket, token, vault, name string) (c *agentCredential, ok bool, err error) {
+	c = &agentCredential{}
+	ok, err = agentFetch(socket, token, vault, "/v1/credentials/"+url.PathEscape(name), c)
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	return c, true, nil
+}
+
+// agentNames lists vault's credential names through the agent on socket.
+// ok is false when no agent answers for vault or it refuses the request.
+func agentNames(socket, token, vault string) (names []string, ok bool, err error) {
+	var entries []struct {
+		Name string `json:"name"`
+	}
+	ok, err = agentFetch(socket, token, vault, "/v1/credentials", &entries)
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	return names, true, nil
+}
+
+// agentFetch decodes the agent's answer to GET path into out, with agentGet's
+// ok and err.
+func agentFetch(socket, token, vault, path string, out any) (ok bool, err error) {
+	if token == "" {
+		return false, nil
+	}
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				d := net.Dialer{Timeout: time.Second}
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	req, err := http.NewRequest(http.MethodGet, "http://agent"+path, nil)
+	if err != nil {
+		return false, nil
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(agentVaultHeader, vault)
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return true, fmt.Errorf("agent: %w", err)
+		}
+		return true, nil
+	}
+	var body agentErrorBody
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return true, fmt.Errorf("agent: %s", resp.Status)
+	}
+	switch body.Code {
+	case "unauthorized", "other_vault", "passphrase_required":
+		return false, nil
+	}
+	for _, e := range errorCodes {
+		if e.code == body.Code {
+			return true, userErr(e.err, "%s", body.Error)
+		}
+	}
+	return true, fmt.Errorf("agent: %s", body.Error)
+}
+
+// agentToken is the token for the agent on socket: API_VAULT_AGENT_TOKEN,
+// or the agent's token file. "" when neither is there.
+func agentToken(socket string) string {
+	if t := os.Getenv("API_VAULT_AGENT_TOKEN"); t != "" {
+		return t
+	}
+	b, err := os.ReadFile(socket + ".token")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func init() {
+	serveCmd.Flags().String("socket", "", "Unix socket to listen on (default agent.sock in the vault directory, or API_VAULT_AGENT_SOCKET); clients need API_VAULT_AGENT_SOCKET set to it")
+	serveCmd.Flags().Duration("idle-timeout", 30*time.Minute, "Exit after this long without a request (0 to never; default from idle_timeout_seconds)")
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/busyrockin/api-vault/core"
+)
+
+func TestAgentServesGet(t *testing.T) {
+	db := tempVault(t)
+	secret, u := "sk-agent", "https://api.example.com"
+	if err := db.AddCredentialV2(&core.Credential{Name: "svc/prod", APIType: "openai", SecretKey: &secret, URL: &u}); err != nil {
+		t.Fatalf("AddCredentialV2: %v", err)
+	}
+	if err := db.AddCredentialV2(&core.Credential{Name: "off", APIType: "openai", SecretKey: &secret}); err != nil {
+		t.Fatalf("AddCredentialV2: %v", err)
+	}
+	if err := db.SetDisabled("off", true); err != nil {
+		t.Fatalf("SetDisabled: %v", err)
+	}
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	ln, token, err := listenAgent(socket)
+	if err != nil {
+		t.Fatalf("listenAgent: %v", err)
+	}
+	for _, f := range []string{socket, socket + ".token"} {
+		if fi, err := os.Stat(f); err != nil || fi.Mode().Perm() != 0600 {
+			t.Errorf("%s: mode %v, %v; want 0600", f, fi.Mode().Perm(), err)
+		}
+	}
+	if got := agentToken(socket); got != token {
+		t.Errorf("agentToken = %q, want the token file's", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- (&agent{db: db, vault: "/v.db", token: token}).serve(ctx, ln, 0) }()
+	defer func() {
+		cancel()
+		if err := <-served; err != nil {
+			t.Errorf("serve: %v", err)
+		}
+	}()
+
+	c, ok, err := agentGet(socket, token, "/v.db", "svc/prod")
+	if !ok || err != nil || c.Secret == nil || *c.Secret != secret || c.URL == nil || *c.URL != u {
+		t.Fatalf("agentGet = %+v, %v, %v", c, ok, err)
+	}
+	if _, ok, err := agentGet(socket, token, "/v.db", "missing"); !ok || !errors.Is(err, core.ErrNotFound) {
+		t.Errorf("missing: ok=%v err=%v, want ErrNotFound", ok, err)
+	}
+	if _, ok, err := agentGet(socket, token, "/v.db", "off"); !ok || !errors.Is(err, core.ErrDisabled) {
+		t.Errorf("disabled: ok=%v err=%v, want ErrDisabled", ok, err)
+	}
+
+	// The caller falls back to opening the vault itself for these.
+	if _, ok, _ := agentGet(socket, "wrong", "/v.db", "svc/prod"); ok {
+		t.Error("a wrong token should be refused")
+	}
+	if _, ok, _ := agentGet(socket, token, "/other.db", "svc/prod"); ok {
+		t.Error("a request for another vault should be refused")
+	}
+	if _, ok, _ := agentGet(socket, token, "", "svc/prod"); ok {
+		t.Error("a request without a vault path should be refused")
+	}
+	if _, ok, _ := agentGet(filepath.Join(t.TempDir(), "none.sock"), token, "/v.db", "svc/prod"); ok {
+		t.Error("no agent should mean ok=false")
+	}
+
+	if _, _, err := listenAgent(socket); err == nil {
+		t.Error("listenAgent over a live agent should fail")
+	}
+}
+
+func TestAgentIdleTimeout(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	ln, token, err := listenAgent(socket)
+	if err != nil {
+		t.Fatalf("listenAgent: %v", err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- (&agent{db: tempVault(t), vault: "/v.db", token: token}).serve(context.Background(), ln, 100*time.Millisecond)
+	}()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("serve: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent didn't stop when idle")
+	}
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Errorf("socket left behind: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+var attestCmd = &cobra.Command{
+	Use:   "attest",
+	Short: "Produce or check a signed statement of the vault's contents",
+	Long: `Write a signed JSON attestation listing each credential's name, type,
+last rotation and a keyed hash of its secret (never the secret itself),
+for compliance evidence.
+
+It is signed with the vault's own Ed25519 attestation key, created on first
+use and kept inside the vault; changing the master password doesn't change
+it. Give auditors the key from --public-key once.
+
+  api-vault attest -f attest.json          write an attestation
+  api-vault attest --verify attest.json    check its signature and list what
+                                           changed in the vault since
+  api-vault attest --verify attest.json --key <base64>
+                                           check the signature only, without
+                                           the vault`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out, _ := cmd.Flags().GetString("file")
+		verify, _ := cmd.Flags().GetString("verify")
+		keyB64, _ := cmd.Flags().GetString("key")
+		showKey, _ := cmd.Flags().GetBool("public-key")
+
+		if verify != "" && keyB64 != "" {
+			pub, err := base64.StdEncoding.DecodeString(keyB64)
+			if err != nil || len(pub) != ed25519.PublicKeySize {
+				return fmt.Errorf("--key must be a base64 Ed25519 public key")
+			}
+			att, err := readAttestation(verify, pub)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "✓ Signature valid: %d credentials as of %s\n",
+				len(att.Credentials), att.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+			return nil
+		}
+
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		switch {
+		case showKey:
+			pub, err := db.AttestationPublicKey()
+			if err != nil {
+				return fmt.Errorf("attest: %w", err)
+			}
+			fmt.Println(base64.StdEncoding.EncodeToString(pub))
+			return nil
+		case verify != "":
+			return verifyAgainstVault(db, verify)
+		}
+
+		data, err := db.Attest()
+		if err != nil {
+			return fmt.Errorf("attest: %w", err)
+		}
+		data = append(data, '\n')
+		if out == "" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(out, data, 0600); err != nil {
+			return fmt.Errorf("attest: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Attestation written to %s\n", out)
+		return nil
+	},
+}
+
+// readAttestation reads and verifies the attestation at path, signed by pub.
+func readAttestation(path string, pub ed25519.PublicKey) (*core.Attestation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("verify: %w", err)
+	}
+	att, err := core.VerifyAttestation(data, pub)
+	if err != nil {
+		return nil, fmt.Errorf("verify %s: %w", path, err)
+	}
+	return att, nil
+}
+
+// verifyAgainstVault checks the attestation at path with the vault's key
+// and reports every change since it was made. Any change is an error, so
+// scripts can gate on the exit status.
+func verifyAgainstVault(db *core.Database, path string) error {
+	pub, err := db.AttestationPublicKey()
+	if err != nil {
+		return fmt.Errorf("verify: %w", err)
+	}
+	old, err := readAttestation(path, pub)
+	if err != nil {
+		return err
+	}
+	data, err := db.Attest()
+	if err != nil {
+		return fmt.Errorf("verify: %w", err)
+	}
+	cur, err := core.VerifyAttestation(data, pub)
+	if err != nil {
+		return fmt.Errorf("verify: %w", err)
+	}
+
+	changes := core.CompareAttestations(old, cur)
+	since := old.CreatedAt.Format("2006-01-02 15:04:05 MST")
+	if len(changes) == 0 {
+		fmt.Fprintf(os.Stderr, "✓ Signature valid; no changes since %s\n", since)
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "✓ Signature valid; changes since %s:\n", since)
+	for _, c := range changes {
+		fmt.Fprintf(os.Stderr, "  ✗ %s\n", c)
+	}
+	return fmt.Errorf("%d credential(s) changed since the attestation", len(changes))
+}
+
+func init() {
+	attestCmd.Flags().StringP("file", "f", "", "Write the attestation to this file instead of stdout")
+	attestCmd.Flags().String("verify", "", "Check an attestation file instead of writing one")
+	attestCmd.Flags().String("key", "", "With --verify: the base64 public key to check against, without opening the vault")
+	attestCmd.Flags().Bool("public-key", false, "Print the vault's attestation public key (base64)")
+	rootCmd.AddCommand(attestCmd)
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/busyrockin/api-vault/rotation"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Check stored credentials for problems",
+	Long: `Check stored credentials for problems. With no flags every check runs.
+
+  --rotation-readiness   credentials whose type has no rotation plugin, or
+                         whose config lacks fields the plugin requires
+  --never-rotated        credentials that have never been rotated, with
+                         their age since creation
+  --rotation-due         credentials past their rotation schedule
+                         (add --rotate-every); 'rotate --due' rotates them
+  --key-formats          decrypts each credential and checks its keys
+                         against the expected format for its api_type
+                         (keys are never printed)
+
+--type limits every check to one api_type.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		readiness, _ := cmd.Flags().GetBool("rotation-readiness")
+		neverRotated, _ := cmd.Flags().GetBool("never-rotated")
+		keyFormats, _ := cmd.Flags().GetBool("key-formats")
+		rotationDue, _ := cmd.Flags().GetBool("rotation-due")
+		apiType, _ := cmd.Flags().GetString("type")
+		all := !readiness && !neverRotated && !keyFormats && !rotationDue
+
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		var creds []core.Credential
+		if apiType != "" {
+			creds, err = db.CredentialsByType(apiType)
+		} else {
+			creds, err = db.ListCredentials()
+		}
+		if err != nil {
+			return fmt.Errorf("list credentials: %w", err)
+		}
+		if len(creds) == 0 {
+			fmt.Fprintln(os.Stderr, "No credentials stored.")
+			return nil
+		}
+
+		problems := 0
+		if readiness || all {
+			n, err := auditRotationReadiness(rotation.GetGlobalRegistry(), creds)
+			if err != nil {
+				return err
+			}
+			problems += n
+		}
+		if neverRotated || all {
+			n, err := auditNeverRotated(db, apiType)
+			if err != nil {
+				return err
+			}
+			problems += n
+		}
+		if rotationDue || all {
+			n, err := auditRotationDue(db, apiType)
+			if err != nil {
+				return err
+			}
+			problems += n
+		}
+		if keyFormats || all {
+			n, err := auditKeyFormats(db, creds)
+			if err != nil {
+				return err
+			}
+			problems += n
+		}
+
+		if problems > 0 {
+			return fmt.Errorf("audit found %d problem(s)", problems)
+		}
+		return nil
+	},
+}
+
+// auditRotationReadiness prints a readiness table and returns how many
+// credentials are not ready to rotate.
+func auditRotationReadiness(reg *rotation.Registry, creds []core.Credential) (int, error) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tREADY\tMISSING")
+
+	notReady := 0
+	for _, c := range creds {
+		ready, missing, err := rotationReadiness(reg, c)
+		status := "yes"
+		switch {
+		case errors.Is(err, core.ErrNoPlugin):
+			status = "no"
+			missing = []string{"(no plugin)"}
+		case err != nil:
+			return notReady, fmt.Errorf("%s: %w", c.Name, err)
+		case !ready:
+			status = "no"
+		}
+		if status == "no" {
+			notReady++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Name, c.APIType, status, strings.Join(missing, ", "))
+	}
+	w.Flush()
+	return notReady, nil
+}
+
+// rotationReadiness reports whether cred can be rotated: a plugin in reg
+// handles its api_type and every Required field of the plugin's
+// ConfigSchema is set in the credential's stored config. missing lists the
+// unset fields. A type with no plugin returns core.ErrNoPlugin.
+func rotationReadiness(reg *rotation.Registry, cred core.Credential) (ready bool, missing []string, err error) {
+	plugin, ok := reg.Get(cred.APIType)
+	if !ok {
+		return false, nil, fmt.Errorf("%w for api_type %q", core.ErrNoPlugin, cred.APIType)
+	}
+	for _, f := range plugin.ConfigSchema().Fields {
+		if f.Required && cred.Config[f.Name] == "" {
+			missing = append(missing, f.Name)
+		}
+	}
+	return len(missing) == 0, missing, nil
+}
+
+// auditNeverRotated lists credentials with no rotation on record and
+// returns how many there are.
+func auditNeverRotated(db *core.Database, apiType string) (int, error) {
+	creds, err := db.NeverRotated(apiType)
+	if err != nil {
+		return 0, fmt.Errorf("never rotated: %w", err)
+	}
+	if len(creds) == 0 {
+		fmt.Fprintln(os.Stderr, "✓ Every credential has been rotated at least once.")
+		return 0, nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tCREATED\tAGE")
+	for _, c := range creds {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Name, c.APIType, c.CreatedAt.Format("2006-01-02"), core.HumanAge(c.CreatedAt))
+	}
+	w.Flush()
+	return len(creds), nil
+}
+
+// auditRotationDue lists credentials past their rotation schedule and
+// returns how many there are.
+func auditRotationDue(db *core.Database, apiType string) (int, error) {
+	now := time.Now()
+	creds, err := db.ListDueForRotation(now)
+	if err != nil {
+		return 0, fmt.Errorf("rotation due: %w", err)
+	}
+	if apiType != "" {
+		creds = slices.DeleteFunc(creds, func(c core.Credential) bool { return c.APIType != apiType })
+	}
+	if len(creds) == 0 {
+		fmt.Fprintln(os.Stderr, "✓ No credential is past its rotation schedule.")
+		return 0, nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tEVERY\tDUE\tOVERDUE")
+	for _, c := range creds {
+		due, _ := c.NextRotation()
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Name, c.APIType, formatInterval(*c.RotationInterval),
+			due.Format("2006-01-02"), core.HumanAge(due))
+	}
+	w.Flush()
+	return len(creds), nil
+}
+
+// auditKeyFormats prints each credential's key format check and returns how
+// many failed. Only the verdicts are printed, never the keys.
+func auditKeyFormats(db *core.Database, creds []core.Credential) (int, error) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tFORMAT")
+
+	bad := 0
+	for _, c := range creds {
+		status := "ok"
+		switch {
+		case c.Protected:
+			status = "skipped (passphrase-protected)"
+		default:
+			cred, err := db.InspectCredential(c.Name)
+			if err != nil {
+				bad++
+				status = "✗ " + err.Error()
+				break
+			}
+			problems := core.CheckKeyFormat(cred.APIType, cred.SecretKey, cred.PublicKey)
+			switch {
+			case len(problems) > 0:
+				bad++
+				status = "✗ " + strings.Join(problems, "; ")
+			case !core.HasKeyFormat(cred.APIType):
+				status = "unchecked (no format for type)"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, c.APIType, status)
+	}
+	w.Flush()
+	return bad, nil
+}
+
+func init() {
+	auditCmd.Flags().Bool("key-formats", false, "Check each stored key against its api_type's format")
+	auditCmd.Flags().Bool("rotation-readiness", false, "Check that each credential can be rotated")
+	auditCmd.Flags().Bool("never-rotated", false, "List credentials that have never been rotated")
+	auditCmd.Flags().Bool("rotation-due", false, "List credentials past their rotation schedule")
+	auditCmd.Flags().StringP("type", "t", "", "Only audit credentials of this api_type")
+	rootCmd.AddCommand(auditCmd)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var compactCmd = &cobra.Command{
+	Use:   "compact",
+	Short: "Reclaim space left by deleted credentials",
+	Long: `Rebuild the vault file with VACUUM. Deleted credentials leave their
+encrypted bytes in free pages until the file is rebuilt; compact drops them
+and returns the space.
+
+--rekey first re-derives the field key with a fresh salt and re-encrypts
+every stored key, so nothing in the rebuilt file is under the old key.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rekey, _ := cmd.Flags().GetBool("rekey")
+
+		db, pw, err := openVaultPassword()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		if rekey {
+			if err := db.RotateFieldKey(pw); err != nil {
+				return fmt.Errorf("rotate field key: %w", err)
+			}
+			fmt.Fprintln(os.Stderr, "✓ Field key rotated")
+		}
+
+		stats, err := db.Compact()
+		if err != nil {
+			return fmt.Errorf("compact: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "✓ Compacted %s: %d → %d bytes (reclaimed %d)\n",
+			vaultPath, stats.Before, stats.After, stats.Reclaimed())
+		return nil
+	},
+}
+
+func init() {
+	compactCmd.Flags().Bool("rekey", false, "Also rotate the field encryption key before compacting")
+	rootCmd.AddCommand(compactCmd)
+}
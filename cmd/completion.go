@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate a shell completion script",
+	Long: `Print a completion script for the given shell. Load it in the current
+shell with e.g.
+
+  source <(api-vault completion bash)
+
+or save it where your shell looks for completions.
+
+Credential names for get, delete, rotate, update and qr are completed from a
+running agent (api-vault serve), or from the vault when API_VAULT_PASSWORD is
+set. Completion never prompts for the password; without either, names are
+simply not offered.`,
+	ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "bash":
+			return rootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			return rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			return rootCmd.GenFishCompletion(os.Stdout, true)
+		default:
+			return rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+		}
+	},
+}
+
+// completeCredentialNames is a ValidArgsFunction offering the vault's
+// credential names for a command's single name argument. It runs in the
+// shell's completion hook, where root's PersistentPreRunE hasn't seen the
+// command's flags, so settings are resolved here. The hook must not prompt,
+// since the shell owns the terminal while it runs: names come from a running
+// agent, or from a read-only open with API_VAULT_PASSWORD. Nothing is
+// written to stdout but names; any failure just offers no names.
+func completeCredentialNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	none := cobra.ShellCompDirectiveNoFileComp
+	if len(args) > 0 {
+		return nil, none
+	}
+	s, err := loadSettings(cmd.Flags())
+	if err != nil {
+		return nil, none
+	}
+	if _, err := os.Stat(s.VaultPath); err != nil {
+		return nil, none
+	}
+	all, err := completionNames(s.VaultPath)
+	if err != nil {
+		return nil, none
+	}
+	var names []string
+	for _, n := range all {
+		if strings.HasPrefix(n, toComplete) {
+			names = append(names, n)
+		}
+	}
+	return names, none
+}
+
+// completionNames lists path's credential names without prompting: through
+// the agent unless API_VAULT_NO_AGENT is set, else with API_VAULT_PASSWORD.
+func completionNames(path string) ([]string, error) {
+	if os.Getenv("API_VAULT_NO_AGENT") == "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			socket := agentSocketIn(filepath.Dir(path))
+			if names, ok, err := agentNames(socket, agentToken(socket), abs); ok {
+				return names, err
+			}
+		}
+	}
+	pw := os.Getenv("API_VAULT_PASSWORD")
+	if pw == "" {
+		return nil, nil
+	}
+	db, err := core.OpenReadOnly(path, pw, core.WithoutAccessLog())
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	creds, err := db.ListCredentials()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(creds))
+	for _, c := range creds {
+		names = append(names, c.Name)
+	}
+	return names, nil
+}
+
+func init() {
+	for _, c := range []*cobra.Command{getCmd, deleteCmd, rotateCmd, updateCmd, qrCmd} {
+		c.ValidArgsFunction = completeCredentialNames
+	}
+	rootCmd.AddCommand(completionCmd)
+}
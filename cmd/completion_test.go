@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/busyrockin/api-vault/core"
+)
+
+func TestCompleteCredentialNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.db")
+	db, err := core.NewDatabase(path, "test-password")
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	for _, name := range []string{"openai-prod", "openai-dev", "github"} {
+		db.AddCredential(name, "secret", "t")
+	}
+	db.Close()
+
+	complete := func(password string, args ...string) []string {
+		t.Setenv("API_VAULT_PASSWORD", password)
+		var out bytes.Buffer
+		rootCmd.SetOut(&out)
+		rootCmd.SetArgs(append([]string{"__complete"}, args...))
+		t.Cleanup(func() { rootCmd.SetOut(nil); rootCmd.SetArgs(nil); resetFlags(rootCmd) })
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("__complete %v: %v", args, err)
+		}
+		var names []string
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			if !strings.HasPrefix(line, ":") {
+				names = append(names, line)
+			}
+		}
+		return names
+	}
+
+	got := complete("test-password", "get", "--vault-path", path, "open")
+	if strings.Join(got, ",") != "openai-dev,openai-prod" {
+		t.Fatalf("completions = %v, want openai-dev, openai-prod", got)
+	}
+	if got := complete("test-password", "get", "--vault-path", path, "github", ""); got != nil {
+		t.Fatalf("completions after the name = %v, want none", got)
+	}
+	if got := complete("wrong", "delete", "--vault-path", path, ""); got != nil {
+		t.Fatalf("completions with a wrong password = %v, want none", got)
+	}
+	// Without a password the hook must not prompt; it offers nothing.
+	if got := complete("", "get", "--vault-path", path, "open"); got != nil {
+		t.Fatalf("completions without a password = %v, want none", got)
+	}
+
+	// A running agent answers without a password.
+	db, err = core.OpenReadOnly(path, "test-password")
+	if err != nil {
+		t.Fatalf("OpenReadOnly: %v", err)
+	}
+	defer db.Close()
+	socket := filepath.Join(filepath.Dir(path), "agent.sock")
+	ln, token, err := listenAgent(socket)
+	if err != nil {
+		t.Fatalf("listenAgent: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- (&agent{db: db, vault: path, token: token}).serve(ctx, ln, 0) }()
+	defer func() { cancel(); <-served }()
+
+	got = complete("", "get", "--vault-path", path, "open")
+	if strings.Join(got, ",") != "openai-dev,openai-prod" {
+		t.Fatalf("completions through the agent = %v, want openai-dev, openai-prod", got)
+	}
+}
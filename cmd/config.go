@@ -0,0 +1,327 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// settings is the effective runtime configuration. Later sources override
+// earlier ones: defaults < config file < environment < flags. Nothing in
+// here is secret; the master password never passes through settings.
+type settings struct {
+	ConfigFile     string `json:"config_file"`
+	Profile        string `json:"profile,omitempty"`
+	VaultPath      string `json:"vault_path"`
+	DefaultType    string `json:"default_type"`
+	MaxSecretSize  int    `json:"max_secret_size"`
+	PasswordSource string `json:"password_source"`
+	PasswordTries  int    `json:"password_attempts"`
+
+	// RevealTimeout is how long interactive mode shows a revealed secret
+	// before hiding it again; 0 keeps it until toggled off.
+	RevealTimeout int `json:"reveal_timeout_seconds"`
+
+	// ClipboardClear is copy's --clear default: how long a copied secret
+	// stays on the clipboard; 0 leaves it there.
+	ClipboardClear int `json:"clipboard_clear_seconds"`
+
+	// IdleTimeout is serve's --idle-timeout default; 0 keeps the agent
+	// running until stopped.
+	IdleTimeout int `json:"idle_timeout_seconds"`
+
+	// LogLevel turns on the vault's event log on stderr at debug, info,
+	// warn or error; "" leaves it off.
+	LogLevel string `json:"log_level,omitempty"`
+
+	// AccessLog records each secret read in the vault, for history --access
+	// and the last-accessed column. Off by default: it makes every read a
+	// write.
+	AccessLog bool `json:"access_log"`
+
+	// WeakKDFOK acknowledges Argon2id parameters below today's defaults,
+	// silencing the note printed on every open of such a vault.
+	WeakKDFOK bool `json:"weak_kdf_ok"`
+
+	InsecurePermissions bool `json:"insecure_permissions"`
+	NoMigrationBackup   bool `json:"no_migration_backup"`
+	ReadOnly            bool `json:"read_only"`
+}
+
+// profileSettings is one layer of config.json. Pointers distinguish unset
+// from zero so a profile only overrides what it names.
+type profileSettings struct {
+	VaultPath      *string `json:"vault_path"`
+	DefaultType    *string `json:"default_type"`
+	MaxSecretSize  *int    `json:"max_secret_size"`
+	RevealTimeout  *int    `json:"reveal_timeout_seconds"`
+	ClipboardClear *int    `json:"clipboard_clear_seconds"`
+	IdleTimeout    *int    `json:"idle_timeout_seconds"`
+	LogLevel       *string `json:"log_level"`
+	AccessLog      *bool   `json:"access_log"`
+	WeakKDFOK      *bool   `json:"weak_kdf_ok"`
+}
+
+// fileSettings mirrors config.json: top-level settings plus named profiles
+// that are layered on top when selected with --profile.
+type fileSettings struct {
+	profileSettings
+	Profiles map[string]profileSettings `json:"profiles"`
+}
+
+func (s *settings) apply(p profileSettings) {
+	if p.VaultPath != nil {
+		s.VaultPath = expandHome(*p.VaultPath)
+	}
+	if p.DefaultType != nil {
+		s.DefaultType = *p.DefaultType
+	}
+	if p.MaxSecretSize != nil {
+		s.MaxSecretSize = *p.MaxSecretSize
+	}
+	if p.RevealTimeout != nil {
+		s.RevealTimeout = *p.RevealTimeout
+	}
+	if p.ClipboardClear != nil {
+		s.ClipboardClear = *p.ClipboardClear
+	}
+	if p.IdleTimeout != nil {
+		s.IdleTimeout = *p.IdleTimeout
+	}
+	if p.LogLevel != nil {
+		s.LogLevel = *p.LogLevel
+	}
+	if p.AccessLog != nil {
+		s.AccessLog = *p.AccessLog
+	}
+	if p.WeakKDFOK != nil {
+		s.WeakKDFOK = *p.WeakKDFOK
+	}
+}
+
+// cfg holds the settings resolved for the running command.
+var cfg = defaultSettings()
+
+func defaultSettings() settings {
+	home, _ := os.UserHomeDir()
+	return settings{
+		VaultPath:      filepath.Join(home, ".api-vault", "vault.db"),
+		MaxSecretSize:  core.DefaultMaxSecretSize,
+		PasswordSource: "prompt",
+		PasswordTries:  3,
+		RevealTimeout:  30,
+		IdleTimeout:    30 * 60,
+	}
+}
+
+// loadSettings merges every configuration source for the current invocation.
+func loadSettings(flags *pflag.FlagSet) (settings, error) {
+	s := defaultSettings()
+
+	s.Profile, _ = flags.GetString("profile")
+	if s.Profile == "" {
+		s.Profile = os.Getenv("API_VAULT_PROFILE")
+	}
+
+	path, explicit := configFilePath(flags)
+	fs, err := readConfigFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && !explicit:
+		// No config file is fine, unless a profile was asked for.
+		if s.Profile != "" {
+			return s, fmt.Errorf("profile %q: no config file at %s", s.Profile, path)
+		}
+	case err != nil:
+		return s, fmt.Errorf("config %s: %w", path, err)
+	default:
+		s.ConfigFile = path
+		s.apply(fs.profileSettings)
+		if s.Profile != "" {
+			p, ok := fs.Profiles[s.Profile]
+			if !ok {
+				return s, fmt.Errorf("profile %q not found in %s (available: %s)",
+					s.Profile, path, strings.Join(slices.Sorted(maps.Keys(fs.Profiles)), ", "))
+			}
+			s.apply(p)
+		}
+	}
+
+	if v := os.Getenv("API_VAULT_PATH"); v != "" {
+		s.VaultPath = expandHome(v)
+	}
+	if v := os.Getenv("API_VAULT_DEFAULT_TYPE"); v != "" {
+		s.DefaultType = v
+	}
+	if v := os.Getenv("API_VAULT_MAX_SECRET_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return s, fmt.Errorf("API_VAULT_MAX_SECRET_SIZE: %w", err)
+		}
+		s.MaxSecretSize = n
+	}
+	if v := os.Getenv("API_VAULT_REVEAL_TIMEOUT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return s, fmt.Errorf("API_VAULT_REVEAL_TIMEOUT: %w", err)
+		}
+		s.RevealTimeout = n
+	}
+	if v := os.Getenv("API_VAULT_CLIPBOARD_CLEAR"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return s, fmt.Errorf("API_VAULT_CLIPBOARD_CLEAR: %w", err)
+		}
+		s.ClipboardClear = n
+	}
+	if v := os.Getenv("API_VAULT_IDLE_TIMEOUT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return s, fmt.Errorf("API_VAULT_IDLE_TIMEOUT: %w", err)
+		}
+		s.IdleTimeout = n
+	}
+	if v := os.Getenv("API_VAULT_LOG_LEVEL"); v != "" {
+		s.LogLevel = v
+	}
+	if s.LogLevel != "" {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(s.LogLevel)); err != nil {
+			return s, fmt.Errorf("log level must be debug, info, warn or error, got %q", s.LogLevel)
+		}
+	}
+	if v := os.Getenv("API_VAULT_ACCESS_LOG"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return s, fmt.Errorf("API_VAULT_ACCESS_LOG: %w", err)
+		}
+		s.AccessLog = b
+	}
+	if v := os.Getenv("API_VAULT_WEAK_KDF_OK"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return s, fmt.Errorf("API_VAULT_WEAK_KDF_OK: %w", err)
+		}
+		s.WeakKDFOK = b
+	}
+	if os.Getenv("API_VAULT_PASSWORD") != "" {
+		s.PasswordSource = "env"
+	}
+	if v := os.Getenv("API_VAULT_READ_ONLY"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return s, fmt.Errorf("API_VAULT_READ_ONLY: %w", err)
+		}
+		s.ReadOnly = b
+	}
+
+	name, _ := flags.GetString("vault")
+	path, _ = flags.GetString("vault-path")
+	switch {
+	case name != "" && path != "":
+		return s, fmt.Errorf("pass --vault or --vault-path, not both")
+	case name != "":
+		p, err := namedVaultPath(filepath.Dir(s.VaultPath), name)
+		if err != nil {
+			return s, err
+		}
+		s.VaultPath = p
+	case path != "":
+		s.VaultPath = expandHome(path)
+	}
+
+	if flags.Changed("insecure-permissions") {
+		s.InsecurePermissions, _ = flags.GetBool("insecure-permissions")
+	}
+	if flags.Changed("no-backup") {
+		s.NoMigrationBackup, _ = flags.GetBool("no-backup")
+	}
+	if flags.Changed("read-only") {
+		s.ReadOnly, _ = flags.GetBool("read-only")
+	}
+	if flags.Changed("max-secret-size") {
+		s.MaxSecretSize, _ = flags.GetInt("max-secret-size")
+	}
+	if flags.Changed("password-attempts") {
+		s.PasswordTries, _ = flags.GetInt("password-attempts")
+	}
+
+	return s, nil
+}
+
+// namedVaultPath is where --vault name lives: <name>.db in dir, the
+// directory of the configured vault (~/.api-vault by default).
+func namedVaultPath(dir, name string) (string, error) {
+	name = strings.TrimSuffix(name, ".db")
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid vault name %q; use --vault-path for a file elsewhere", name)
+	}
+	return filepath.Join(dir, name+".db"), nil
+}
+
+// configFilePath resolves --config, then API_VAULT_CONFIG, then the default
+// location. explicit reports whether the user named the file.
+func configFilePath(flags *pflag.FlagSet) (string, bool) {
+	if p, _ := flags.GetString("config"); p != "" {
+		return expandHome(p), true
+	}
+	if p := os.Getenv("API_VAULT_CONFIG"); p != "" {
+		return expandHome(p), true
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".api-vault", "config.json"), false
+}
+
+func readConfigFile(path string) (*fileSettings, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fs fileSettings
+	if err := json.Unmarshal(b, &fs); err != nil {
+		return nil, err
+	}
+	return &fs, nil
+}
+
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, p[1:])
+	}
+	return p
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect runtime and vault configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration as JSON",
+	Long: `Print the merged configuration (defaults < config file < env < flags).
+Secrets are never included, and neither are the vault's own settings such as
+its key derivation parameters; 'api-vault upgrade-kdf' reports those.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(cfg)
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigShowLayering(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	file := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(file, []byte(`{
+		"reveal_timeout_seconds": 5,
+		"clipboard_clear_seconds": 10,
+		"idle_timeout_seconds": 60,
+		"profiles": {"work": {"vault_path": "~/work.db", "idle_timeout_seconds": 120}}
+	}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("API_VAULT_CLIPBOARD_CLEAR", "20")
+
+	out, err := runCLI(t, "config", "show", "--config", file, "--profile", "work")
+	if err != nil {
+		t.Fatalf("config show: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("config show printed %q: %v", out, err)
+	}
+	for key, want := range map[string]any{
+		"vault_path":              filepath.Join(dir, "work.db"), // profile, ~ expanded
+		"reveal_timeout_seconds":  float64(5),                    // file
+		"idle_timeout_seconds":    float64(120),                  // profile over file
+		"clipboard_clear_seconds": float64(20),                   // env over file
+		"profile":                 "work",
+	} {
+		if got[key] != want {
+			t.Errorf("%s = %v, want %v", key, got[key], want)
+		}
+	}
+	// KDF parameters belong to the vault, which config show never opens.
+	if _, ok := got["kdf"]; ok {
+		t.Errorf("config show reports kdf: %v", got["kdf"])
+	}
+
+	if _, err := runCLI(t, "config", "show", "--config", file, "--profile", "home"); err == nil {
+		t.Error("an unknown profile should be refused")
+	}
+}
+
+func TestProfileSelection(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	file := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(file, []byte(`{
+		"default_type": "openai",
+		"profiles": {
+			"work": {"vault_path": "/srv/work.db", "default_type": "github"},
+			"home": {"log_level": "debug"}
+		}
+	}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	show := func(args ...string) map[string]any {
+		t.Helper()
+		out, err := runCLI(t, append([]string{"config", "show", "--config", file}, args...)...)
+		if err != nil {
+			t.Fatalf("config show %v: %v", args, err)
+		}
+		var got map[string]any
+		if err := json.Unmarshal([]byte(out), &got); err != nil {
+			t.Fatalf("config show printed %q: %v", out, err)
+		}
+		return got
+	}
+
+	if got := show(); got["default_type"] != "openai" || got["vault_path"] != filepath.Join(dir, ".api-vault", "vault.db") {
+		t.Errorf("no profile: %v", got)
+	}
+	if got := show("--profile", "work"); got["default_type"] != "github" || got["vault_path"] != "/srv/work.db" {
+		t.Errorf("--profile work: %v", got)
+	}
+	// A profile only overrides what it names.
+	t.Setenv("API_VAULT_PROFILE", "home")
+	if got := show(); got["profile"] != "home" || got["log_level"] != "debug" || got["default_type"] != "openai" {
+		t.Errorf("API_VAULT_PROFILE=home: %v", got)
+	}
+	// The flag beats the environment, and flags and env beat the profile.
+	t.Setenv("API_VAULT_DEFAULT_TYPE", "stripe")
+	if got := show("--profile", "work", "--vault-path", "/tmp/x.db"); got["profile"] != "work" ||
+		got["default_type"] != "stripe" || got["vault_path"] != "/tmp/x.db" {
+		t.Errorf("--profile work with overrides: %v", got)
+	}
+
+	if _, err := runCLI(t, "config", "show", "--config", filepath.Join(dir, "none.json"), "--profile", "work"); err == nil {
+		t.Error("an explicit config file that doesn't exist should be an error")
+	}
+	t.Setenv("API_VAULT_CONFIG", "")
+	if _, err := runCLI(t, "config", "show", "--profile", "work"); err == nil {
+		t.Error("--profile without any config file should be an error")
+	}
+}
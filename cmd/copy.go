@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+// The system clipboard; tests swap in a fake.
+var (
+	clipboardUnsupported = clipboard.Unsupported
+	readClipboard        = clipboard.ReadAll
+	writeClipboard       = clipboard.WriteAll
+)
+
+var copyCmd = &cobra.Command{
+	Use:   "copy <name>",
+	Short: "Copy a decrypted API key to the clipboard",
+	Long: `Copy a credential's secret to the system clipboard. Nothing is written to
+stdout; a confirmation goes to stderr.
+
+--clear 30s keeps the command running for that long, then empties the
+clipboard if it still holds the secret. Ctrl-C clears it straight away.
+Without the flag, clipboard_clear_seconds from config.json or
+API_VAULT_CLIPBOARD_CLEAR applies (0, the default, leaves it there).
+
+On Linux this needs xclip, xsel or wl-clipboard; over SSH or in a headless
+session there is no clipboard, so use 'api-vault get' instead.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clearAfter, _ := cmd.Flags().GetDuration("clear")
+		if !cmd.Flags().Changed("clear") {
+			clearAfter = time.Duration(cfg.ClipboardClear) * time.Second
+		}
+		if clearAfter < 0 {
+			return fmt.Errorf("--clear must be positive, got %s", clearAfter)
+		}
+		if clipboardUnsupported {
+			return fmt.Errorf("no clipboard available (install xclip, xsel or wl-clipboard, or use 'api-vault get')")
+		}
+
+		name := args[0]
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		key, err := db.GetCredential(name)
+		if errors.Is(err, core.ErrPassphraseRequired) {
+			key, err = unlockSecret(db, name)
+		}
+		db.Close()
+		if err != nil {
+			if errors.Is(err, core.ErrNotFound) {
+				return userErr(err, "credential %q not found", name)
+			}
+			if errors.Is(err, core.ErrDisabled) {
+				return userErr(err, "credential %q is disabled (run 'api-vault enable %s')", name, name)
+			}
+			return fmt.Errorf("get credential: %w", err)
+		}
+		if key == "" {
+			return fmt.Errorf("credential %q has no secret", name)
+		}
+
+		if err := writeClipboard(key); err != nil {
+			return fmt.Errorf("copy to clipboard: %w", err)
+		}
+		if clearAfter == 0 {
+			fmt.Fprintf(os.Stderr, "✓ Copied %q to the clipboard\n", name)
+			return nil
+		}
+
+		fmt.Fprintf(os.Stderr, "✓ Copied %q to the clipboard; clearing in %s (Ctrl-C to clear now)\n", name, clearAfter)
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		defer signal.Stop(sig)
+		select {
+		case <-time.After(clearAfter):
+		case <-sig:
+		}
+		return clearClipboard(key)
+	},
+}
+
+// clearClipboard empties the clipboard unless something other than secret
+// has been copied since.
+func clearClipboard(secret string) error {
+	if cur, err := readClipboard(); err == nil && cur != secret {
+		fmt.Fprintln(os.Stderr, "- Clipboard changed since; left alone")
+		return nil
+	}
+	if err := writeClipboard(""); err != nil {
+		return fmt.Errorf("clear clipboard: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "✓ Clipboard cleared")
+	return nil
+}
+
+func init() {
+	copyCmd.Flags().Duration("clear", 0, "Clear the clipboard after this long, e.g. 30s")
+	rootCmd.AddCommand(copyCmd)
+}
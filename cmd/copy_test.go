@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/busyrockin/api-vault/core"
+)
+
+// fakeClipboard stands in for the system clipboard, recording every write.
+type fakeClipboard struct {
+	mu     sync.Mutex
+	value  string
+	writes []clipboardWrite
+	// onRead, if set, replaces the value before each read, as another
+	// program copying something would.
+	onRead string
+}
+
+type clipboardWrite struct {
+	value string
+	at    time.Time
+}
+
+func useFakeClipboard(t *testing.T) *fakeClipboard {
+	f := &fakeClipboard{}
+	unsupported, read, write := clipboardUnsupported, readClipboard, writeClipboard
+	t.Cleanup(func() { clipboardUnsupported, readClipboard, writeClipboard = unsupported, read, write })
+	clipboardUnsupported = false
+	readClipboard = func() (string, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.onRead != "" {
+			f.value = f.onRead
+		}
+		return f.value, nil
+	}
+	writeClipboard = func(s string) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.value = s
+		f.writes = append(f.writes, clipboardWrite{s, time.Now()})
+		return nil
+	}
+	return f
+}
+
+func TestCopyClear(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.db")
+	db, err := core.NewDatabase(path, "test-password")
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	db.AddCredential("svc", "sk-copied", "t")
+	db.Close()
+	t.Setenv("API_VAULT_PASSWORD", "test-password")
+	copyArgs := func(extra ...string) []string {
+		return append([]string{"copy", "svc", "--vault-path", path, "--insecure-permissions"}, extra...)
+	}
+
+	clip := useFakeClipboard(t)
+	if _, err := runCLI(t, copyArgs()...); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if len(clip.writes) != 1 || clip.value != "sk-copied" {
+		t.Fatalf("without --clear: writes %+v, want just the secret", clip.writes)
+	}
+
+	clip = useFakeClipboard(t)
+	if _, err := runCLI(t, copyArgs("--clear", "200ms")...); err != nil {
+		t.Fatalf("copy --clear: %v", err)
+	}
+	if len(clip.writes) != 2 || clip.writes[0].value != "sk-copied" || clip.writes[1].value != "" {
+		t.Fatalf("--clear writes = %+v, want the secret then empty", clip.writes)
+	}
+	if held := clip.writes[1].at.Sub(clip.writes[0].at); held < 200*time.Millisecond {
+		t.Fatalf("secret held for %s, want about 200ms", held)
+	}
+
+	// Something else copied meanwhile is left alone.
+	clip = useFakeClipboard(t)
+	clip.onRead = "something else"
+	if _, err := runCLI(t, copyArgs("--clear", "10ms")...); err != nil {
+		t.Fatalf("copy --clear: %v", err)
+	}
+	if len(clip.writes) != 1 || clip.value != "something else" {
+		t.Fatalf("changed clipboard: writes %+v, value %q", clip.writes, clip.value)
+	}
+
+	// The clipboard_clear_seconds setting is the default; --clear 0 wins.
+	t.Setenv("API_VAULT_CLIPBOARD_CLEAR", "1")
+	clip = useFakeClipboard(t)
+	if _, err := runCLI(t, copyArgs()...); err != nil {
+		t.Fatalf("copy with API_VAULT_CLIPBOARD_CLEAR: %v", err)
+	}
+	if len(clip.writes) != 2 || clip.writes[1].at.Sub(clip.writes[0].at) < time.Second {
+		t.Fatalf("API_VAULT_CLIPBOARD_CLEAR=1: writes %+v", clip.writes)
+	}
+	clip = useFakeClipboard(t)
+	if _, err := runCLI(t, copyArgs("--clear", "0")...); err != nil {
+		t.Fatalf("copy --clear 0: %v", err)
+	}
+	if len(clip.writes) != 1 {
+		t.Fatalf("--clear 0 over the setting: writes %+v", clip.writes)
+	}
+
+	if _, err := runCLI(t, copyArgs("--clear", "-1s")...); err == nil {
+		t.Error("a negative --clear should be refused")
+	}
+}
@@ -1,22 +1,34 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 
 	"github.com/busyrockin/api-vault/core"
+	"github.com/busyrockin/api-vault/rotation"
 	"github.com/spf13/cobra"
 )
 
 var deleteCmd = &cobra.Command{
 	Use:   "delete <name>",
 	Short: "Remove a stored credential",
-	Args:  cobra.ExactArgs(1),
+	Long: `Remove a stored credential and its rotation history.
+
+With --revoke, the key is first revoked at the provider by the credential's
+rotation plugin. If the plugin can't revoke keys, or revocation fails,
+nothing is deleted.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		name := args[0]
+		revoke, _ := cmd.Flags().GetBool("revoke")
 
-		if !confirm(fmt.Sprintf("Delete credential %q?", name)) {
+		question := fmt.Sprintf("Delete credential %q?", name)
+		if revoke {
+			question = fmt.Sprintf("Revoke %q at the provider and delete it?", name)
+		}
+		if !confirm(question) {
 			fmt.Fprintln(os.Stderr, "Aborted.")
 			return nil
 		}
@@ -27,18 +39,64 @@ var deleteCmd = &cobra.Command{
 		}
 		defer db.Close()
 
-		if err := db.DeleteCredential(name); err != nil {
+		if revoke {
+			ctx, cancel := context.WithTimeout(context.Background(), rotateTimeout)
+			defer cancel()
+			plugin, err := revokeAtProvider(ctx, db, rotation.GetGlobalRegistry(), name)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Revoked %q via %s plugin\n", name, plugin.Name())
+		}
+
+		stats, err := db.DeleteCredentialWithStats(name)
+		if err != nil {
 			if errors.Is(err, core.ErrNotFound) {
-				return fmt.Errorf("credential %q not found", name)
+				return userErr(err, "credential %q not found", name)
 			}
 			return fmt.Errorf("delete credential: %w", err)
 		}
 
-		fmt.Fprintf(os.Stderr, "Deleted credential %q\n", name)
+		if stats.Rotations > 0 {
+			fmt.Fprintf(os.Stderr, "Deleted credential %q and %d rotation records\n", name, stats.Rotations)
+		} else {
+			fmt.Fprintf(os.Stderr, "Deleted credential %q\n", name)
+		}
 		return nil
 	},
 }
 
+// revokeAtProvider kills the credential's key via its plugin's Revoker.
+func revokeAtProvider(ctx context.Context, db *core.Database, reg *rotation.Registry, name string) (rotation.Plugin, error) {
+	cred, err := db.GetCredentialV2(name)
+	if errors.Is(err, core.ErrPassphraseRequired) {
+		var p string
+		if p, err = readCredentialPassphrase(name, false); err == nil {
+			cred, err = db.UnlockCredential(name, p)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, core.ErrNotFound) {
+			return nil, userErr(err, "credential %q not found", name)
+		}
+		return nil, fmt.Errorf("credential %q: %w", name, err)
+	}
+
+	plugin, ok := reg.Get(cred.APIType)
+	if !ok {
+		return nil, userErr(core.ErrNoPlugin, "no rotation plugin for api_type %q; delete without --revoke and revoke the key manually", cred.APIType)
+	}
+	r, ok := plugin.(rotation.Revoker)
+	if !ok {
+		return nil, fmt.Errorf("%s plugin cannot revoke keys; delete without --revoke and revoke the key manually", plugin.Name())
+	}
+	if err := r.Revoke(ctx, credentialInfo(cred)); err != nil {
+		return nil, fmt.Errorf("revoke at provider (nothing deleted): %w", err)
+	}
+	return plugin, nil
+}
+
 func init() {
+	deleteCmd.Flags().Bool("revoke", false, "Revoke the key at the provider before deleting")
 	rootCmd.AddCommand(deleteCmd)
 }
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+var disableCmd = &cobra.Command{
+	Use:   "disable <name>",
+	Short: "Take a credential out of service without deleting it",
+	Long:  "Disabled credentials stay in the vault and in list output, but get refuses to return them until they are enabled again.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setDisabled(args[0], true)
+	},
+}
+
+var enableCmd = &cobra.Command{
+	Use:   "enable <name>",
+	Short: "Put a disabled credential back into service",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setDisabled(args[0], false)
+	},
+}
+
+func setDisabled(name string, disabled bool) error {
+	db, err := openVault()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.SetDisabled(name, disabled); err != nil {
+		if errors.Is(err, core.ErrNotFound) {
+			return userErr(err, "credential %q not found", name)
+		}
+		return fmt.Errorf("update credential: %w", err)
+	}
+
+	state := "Enabled"
+	if disabled {
+		state = "Disabled"
+	}
+	fmt.Fprintf(os.Stderr, "%s credential %q\n", state, name)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(disableCmd)
+	rootCmd.AddCommand(enableCmd)
+}
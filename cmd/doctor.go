@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the vault for configuration and integrity problems",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := os.Stat(vaultPath); err != nil {
+			return fmt.Errorf("vault not found — run 'api-vault init' first")
+		}
+
+		problems := 0
+		check := func(name string, err error) {
+			if err != nil {
+				problems++
+				fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", name, err)
+				return
+			}
+			fmt.Fprintf(os.Stderr, "  ✓ %s\n", name)
+		}
+
+		check("permissions", checkVaultPermissions(vaultPath))
+		_, err := core.ReadCipherSettings(vaultPath)
+		check("cipher settings", err)
+
+		pw, err := readPassword("Master password: ")
+		if err != nil {
+			return err
+		}
+		db, err := core.NewDatabase(vaultPath, pw, vaultOptions()...)
+		check("unlock", err)
+		if err == nil {
+			defer db.Close()
+			check("integrity", db.VerifyIntegrity())
+			if !db.MemoryLocked() {
+				fmt.Fprintln(os.Stderr, "  - key memory lock: unavailable (no mlock here, or ulimit -l too low); the key may be swapped to disk")
+			}
+		}
+
+		if problems > 0 {
+			return fmt.Errorf("%d problem(s) found", problems)
+		}
+		fmt.Fprintln(os.Stderr, "No problems found.")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/busyrockin/api-vault/ui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// editModel is interactive mode's edit screen: a two-field form for a
+// credential's api type and environment, saved with UpdateCredentialV2.
+// Esc is handled by interactiveModel.
+type editModel struct {
+	db      *core.Database
+	name    string
+	apiType string
+	env     string
+	field   int // 0 api type, 1 environment
+	err     error
+	done    bool
+}
+
+// newEditModel loads name's current values. GetMetadata is enough, and
+// unlike GetCredentialV2 it works on disabled and protected credentials.
+func newEditModel(db *core.Database, name string) (editModel, error) {
+	c, err := db.GetMetadata(name)
+	if err != nil {
+		return editModel{}, err
+	}
+	return editModel{db: db, name: name, apiType: c.APIType, env: deref(c.Environment)}, nil
+}
+
+func (m editModel) Update(msg tea.Msg) (editModel, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	value := &m.apiType
+	if m.field == 1 {
+		value = &m.env
+	}
+
+	switch key.String() {
+	case "tab", "shift+tab", "up", "down":
+		m.field = 1 - m.field
+	case "enter":
+		if m.field == 0 {
+			m.field = 1
+			return m, nil
+		}
+		return m.save(), nil
+	case "backspace":
+		if len(*value) > 0 {
+			*value = (*value)[:len(*value)-1]
+		}
+	default:
+		if len(key.String()) == 1 {
+			*value += key.String()
+		}
+	}
+	return m, nil
+}
+
+func (m editModel) save() editModel {
+	apiType := strings.TrimSpace(m.apiType)
+	env := strings.TrimSpace(m.env)
+	if err := m.db.UpdateCredentialV2(m.name, &core.CredentialPatch{APIType: &apiType, Environment: &env}); err != nil {
+		m.err = fmt.Errorf("failed to save: %w", err)
+		return m
+	}
+	m.done = true
+	m.err = nil
+	return m
+}
+
+func (m editModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(ui.TitleStyle.Render("🔐 Edit Credential"))
+	b.WriteString("\n\n")
+	b.WriteString(ui.SubtitleStyle.Render(m.name))
+	b.WriteString("\n\n")
+
+	if m.err != nil {
+		b.WriteString(ui.StatusErrorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
+		b.WriteString("\n\n")
+	}
+
+	for i, f := range []struct{ label, value string }{
+		{"Type:        ", m.apiType},
+		{"Environment: ", m.env},
+	} {
+		if i == m.field {
+			b.WriteString(ui.SelectedStyle.Render("❯ " + f.label))
+			b.WriteString(ui.Primary.Render(f.value + "_"))
+		} else {
+			b.WriteString(ui.NormalStyle.Render("  " + f.label + f.value))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(ui.HelpStyle.Render("[Tab/↑↓] Switch field  [Enter] Next/Save  [Esc] Cancel"))
+
+	return ui.BoxStyle.Render(b.String())
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+var envCmd = &cobra.Command{
+	Use:   "env [name...]",
+	Short: "Print credentials as shell export statements",
+	Long: `Print 'export NAME=secret' lines for the named credentials (or --all) to
+load into a shell:
+
+  eval "$(api-vault env openai stripe)"
+  api-vault env --all --format fish | source
+
+Variable names are the credential names, after --prefix, uppercased with
+anything other than letters, digits and _ replaced by _. Values are
+single-quoted. Only the statements go to stdout; messages go to stderr.
+Nothing is printed if a named credential can't be read.
+
+--all skips disabled, passphrase-protected and public-key-only credentials
+with a warning; named ones are unlocked as 'get' does.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		prefix, _ := cmd.Flags().GetString("prefix")
+		format, _ := cmd.Flags().GetString("format")
+
+		var line func(name, value string) string
+		switch format {
+		case "sh":
+			line = func(n, v string) string { return "export " + n + "=" + shQuote(v) }
+		case "fish":
+			line = func(n, v string) string { return "set -x " + n + " " + fishQuote(v) }
+		default:
+			return fmt.Errorf("--format must be sh or fish, got %q", format)
+		}
+		if all == (len(args) > 0) {
+			return fmt.Errorf("name credentials or pass --all")
+		}
+
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		names := args
+		if all {
+			creds, err := db.ListCredentials()
+			if err != nil {
+				return fmt.Errorf("list credentials: %w", err)
+			}
+			names = nil
+			for _, c := range creds {
+				switch {
+				case c.Disabled:
+					fmt.Fprintf(os.Stderr, "  - %s: disabled, skipped\n", c.Name)
+				case c.Protected:
+					fmt.Fprintf(os.Stderr, "  - %s: passphrase-protected, skipped\n", c.Name)
+				default:
+					names = append(names, c.Name)
+				}
+			}
+		}
+
+		var out strings.Builder
+		vars := make(map[string]string, len(names))
+		for _, name := range names {
+			v := envVarName(prefix + name)
+			if prev, ok := vars[v]; ok {
+				return fmt.Errorf("%q and %q both map to $%s; rename one or use separate calls", prev, name, v)
+			}
+			vars[v] = name
+
+			secret, err := db.GetCredential(name)
+			if errors.Is(err, core.ErrPassphraseRequired) && !all {
+				secret, err = unlockSecret(db, name)
+			}
+			if err != nil {
+				if errors.Is(err, core.ErrNotFound) {
+					return userErr(err, "credential %q not found", name)
+				}
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if secret == "" {
+				if !all {
+					return fmt.Errorf("credential %q has no secret", name)
+				}
+				fmt.Fprintf(os.Stderr, "  - %s: no secret, skipped\n", name)
+				continue
+			}
+			out.WriteString(line(v, secret))
+			out.WriteByte('\n')
+		}
+		_, err = os.Stdout.WriteString(out.String())
+		return err
+	},
+}
+
+// envVarName turns a credential name into a shell identifier: uppercased,
+// with anything but letters, digits and _ replaced by _, and never starting
+// with a digit.
+func envVarName(name string) string {
+	b := []byte(strings.ToUpper(name))
+	for i, c := range b {
+		if c != '_' && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			b[i] = '_'
+		}
+	}
+	if len(b) == 0 || b[0] >= '0' && b[0] <= '9' {
+		return "_" + string(b)
+	}
+	return string(b)
+}
+
+// shQuote single-quotes s for POSIX shells.
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote single-quotes s for fish, where \ and ' are escaped inside.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func init() {
+	envCmd.Flags().Bool("all", false, "Every credential instead of named ones")
+	envCmd.Flags().String("prefix", "", "Prepend to every variable name, e.g. OPENAI_")
+	envCmd.Flags().String("format", "sh", "Shell syntax: sh (export) or fish (set -x)")
+	rootCmd.AddCommand(envCmd)
+}
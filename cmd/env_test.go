@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestEnvVarName(t *testing.T) {
+	for in, want := range map[string]string{
+		"openai":          "OPENAI",
+		"OPENAI_stripe-1": "OPENAI_STRIPE_1",
+		"my.app key":      "MY_APP_KEY",
+		"1password":       "_1PASSWORD",
+	} {
+		if got := envVarName(in); got != want {
+			t.Errorf("envVarName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestShellQuoting(t *testing.T) {
+	const secret = `it's a \ "secret" $HOME` + "\nline2"
+	for _, tc := range []struct {
+		shell, script string
+	}{
+		{"sh", "export V=" + shQuote(secret) + "\nprintf %s \"$V\""},
+		{"fish", "set -x V " + fishQuote(secret) + "\nprintf %s \"$V\""},
+	} {
+		if _, err := exec.LookPath(tc.shell); err != nil {
+			continue
+		}
+		out, err := exec.Command(tc.shell, "-c", tc.script).Output()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.shell, err)
+		}
+		if string(out) != secret {
+			t.Errorf("%s round trip = %q, want %q", tc.shell, out, secret)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var envsCmd = &cobra.Command{
+	Use:   "envs",
+	Short: "List the environments used in the vault",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		envs, err := db.Environments()
+		if err != nil {
+			return fmt.Errorf("list environments: %w", err)
+		}
+		if len(envs) == 0 {
+			fmt.Fprintln(os.Stderr, "No credentials have an environment set.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ENVIRONMENT\tCREDENTIALS")
+		for _, e := range envs {
+			fmt.Fprintf(w, "%s\t%d\n", e.Environment, e.Count)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(envsCmd)
+}
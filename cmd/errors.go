@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/busyrockin/api-vault/core"
+)
+
+// userError replaces an error's message with a friendlier one while still
+// matching its cause under errors.Is, so error codes survive rewording.
+type userError struct {
+	msg string
+	err error
+}
+
+func (e *userError) Error() string { return e.msg }
+func (e *userError) Unwrap() error { return e.err }
+
+func userErr(cause error, format string, args ...any) error {
+	return &userError{msg: fmt.Sprintf(format, args...), err: cause}
+}
+
+// errorCodes maps core sentinel errors to the stable codes printed by
+// --json-errors and --output json and to process exit codes. The first match wins; anything
+// else is "error" with exit code 1. Keep the exit code table in rootCmd's
+// help in sync.
+var errorCodes = []struct {
+	err  error
+	code string
+	exit int
+}{
+	{core.ErrNotFound, "not_found", 3},
+	{core.ErrDuplicate, "duplicate", 4},
+	{core.ErrWrongPassword, "wrong_password", 5},
+	{core.ErrDecryptFail, "decrypt_failed", 6},
+	{core.ErrIntegrity, "integrity", 7},
+	{core.ErrDisabled, "disabled", 8},
+	{core.ErrPassphraseRequired, "passphrase_required", 9},
+	{core.ErrWrongPassphrase, "wrong_passphrase", 9},
+	{core.ErrBadPassphrase, "bad_passphrase", 9},
+	{core.ErrNoPlugin, "no_plugin", 10},
+	{core.ErrTooLarge, "too_large", 11},
+	{core.ErrCipherMismatch, "cipher_mismatch", 12},
+	{core.ErrRotationInProgress, "rotation_in_progress", 13},
+	{core.ErrBadAttestation, "bad_attestation", 14},
+	{core.ErrReadOnly, "read_only", 15},
+}
+
+func errorCode(err error) string {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return "error"
+}
+
+// ExitCode is the process exit status for an error returned by Execute.
+func ExitCode(err error) int {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.exit
+		}
+	}
+	return 1
+}
+
+// PrintError writes a failed command's error to w: plain text, or a JSON
+// object with a machine-readable code when --json-errors is set. With
+// --output json it goes to stdout instead, as {"ok":false,...}.
+func PrintError(w io.Writer, err error) {
+	if outputJSON() {
+		writeJSONError(os.Stdout, err)
+		return
+	}
+	if asJSON, _ := rootCmd.PersistentFlags().GetBool("json-errors"); asJSON {
+		json.NewEncoder(w).Encode(struct {
+			Error string `json:"error"`
+			Code  string `json:"code"`
+		}{err.Error(), errorCode(err)})
+		return
+	}
+	fmt.Fprintln(w, err)
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/busyrockin/api-vault/core"
+)
+
+func TestErrorCode(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want string
+		exit int
+	}{
+		{userErr(core.ErrNotFound, "credential %q not found", "x"), "not_found", 3},
+		{fmt.Errorf("add credential: %w", core.ErrDuplicate), "duplicate", 4},
+		{fmt.Errorf("failed to unlock vault: %w", core.ErrWrongPassword), "wrong_password", 5},
+		{fmt.Errorf("get credential: %w", core.ErrDecryptFail), "decrypt_failed", 6},
+		{fmt.Errorf("something else"), "error", 1},
+	} {
+		if got := errorCode(tc.err); got != tc.want {
+			t.Errorf("errorCode(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+		if got := ExitCode(tc.err); got != tc.exit {
+			t.Errorf("ExitCode(%v) = %d, want %d", tc.err, got, tc.exit)
+		}
+	}
+}
+
+func TestWriteJSONError(t *testing.T) {
+	var b bytes.Buffer
+	writeJSONError(&b, userErr(core.ErrNotFound, "credential %q not found", "x"))
+	want := `{"ok":false,"error":"credential \"x\" not found","code":"not_found"}` + "\n"
+	if b.String() != want {
+		t.Fatalf("writeJSONError = %s, want %s", b.String(), want)
+	}
+}
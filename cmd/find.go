@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+var findCmd = &cobra.Command{
+	Use:   "find --key-id <id>",
+	Short: "Find the credential holding a provider key ID",
+	Long: `Map a provider-side key identifier back to its vault credential, e.g. when
+a provider alerts on a specific key. Matches the key ID recorded by the
+most recent rotation.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keyID, _ := cmd.Flags().GetString("key-id")
+
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		c, err := db.GetCredentialByKeyID(keyID)
+		if err != nil {
+			if errors.Is(err, core.ErrNotFound) {
+				return userErr(err, "no credential has key id %q", keyID)
+			}
+			return fmt.Errorf("find: %w", err)
+		}
+
+		lastRotated := "never"
+		if c.LastRotated != nil {
+			lastRotated = c.LastRotated.Format("2006-01-02")
+		}
+		env := ""
+		if c.Environment != nil {
+			env = *c.Environment
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTYPE\tENV\tLAST ROTATED")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Name, c.APIType, env, lastRotated)
+		w.Flush()
+		if c.Disabled {
+			fmt.Fprintln(os.Stderr, "Note: this credential is disabled.")
+		}
+		return nil
+	},
+}
+
+func init() {
+	findCmd.Flags().String("key-id", "", "Provider key ID to look up")
+	findCmd.MarkFlagRequired("key-id")
+	rootCmd.AddCommand(findCmd)
+}
@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/busyrockin/api-vault/core"
 	"github.com/spf13/cobra"
@@ -11,27 +14,134 @@ import (
 var getCmd = &cobra.Command{
 	Use:   "get <name>",
 	Short: "Retrieve a decrypted API key",
-	Args:  cobra.ExactArgs(1),
+	Long: `Print a credential's secret to stdout, with no trailing newline.
+
+--field picks another field instead: public (the public key) or url (the
+first URL). --json prints secret, public and url as one JSON object, leaving
+out the ones the credential doesn't have; with --field, only that one.
+
+When an agent ('api-vault serve') is running for this vault, the credential
+is read through it without asking for the master password; --no-agent or
+API_VAULT_NO_AGENT=1 opens the vault directly.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		field, _ := cmd.Flags().GetString("field")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		noAgent, _ := cmd.Flags().GetBool("no-agent")
+		switch field {
+		case "", "secret", "public", "url":
+		default:
+			return fmt.Errorf("--field must be secret, public or url, got %q", field)
+		}
+
+		if !noAgent && os.Getenv("API_VAULT_NO_AGENT") == "" {
+			if c, ok, err := getFromAgent(args[0]); ok {
+				if err != nil {
+					return getErr(args[0], err)
+				}
+				return printGet(args[0], c, field, asJSON)
+			}
+		}
+
 		db, err := openVault()
 		if err != nil {
 			return err
 		}
 		defer db.Close()
 
-		key, err := db.GetCredential(args[0])
-		if err != nil {
-			if errors.Is(err, core.ErrNotFound) {
-				return fmt.Errorf("credential %q not found", args[0])
+		if field == "" && !asJSON {
+			key, err := db.GetCredential(args[0])
+			if errors.Is(err, core.ErrPassphraseRequired) {
+				key, err = unlockSecret(db, args[0])
+			}
+			if err != nil {
+				return getErr(args[0], err)
 			}
-			return fmt.Errorf("get credential: %w", err)
+			fmt.Print(key)
+			return nil
 		}
 
-		fmt.Print(key)
-		return nil
+		c, err := db.GetCredentialV2(args[0])
+		if errors.Is(err, core.ErrPassphraseRequired) {
+			c, err = unlockCredential(db, args[0])
+		}
+		if err != nil {
+			return getErr(args[0], err)
+		}
+
+		return printGet(args[0], &agentCredential{c.SecretKey, c.PublicKey, c.URL}, field, asJSON)
 	},
 }
 
+// printGet prints what get was asked for: one field of c ("" for the
+// secret), or with asJSON the whole of c unless a field was named.
+func printGet(name string, c *agentCredential, field string, asJSON bool) error {
+	if asJSON && field == "" {
+		return json.NewEncoder(os.Stdout).Encode(c)
+	}
+	value, label, only := c.Secret, "secret", agentCredential{Secret: c.Secret}
+	switch field {
+	case "public":
+		value, label, only = c.Public, "public key", agentCredential{Public: c.Public}
+	case "url":
+		value, label, only = c.URL, "URL", agentCredential{URL: c.URL}
+	}
+	if value == nil {
+		return fmt.Errorf("credential %q has no %s", name, label)
+	}
+	if asJSON {
+		return json.NewEncoder(os.Stdout).Encode(only)
+	}
+	fmt.Print(*value)
+	return nil
+}
+
+// getFromAgent is agentGet for the selected vault and the default socket.
+func getFromAgent(name string) (*agentCredential, bool, error) {
+	vault, err := filepath.Abs(vaultPath)
+	if err != nil {
+		return nil, false, nil
+	}
+	socket := agentSocket()
+	return agentGet(socket, agentToken(socket), vault, name)
+}
+
+// getErr rewords a failed read of name for the user.
+func getErr(name string, err error) error {
+	if errors.Is(err, core.ErrNotFound) {
+		return userErr(err, "credential %q not found", name)
+	}
+	if errors.Is(err, core.ErrDisabled) {
+		return userErr(err, "credential %q is disabled (run 'api-vault enable %s')", name, name)
+	}
+	return fmt.Errorf("get credential: %w", err)
+}
+
+// unlockCredential prompts for a protected credential's extra passphrase and
+// returns the credential with its secret.
+func unlockCredential(db *core.Database, name string) (*core.Credential, error) {
+	p, err := readCredentialPassphrase(name, false)
+	if err != nil {
+		return nil, err
+	}
+	return db.UnlockCredential(name, p)
+}
+
+// unlockSecret is unlockCredential for callers that only need the secret.
+func unlockSecret(db *core.Database, name string) (string, error) {
+	c, err := unlockCredential(db, name)
+	if err != nil {
+		return "", err
+	}
+	if c.SecretKey == nil {
+		return "", nil
+	}
+	return *c.SecretKey, nil
+}
+
 func init() {
+	getCmd.Flags().String("field", "", "Print one field instead of the secret: secret, public or url")
+	getCmd.Flags().Bool("json", false, "Print secret, public and url (or just --field) as a JSON object")
+	getCmd.Flags().Bool("no-agent", false, "Open the vault directly even if an agent is running")
 	rootCmd.AddCommand(getCmd)
 }
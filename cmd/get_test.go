@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/busyrockin/api-vault/core"
+)
+
+func TestGetOutputModes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.db")
+	db, err := core.NewDatabase(path, "test-password")
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	sk, pk, u, only := "sk-full", "pk-full", "https://api.example.com", "sk-only"
+	db.AddCredentialV2(&core.Credential{Name: "full", APIType: "t", SecretKey: &sk, PublicKey: &pk, URL: &u})
+	db.AddCredentialV2(&core.Credential{Name: "partial", APIType: "t", SecretKey: &only})
+
+	cases := []struct {
+		args []string
+		want string // "" means an error is expected
+	}{
+		{[]string{"full"}, "sk-full"},
+		{[]string{"full", "--field", "secret"}, "sk-full"},
+		{[]string{"full", "--field", "public"}, "pk-full"},
+		{[]string{"full", "--field", "url"}, u},
+		{[]string{"full", "--json"}, `{"secret":"sk-full","public":"pk-full","url":"https://api.example.com"}` + "\n"},
+		{[]string{"full", "--json", "--field", "public"}, `{"public":"pk-full"}` + "\n"},
+		{[]string{"partial", "--json"}, `{"secret":"sk-only"}` + "\n"},
+		{[]string{"partial", "--field", "public"}, ""},
+		{[]string{"partial", "--json", "--field", "url"}, ""},
+		{[]string{"full", "--field", "token"}, ""},
+	}
+	run := func(how string) {
+		t.Helper()
+		for _, tc := range cases {
+			out, err := runCLI(t, append(append([]string{"get"}, tc.args...), "--vault-path", path, "--insecure-permissions")...)
+			switch {
+			case tc.want == "" && err == nil:
+				t.Errorf("%s: get %v = %q, want an error", how, tc.args, out)
+			case tc.want != "" && (err != nil || out != tc.want):
+				t.Errorf("%s: get %v = %q, %v; want %q", how, tc.args, out, err, tc.want)
+			}
+		}
+		if _, err := runCLI(t, "get", "missing", "--vault-path", path, "--insecure-permissions"); !errors.Is(err, core.ErrNotFound) {
+			t.Errorf("%s: get missing = %v, want ErrNotFound", how, err)
+		}
+	}
+
+	t.Setenv("API_VAULT_PASSWORD", "test-password")
+	t.Setenv("API_VAULT_NO_AGENT", "1")
+	run("vault")
+
+	// The same answers come through an agent; a wrong password shows the
+	// vault itself is never opened.
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	ln, token, err := listenAgent(socket)
+	if err != nil {
+		t.Fatalf("listenAgent: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- (&agent{db: db, vault: path, token: token}).serve(ctx, ln, 0) }()
+	defer func() {
+		cancel()
+		if err := <-served; err != nil {
+			t.Errorf("serve: %v", err)
+		}
+	}()
+	t.Setenv("API_VAULT_AGENT_SOCKET", socket)
+	t.Setenv("API_VAULT_NO_AGENT", "")
+	t.Setenv("API_VAULT_PASSWORD", "wrong")
+	run("agent")
+}
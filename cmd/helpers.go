@@ -1,54 +1,169 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 
 	"github.com/busyrockin/api-vault/core"
 	"golang.org/x/term"
 )
 
+// vaultPath and vaultDir are resolved from settings before each command runs.
 var (
-	vaultDir  string
-	vaultPath string
+	vaultPath = cfg.VaultPath
+	vaultDir  = filepath.Dir(cfg.VaultPath)
 )
 
-func init() {
-	home, _ := os.UserHomeDir()
-	vaultDir = filepath.Join(home, ".api-vault")
-	vaultPath = filepath.Join(vaultDir, "vault.db")
-}
-
 func readPassword(prompt string) (string, error) {
 	if pw := os.Getenv("API_VAULT_PASSWORD"); pw != "" {
 		return pw, nil
 	}
-	fmt.Fprint(os.Stderr, prompt)
-	b, err := term.ReadPassword(int(os.Stdin.Fd()))
-	fmt.Fprintln(os.Stderr)
-	if err != nil {
-		return "", fmt.Errorf("read password: %w", err)
+	return promptHidden(prompt)
+}
+
+// readPassphrase reads an export passphrase from API_VAULT_EXPORT_PASSPHRASE
+// or the terminal. With confirm, the user types it twice.
+func readPassphrase(confirm bool) (string, error) {
+	if pw := os.Getenv("API_VAULT_EXPORT_PASSPHRASE"); pw != "" {
+		return pw, nil
 	}
-	if len(b) == 0 {
-		return "", fmt.Errorf("password cannot be empty")
+	if confirm {
+		return promptHiddenTwice("Export passphrase: ", "Confirm passphrase: ", "passphrases do not match")
 	}
-	return string(b), nil
+	return promptHidden("Export passphrase: ")
+}
+
+// readCredentialPassphrase reads a per-credential passphrase from
+// API_VAULT_EXTRA_PASSPHRASE or the terminal. With confirm, it is typed twice.
+func readCredentialPassphrase(name string, confirm bool) (string, error) {
+	if p := os.Getenv("API_VAULT_EXTRA_PASSPHRASE"); p != "" {
+		return p, nil
+	}
+	prompt := fmt.Sprintf("Passphrase for %q: ", name)
+	if confirm {
+		return promptHiddenTwice(prompt, "Confirm passphrase: ", "passphrases do not match")
+	}
+	return promptHidden(prompt)
 }
 
 func openVault() (*core.Database, error) {
+	db, _, err := openVaultPassword()
+	return db, err
+}
+
+// openVaultPassword is openVault that also returns the master password,
+// for commands that need it again (e.g. passwd).
+func openVaultPassword() (*core.Database, string, error) {
 	if _, err := os.Stat(vaultPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("vault not found — run 'api-vault init' first")
+		return nil, "", fmt.Errorf("vault not found — run 'api-vault init' first")
 	}
-	pw, err := readPassword("Master password: ")
-	if err != nil {
-		return nil, err
+	if !cfg.InsecurePermissions {
+		if err := checkVaultPermissions(vaultPath); err != nil {
+			return nil, "", err
+		}
 	}
-	db, err := core.NewDatabase(vaultPath, pw)
+	db, pw, err := unlockVault()
 	if err != nil {
-		return nil, fmt.Errorf("failed to unlock vault (wrong password?)")
+		return nil, "", err
+	}
+	if err := db.VerifyIntegrity(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v (run 'api-vault doctor' for details)\n", err)
+	}
+	if weakKDF(db.KDF()) && !cfg.WeakKDFOK {
+		fmt.Fprintln(os.Stderr, "Note: the vault's Argon2id parameters are below today's defaults; run 'api-vault upgrade-kdf' to strengthen them, or set weak_kdf_ok (API_VAULT_WEAK_KDF_OK=1) to keep them")
+	}
+	return db, pw, nil
+}
+
+// weakKDF reports whether k is Argon2id costing less than today's defaults.
+func weakKDF(k core.KeyDeriver) bool {
+	p, ok := k.(core.Argon2Params)
+	return ok && p.WeakerThan(core.DefaultArgon2Params())
+}
+
+// unlockVault opens the vault, re-prompting on a wrong password up to
+// cfg.PasswordTries times. A password from the environment (or any
+// non-terminal stdin) gets a single attempt, since retrying can't change it.
+func unlockVault() (*core.Database, string, error) {
+	tries := cfg.PasswordTries
+	if tries < 1 || os.Getenv("API_VAULT_PASSWORD") != "" || !term.IsTerminal(int(os.Stdin.Fd())) {
+		tries = 1
+	}
+
+	for i := 1; ; i++ {
+		pw, err := readPassword("Master password: ")
+		if err != nil {
+			return nil, "", err
+		}
+		db, err := core.NewDatabase(vaultPath, pw, vaultOptions()...)
+		switch {
+		case err == nil:
+			return db, pw, nil
+		case !errors.Is(err, core.ErrWrongPassword):
+			return nil, "", fmt.Errorf("failed to unlock vault: %w", err)
+		case i >= tries:
+			return nil, "", fmt.Errorf("failed to unlock vault: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Wrong password, try again (%d/%d).\n", i, tries)
+	}
+}
+
+// vaultOptions are the core.NewDatabase options implied by settings. The
+// key is always mlock'ed where the platform allows; doctor reports when it
+// couldn't be.
+func vaultOptions() []core.Option {
+	opts := []core.Option{core.WithMemoryLock()}
+	if cfg.NoMigrationBackup {
+		opts = append(opts, core.WithoutMigrationBackup())
+	}
+	if cfg.ReadOnly {
+		opts = append(opts, core.WithReadOnly())
+	}
+	if cfg.AccessLog {
+		opts = append(opts, core.WithAccessLog())
+	}
+	if cfg.LogLevel != "" {
+		var level slog.Level
+		level.UnmarshalText([]byte(cfg.LogLevel))
+		opts = append(opts, core.WithLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))))
+	}
+	return opts
+}
+
+// checkVaultPermissions refuses a vault file (or its WAL files) readable by
+// group/other (looser than 0600) or a vault directory looser than 0700.
+// SQLCipher protects the contents, but loose modes usually mean a
+// misconfigured deployment. Unix only; Windows ACLs don't map onto these
+// bits.
+func checkVaultPermissions(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	type check struct {
+		path string
+		max  os.FileMode
 	}
-	return db, nil
+	checks := []check{{path, 0600}, {filepath.Dir(path), 0700}}
+	for _, f := range core.WALFiles(path) {
+		checks = append(checks, check{f, 0600})
+	}
+	for _, c := range checks {
+		fi, err := os.Stat(c.path)
+		if err != nil {
+			return err
+		}
+		if mode := fi.Mode().Perm(); mode&^c.max != 0 {
+			return fmt.Errorf("%s has permissions %04o, expected at most %04o (fix with: chmod %o %s, or pass --insecure-permissions)",
+				c.path, mode, c.max, c.max, c.path)
+		}
+	}
+	return nil
 }
 
 func confirm(question string) bool {
@@ -57,3 +172,38 @@ func confirm(question string) bool {
 	fmt.Scanln(&ans)
 	return ans == "y" || ans == "Y"
 }
+
+// readSecretFile reads at most max bytes from path, so a mistyped path to a
+// multi-gigabyte file fails fast instead of being slurped into memory.
+// A single trailing newline is trimmed.
+func readSecretFile(path string, max int) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+	defer f.Close()
+	return readSecretFrom(f, "secret file "+path, max)
+}
+
+// readSecretStdin is readSecretFile for standard input, as in
+// echo -n "$KEY" | api-vault add openai --stdin.
+func readSecretStdin(max int) (string, error) {
+	return readSecretFrom(os.Stdin, "stdin", max)
+}
+
+// readSecretFrom reads a secret of at most max bytes from r, described as
+// src in errors, trimming a single trailing newline (\n or \r\n).
+func readSecretFrom(r io.Reader, src string, max int) (string, error) {
+	if max <= 0 {
+		max = core.DefaultMaxSecretSize
+	}
+	b, err := io.ReadAll(io.LimitReader(r, int64(max)+1))
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", src, err)
+	}
+	if len(b) > max {
+		return "", fmt.Errorf("%s: %w (limit %d bytes)", src, core.ErrTooLarge, max)
+	}
+	s := strings.TrimSuffix(string(b), "\n")
+	return strings.TrimSuffix(s, "\r"), nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/busyrockin/api-vault/core"
+)
+
+func TestVaultPermissionCheck(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "vault")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "vault.db")
+	db, err := core.NewDatabase(path, "test-password")
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	db.AddCredential("svc", "sk-perm", "t")
+	db.Close()
+	os.Chmod(path, 0600)
+	t.Setenv("API_VAULT_PASSWORD", "test-password")
+	t.Setenv("API_VAULT_NO_AGENT", "1")
+
+	get := func(extra ...string) (string, error) {
+		return runCLI(t, append([]string{"get", "svc", "--vault-path", path}, extra...)...)
+	}
+	if out, err := get(); err != nil || out != "sk-perm" {
+		t.Fatalf("get with private modes = %q, %v", out, err)
+	}
+
+	for _, loosen := range []struct {
+		path string
+		mode os.FileMode
+		back os.FileMode
+	}{{path, 0644, 0600}, {dir, 0755, 0700}} {
+		os.Chmod(loosen.path, loosen.mode)
+		_, err := get()
+		if err == nil || !strings.Contains(err.Error(), "--insecure-permissions") || !strings.Contains(err.Error(), loosen.path) {
+			t.Errorf("%s at %04o: err = %v, want a permissions refusal naming it", loosen.path, loosen.mode, err)
+		}
+		if out, err := get("--insecure-permissions"); err != nil || out != "sk-perm" {
+			t.Errorf("%s at %04o with --insecure-permissions = %q, %v", loosen.path, loosen.mode, out, err)
+		}
+		os.Chmod(loosen.path, loosen.back)
+	}
+}
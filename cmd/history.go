@@ -2,15 +2,20 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
+	"github.com/busyrockin/api-vault/core"
 	"github.com/spf13/cobra"
 )
 
 var historyCmd = &cobra.Command{
 	Use:   "history <name>",
 	Short: "Show rotation history for a credential",
-	Args:  cobra.ExactArgs(1),
+	Long: `Show rotation history for a credential, newest first. --access shows
+when its secret was read instead (get, env, rotate and the like); reads are
+only recorded with the access_log setting or API_VAULT_ACCESS_LOG=1.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		name := args[0]
 		limit, _ := cmd.Flags().GetInt("limit")
@@ -21,6 +26,10 @@ var historyCmd = &cobra.Command{
 		}
 		defer db.Close()
 
+		if access, _ := cmd.Flags().GetBool("access"); access {
+			return printAccessHistory(db, name, limit)
+		}
+
 		records, err := db.GetRotationHistory(name, limit)
 		if err != nil {
 			return fmt.Errorf("history: %w", err)
@@ -42,13 +51,35 @@ var historyCmd = &cobra.Command{
 				fmt.Printf("  key_id: %s", r.NewKeyID)
 			}
 			fmt.Println()
+			if reason := r.Metadata[core.RotationReasonKey]; reason != "" {
+				fmt.Printf("    reason: %s\n", reason)
+			}
 		}
 
 		return nil
 	},
 }
 
+func printAccessHistory(db *core.Database, name string, limit int) error {
+	records, err := db.AccessHistory(name, limit)
+	if err != nil {
+		return fmt.Errorf("access history: %w", err)
+	}
+	if len(records) == 0 {
+		fmt.Printf("No recorded reads of %q\n", name)
+		if !cfg.AccessLog {
+			fmt.Fprintln(os.Stderr, "Reads are only recorded with the access_log setting or API_VAULT_ACCESS_LOG=1")
+		}
+		return nil
+	}
+	for _, r := range records {
+		fmt.Printf("%s  %s\n", r.At.Format("2006-01-02 15:04:05"), r.Operation)
+	}
+	return nil
+}
+
 func init() {
 	historyCmd.Flags().IntP("limit", "n", 10, "Maximum number of records to show")
+	historyCmd.Flags().Bool("access", false, "Show when the secret was read instead of rotations")
 	rootCmd.AddCommand(historyCmd)
 }
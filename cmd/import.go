@@ -0,0 +1,505 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+// importItem is one entry from a password-manager export, normalized across
+// formats. Fields is keyed by lowercased field name or label.
+type importItem struct {
+	Title   string
+	Notes   string
+	URL     string
+	Fields  map[string]string
+	Created time.Time // zero when the export doesn't say
+	Updated time.Time
+}
+
+// defaultSecretFields are tried in order when --secret-field isn't given.
+// 1Password "API Credential" items keep the key in "credential".
+var defaultSecretFields = []string{"password", "credential"}
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import credentials from a password manager export",
+	Long: `Import credentials from an api-vault export or a password manager export.
+
+Files written by 'api-vault export' are detected automatically and merged
+into the vault; the export passphrase is read from API_VAULT_EXPORT_PASSPHRASE
+or prompted for. Nothing is written if any name already exists, unless
+--on-conflict overwrite is given: then the file's credentials replace the
+vault's and their rotation history is merged.
+
+Password manager exports need --format:
+  --format 1password   JSON from 'op item get --format json' (one or many items), or the app's CSV export
+  --format bitwarden   JSON from 'bw export --format json' or 'bw list items', or 'bw export --format csv'
+
+Each item's title becomes the credential name, its notes the metadata, and its
+first URL the credential URL. --secret-field picks which field holds the
+secret (default: password, then credential). Use - to read from stdin.
+Creation and modification dates in JSON exports are kept, so staleness shows
+correctly after a migration.
+
+--on-conflict overwrite replaces existing credentials, keeping their history.
+Add --confirm-overwrites to review a masked comparison of each one first.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		secretField, _ := cmd.Flags().GetString("secret-field")
+		apiType, _ := cmd.Flags().GetString("type")
+		onConflict, _ := cmd.Flags().GetString("on-conflict")
+		confirmOverwrites, _ := cmd.Flags().GetBool("confirm-overwrites")
+
+		switch onConflict {
+		case "skip", "fail", "overwrite":
+		default:
+			return fmt.Errorf("--on-conflict must be skip, fail or overwrite, got %q", onConflict)
+		}
+		if confirmOverwrites && onConflict != "overwrite" {
+			return fmt.Errorf("--confirm-overwrites needs --on-conflict overwrite")
+		}
+		if confirmOverwrites && args[0] == "-" {
+			return fmt.Errorf("--confirm-overwrites reads answers from stdin, so the export must come from a file")
+		}
+		if apiType == "" {
+			apiType = cfg.DefaultType
+		}
+
+		data, err := readImportFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		if format == "" {
+			if !core.IsExport(data) {
+				return fmt.Errorf("not an api-vault export; pass --format 1password or bitwarden")
+			}
+			if confirmOverwrites {
+				return fmt.Errorf("--confirm-overwrites works with password manager exports only")
+			}
+			return importExport(data, onConflict == "overwrite")
+		}
+
+		var items []importItem
+		switch format {
+		case "1password":
+			items, err = parse1Password(data)
+		case "bitwarden":
+			items, err = parseBitwarden(data)
+		default:
+			return fmt.Errorf("--format must be 1password or bitwarden, got %q", format)
+		}
+		if err != nil {
+			return fmt.Errorf("parse %s export: %w", format, err)
+		}
+
+		fields := defaultSecretFields
+		if secretField != "" {
+			fields = []string{strings.ToLower(secretField)}
+		}
+
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		db.SetMaxSecretSize(cfg.MaxSecretSize)
+
+		imported, overwritten, skipped := 0, 0, 0
+		for _, it := range items {
+			cred, ok := it.credential(fields, apiType)
+			if !ok {
+				skipped++
+				fmt.Fprintf(os.Stderr, "  - %s: no %s field, skipped\n", it.Title, strings.Join(fields, "/"))
+				continue
+			}
+			if onConflict == "overwrite" {
+				existing, err := db.InspectCredential(cred.Name)
+				if err != nil && !errors.Is(err, core.ErrNotFound) {
+					return fmt.Errorf("import %q: %w", cred.Name, err)
+				}
+				if existing != nil {
+					if confirmOverwrites {
+						printOverwritePreview(os.Stderr, existing, cred)
+						if !confirm(fmt.Sprintf("  Overwrite %q?", cred.Name)) {
+							skipped++
+							fmt.Fprintf(os.Stderr, "  - %s: kept existing\n", cred.Name)
+							continue
+						}
+					}
+					if _, err := db.AddOrUpdateCredentialV2(cred); err != nil {
+						return fmt.Errorf("import %q: %w", cred.Name, err)
+					}
+					overwritten++
+					fmt.Fprintf(os.Stderr, "  ✓ %s (overwritten)\n", cred.Name)
+					continue
+				}
+			}
+			if err := db.AddCredentialV2WithTimestamps(cred); err != nil {
+				if errors.Is(err, core.ErrDuplicate) && onConflict == "skip" {
+					skipped++
+					fmt.Fprintf(os.Stderr, "  - %s: already exists, skipped\n", cred.Name)
+					continue
+				}
+				return fmt.Errorf("import %q: %w", cred.Name, err)
+			}
+			imported++
+			fmt.Fprintf(os.Stderr, "  ✓ %s\n", cred.Name)
+		}
+
+		if overwritten > 0 {
+			fmt.Fprintf(os.Stderr, "Imported %d credentials (%d overwritten, %d skipped)\n", imported+overwritten, overwritten, skipped)
+		} else {
+			fmt.Fprintf(os.Stderr, "Imported %d credentials (%d skipped)\n", imported, skipped)
+		}
+		return nil
+	},
+}
+
+// printOverwritePreview compares an existing credential with the one about
+// to replace it. Secrets are only ever shown masked.
+func printOverwritePreview(w io.Writer, old, new *core.Credential) {
+	fmt.Fprintf(w, "  ~ %s already exists:\n", old.Name)
+	row := func(label, from, to string, changed bool) {
+		mark := " "
+		if changed {
+			mark = "*"
+		}
+		fmt.Fprintf(w, "    %s %-13s %s → %s\n", mark, label, from, to)
+	}
+	field := func(label, from, to string) { row(label, orNone(from), orNone(to), from != to) }
+	field("type", old.APIType, new.APIType)
+	field("environment", deref(old.Environment), deref(new.Environment))
+	field("url", deref(old.URL), deref(new.URL))
+
+	secret := func(c *core.Credential) string {
+		switch {
+		case c.Protected:
+			return "(passphrase-protected)"
+		case !c.HasSecret():
+			return "(none)"
+		}
+		return fmt.Sprintf("%s (%d chars)", maskKey(*c.SecretKey), len(*c.SecretKey))
+	}
+	if old.HasSecret() && new.HasSecret() && *old.SecretKey == *new.SecretKey {
+		row("secret", secret(old), "(identical)", false)
+	} else {
+		row("secret", secret(old), secret(new), true)
+	}
+
+	rotated := "never"
+	if old.LastRotated != nil {
+		rotated = old.LastRotated.Format("2006-01-02")
+	}
+	fmt.Fprintf(w, "      %-13s %s (kept)\n", "last rotated", rotated)
+	if old.Disabled {
+		fmt.Fprintln(w, "      (existing credential is disabled and stays disabled)")
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// importExport merges an 'api-vault export' envelope into the vault.
+func importExport(data []byte, overwrite bool) error {
+	pass, err := readPassphrase(false)
+	if err != nil {
+		return err
+	}
+	bundle, err := core.OpenBundle(bytes.NewReader(data), pass)
+	if err != nil {
+		return err
+	}
+
+	db, err := openVault()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.ImportBundle(bundle, overwrite); err != nil {
+		if errors.Is(err, core.ErrDuplicate) {
+			return fmt.Errorf("nothing imported; %w", err)
+		}
+		return fmt.Errorf("import: %w", err)
+	}
+
+	if bundle.Scope == core.ScopeCredential && len(bundle.Credentials) == 1 {
+		fmt.Fprintf(os.Stderr, "Imported credential %q\n", bundle.Credentials[0].Name)
+	} else {
+		fmt.Fprintf(os.Stderr, "Imported %d credentials\n", len(bundle.Credentials))
+	}
+	return nil
+}
+
+// credential maps the item onto a Credential using the first of fields that
+// holds a value as the secret.
+func (it importItem) credential(fields []string, apiType string) (*core.Credential, bool) {
+	if it.Title == "" {
+		return nil, false
+	}
+	for _, f := range fields {
+		secret := it.Fields[f]
+		if secret == "" {
+			continue
+		}
+		cred := &core.Credential{
+			Name: it.Title, APIType: apiType, Metadata: it.Notes, SecretKey: &secret,
+			CreatedAt: it.Created, UpdatedAt: it.Updated,
+		}
+		if it.URL != "" {
+			url := it.URL
+			cred.URL = &url
+		}
+		return cred, true
+	}
+	return nil, false
+}
+
+func readImportFile(path string) ([]byte, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return io.ReadAll(r)
+}
+
+// isJSON reports whether data looks like JSON rather than CSV.
+func isJSON(data []byte) bool {
+	t := bytes.TrimSpace(data)
+	return len(t) > 0 && (t[0] == '{' || t[0] == '[')
+}
+
+// --- 1Password ---
+
+type opItem struct {
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Fields    []struct {
+		ID      string `json:"id"`
+		Label   string `json:"label"`
+		Purpose string `json:"purpose"`
+		Value   string `json:"value"`
+	} `json:"fields"`
+	URLs []struct {
+		Href    string `json:"href"`
+		Primary bool   `json:"primary"`
+	} `json:"urls"`
+}
+
+func parse1Password(data []byte) ([]importItem, error) {
+	if !isJSON(data) {
+		return parseCSVExport(data, map[string]string{
+			"website":    "url",
+			"notesplain": "notes",
+		})
+	}
+
+	// op prints either a JSON array or a stream of objects.
+	var raw []opItem
+	if bytes.TrimSpace(data)[0] == '[' {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var it opItem
+			if err := dec.Decode(&it); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			raw = append(raw, it)
+		}
+	}
+
+	items := make([]importItem, 0, len(raw))
+	for _, r := range raw {
+		it := importItem{Title: r.Title, Fields: make(map[string]string), Created: r.CreatedAt, Updated: r.UpdatedAt}
+		for _, f := range r.Fields {
+			switch f.Purpose {
+			case "NOTES":
+				it.Notes = f.Value
+				continue
+			case "PASSWORD":
+				it.Fields["password"] = f.Value
+			case "USERNAME":
+				it.Fields["username"] = f.Value
+			}
+			for _, k := range []string{f.ID, f.Label} {
+				if k = strings.ToLower(k); k != "" {
+					if _, set := it.Fields[k]; !set {
+						it.Fields[k] = f.Value
+					}
+				}
+			}
+		}
+		for _, u := range r.URLs {
+			if it.URL == "" || u.Primary {
+				it.URL = u.Href
+			}
+		}
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+// --- Bitwarden ---
+
+type bwItem struct {
+	Name         string    `json:"name"`
+	Notes        string    `json:"notes"`
+	CreationDate time.Time `json:"creationDate"`
+	RevisionDate time.Time `json:"revisionDate"`
+	Login        *struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		TOTP     string `json:"totp"`
+		URIs     []struct {
+			URI string `json:"uri"`
+		} `json:"uris"`
+	} `json:"login"`
+	Fields []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"fields"`
+}
+
+func parseBitwarden(data []byte) ([]importItem, error) {
+	if !isJSON(data) {
+		items, err := parseCSVExport(data, map[string]string{
+			"name":           "title",
+			"login_uri":      "url",
+			"login_username": "username",
+			"login_password": "password",
+			"login_totp":     "totp",
+		})
+		if err != nil {
+			return nil, err
+		}
+		// Custom fields are packed into one column as "name: value" lines.
+		for i := range items {
+			sc := bufio.NewScanner(strings.NewReader(items[i].Fields["fields"]))
+			for sc.Scan() {
+				if k, v, ok := strings.Cut(sc.Text(), ": "); ok {
+					items[i].Fields[strings.ToLower(k)] = v
+				}
+			}
+			delete(items[i].Fields, "fields")
+		}
+		return items, nil
+	}
+
+	var raw []bwItem
+	if bytes.TrimSpace(data)[0] == '[' {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	} else {
+		var export struct {
+			Encrypted bool     `json:"encrypted"`
+			Items     []bwItem `json:"items"`
+		}
+		if err := json.Unmarshal(data, &export); err != nil {
+			return nil, err
+		}
+		if export.Encrypted {
+			return nil, fmt.Errorf("encrypted Bitwarden exports are not supported; export unencrypted JSON")
+		}
+		raw = export.Items
+	}
+
+	items := make([]importItem, 0, len(raw))
+	for _, r := range raw {
+		it := importItem{Title: r.Name, Notes: r.Notes, Fields: make(map[string]string), Created: r.CreationDate, Updated: r.RevisionDate}
+		for _, f := range r.Fields {
+			it.Fields[strings.ToLower(f.Name)] = f.Value
+		}
+		if r.Login != nil {
+			it.Fields["username"] = r.Login.Username
+			it.Fields["password"] = r.Login.Password
+			it.Fields["totp"] = r.Login.TOTP
+			if len(r.Login.URIs) > 0 {
+				it.URL = r.Login.URIs[0].URI
+			}
+		}
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+// parseCSVExport reads a headered CSV export. Header names are lowercased
+// and passed through rename; "title", "url" and "notes" fill the matching
+// importItem fields and every column is also available in Fields.
+func parseCSVExport(data []byte, rename map[string]string) ([]importItem, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := make([]string, len(records[0]))
+	for i, h := range records[0] {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if to, ok := rename[h]; ok {
+			h = to
+		}
+		header[i] = h
+	}
+
+	items := make([]importItem, 0, len(records)-1)
+	for _, rec := range records[1:] {
+		it := importItem{Fields: make(map[string]string)}
+		for i, v := range rec {
+			if i < len(header) {
+				it.Fields[header[i]] = v
+			}
+		}
+		it.Title, it.URL, it.Notes = it.Fields["title"], it.Fields["url"], it.Fields["notes"]
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+func init() {
+	importCmd.Flags().String("format", "", "Password manager format: 1password or bitwarden (omit for api-vault exports)")
+	importCmd.Flags().String("secret-field", "", "Export field to store as the secret (default: password, then credential)")
+	importCmd.Flags().StringP("type", "t", "", "API type for imported credentials")
+	importCmd.Flags().String("on-conflict", "skip", "What to do when a name already exists: skip, fail or overwrite")
+	importCmd.Flags().Bool("confirm-overwrites", false, "With --on-conflict overwrite, preview and confirm each replacement")
+	rootCmd.AddCommand(importCmd)
+}
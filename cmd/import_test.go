@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/busyrockin/api-vault/core"
+)
+
+func TestParse1PasswordJSONStream(t *testing.T) {
+	data := []byte(`{"title":"stripe","category":"API_CREDENTIAL","fields":[
+		{"id":"credential","label":"credential","value":"sk_live_1"},
+		{"id":"notesPlain","label":"notesPlain","purpose":"NOTES","value":"billing"}],
+		"urls":[{"href":"https://a.example"},{"primary":true,"href":"https://stripe.com"}]}
+	{"title":"github","fields":[{"id":"password","label":"password","purpose":"PASSWORD","value":"ghp_x"}]}`)
+
+	items, err := parse1Password(data)
+	if err != nil {
+		t.Fatalf("parse1Password: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+
+	cred, ok := items[0].credential(defaultSecretFields, "stripe")
+	if !ok {
+		t.Fatal("stripe: no secret found")
+	}
+	if *cred.SecretKey != "sk_live_1" || cred.Metadata != "billing" || *cred.URL != "https://stripe.com" {
+		t.Errorf("stripe: got secret=%q metadata=%q url=%q", *cred.SecretKey, cred.Metadata, *cred.URL)
+	}
+	if cred, ok := items[1].credential(defaultSecretFields, "github"); !ok || *cred.SecretKey != "ghp_x" {
+		t.Errorf("github: got %v, %v", cred, ok)
+	}
+}
+
+func TestParseBitwardenJSON(t *testing.T) {
+	data := []byte(`{"encrypted":false,"items":[
+		{"name":"openai","notes":"prod","creationDate":"2021-03-04T05:06:07.000Z","login":{"username":"me","password":"pw","uris":[{"uri":"https://openai.com"}]},
+		 "fields":[{"name":"API Key","value":"sk-abc"}]},
+		{"name":"note-only","notes":"nothing here"}]}`)
+
+	items, err := parseBitwarden(data)
+	if err != nil {
+		t.Fatalf("parseBitwarden: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+
+	cred, ok := items[0].credential([]string{"api key"}, "openai")
+	if !ok || *cred.SecretKey != "sk-abc" || *cred.URL != "https://openai.com" || cred.Metadata != "prod" {
+		t.Errorf("openai: got %+v, %v", cred, ok)
+	}
+	if got := cred.CreatedAt.Format("2006-01-02"); got != "2021-03-04" {
+		t.Errorf("created_at = %s, want 2021-03-04", got)
+	}
+	if _, ok := items[1].credential(defaultSecretFields, "x"); ok {
+		t.Error("item without a password should be skipped")
+	}
+
+	if _, err := parseBitwarden([]byte(`{"encrypted":true,"data":"..."}`)); err == nil {
+		t.Error("encrypted export should be rejected")
+	}
+}
+
+func TestParseCSVExports(t *testing.T) {
+	op := []byte("Title,Website,Username,Password,Notes\nanthropic,https://console.anthropic.com,me,sk-ant,\"multi\nline\"\n")
+	items, err := parse1Password(op)
+	if err != nil {
+		t.Fatalf("parse1Password csv: %v", err)
+	}
+	if len(items) != 1 || items[0].Title != "anthropic" || items[0].Fields["password"] != "sk-ant" ||
+		items[0].URL != "https://console.anthropic.com" || items[0].Notes != "multi\nline" {
+		t.Errorf("1password csv: got %+v", items)
+	}
+
+	bw := []byte("folder,favorite,type,name,notes,fields,reprompt,login_uri,login_username,login_password,login_totp\n" +
+		",,login,resend,,\"token: re_123\nregion: eu\",0,https://resend.com,me,pw,\n")
+	items, err = parseBitwarden(bw)
+	if err != nil {
+		t.Fatalf("parseBitwarden csv: %v", err)
+	}
+	if len(items) != 1 || items[0].Title != "resend" || items[0].Fields["token"] != "re_123" ||
+		items[0].Fields["password"] != "pw" || items[0].URL != "https://resend.com" {
+		t.Errorf("bitwarden csv: got %+v", items)
+	}
+}
+
+func TestOverwritePreviewMasksSecrets(t *testing.T) {
+	oldSecret, newSecret, env := "sk-old-secret-value-1", "sk-new-secret-value-2", "prod"
+	old := &core.Credential{Name: "svc", APIType: "openai", SecretKey: &oldSecret, Environment: &env}
+	incoming := &core.Credential{Name: "svc", APIType: "openai", SecretKey: &newSecret}
+
+	var buf bytes.Buffer
+	printOverwritePreview(&buf, old, incoming)
+	out := buf.String()
+
+	if strings.Contains(out, oldSecret) || strings.Contains(out, newSecret) {
+		t.Fatalf("preview leaks a secret:\n%s", out)
+	}
+	for _, want := range []string{"* environment", "prod → (none)", "* secret", "(21 chars)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("preview missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestParseDotEnv(t *testing.T) {
+	src := "# comment\n" +
+		"OPENAI_API_KEY=sk-plain # trailing comment\n" +
+		"export STRIPE_KEY = \"sk_live_\\\"q\\\"\"\n" +
+		"\n" +
+		"LITERAL='a#b \\n c'\n" +
+		"PEM=\"-----BEGIN KEY-----\n" +
+		"abc\n" +
+		"-----END KEY-----\"\n" +
+		"EMPTY=\n" +
+		"not a line\n" +
+		"OPENAI_API_KEY=again\n" +
+		"1BAD=x\n" +
+		"OPEN=\"never closed\n"
+
+	entries, err := parseDotEnv(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parseDotEnv: %v", err)
+	}
+	want := []struct {
+		line       int
+		key, value string
+		bad        bool
+	}{
+		{2, "OPENAI_API_KEY", "sk-plain", false},
+		{3, "STRIPE_KEY", `sk_live_"q"`, false},
+		{5, "LITERAL", `a#b \n c`, false},
+		{6, "PEM", "-----BEGIN KEY-----\nabc\n-----END KEY-----", false},
+		{9, "EMPTY", "", true},
+		{10, "", "", true},
+		{11, "OPENAI_API_KEY", "again", true},
+		{12, "", "", true},
+		{13, "OPEN", "", true},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.line != w.line || (e.err != nil) != w.bad || !w.bad && (e.key != w.key || e.value != w.value) {
+			t.Errorf("entry %d = line %d %q=%q err %v, want line %d %q=%q bad=%v",
+				i, e.line, e.key, e.value, e.err, w.line, w.key, w.value, w.bad)
+		}
+	}
+}
+
+func TestParseKeychainDump(t *testing.T) {
+	dump := `keychain: "/Users/me/Library/Keychains/login.keychain-db"
+version: 512
+class: "genp"
+attributes:
+    0x00000007 <blob>="openai"
+    "acct"<blob>="me@example.com"
+    "svce"<blob>="openai"
+data:
+"sk-a\\b\"c\303\251"
+keychain: "/Users/me/Library/Keychains/login.keychain-db"
+version: 512
+class: "genp"
+attributes:
+    "acct"<blob>=<NULL>
+    "svce"<blob>=0x73747269706500  "stripe\000"
+data:
+0x736B5F6C697665  "sk_live"
+keychain: "/Users/me/Library/Keychains/login.keychain-db"
+version: 512
+class: "inet"
+attributes:
+    "acct"<blob>="me"
+    "srvr"<blob>="example.com"
+data:
+"pw"
+`
+	items, err := parseKeychainDump(strings.NewReader(dump))
+	if err != nil {
+		t.Fatalf("parseKeychainDump: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("got %d items, want 3", len(items))
+	}
+	if it := items[0]; it.class != "genp" || it.service != "openai" || it.account != "me@example.com" ||
+		it.secret == nil || *it.secret != `sk-a\b"cé` {
+		t.Errorf("openai: got %+v", it)
+	}
+	if it := items[1]; it.service != "stripe" || it.account != "" || it.secret == nil || *it.secret != "sk_live" {
+		t.Errorf("stripe: got %+v", it)
+	}
+	if items[2].class != "inet" {
+		t.Errorf("class = %q, want inet", items[2].class)
+	}
+
+	// Without -d the dump has no passwords.
+	items, err = parseKeychainDump(strings.NewReader("keychain: \"k\"\nclass: \"genp\"\nattributes:\n    \"svce\"<blob>=\"x\"\n"))
+	if err != nil || len(items) != 1 || items[0].secret != nil {
+		t.Errorf("no data: got %+v, %v", items, err)
+	}
+}
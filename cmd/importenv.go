@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+var importEnvCmd = &cobra.Command{
+	Use:   "import-env <file>",
+	Short: "Import credentials from a .env file",
+	Long: `Store each KEY=value line of a .env file as a credential named KEY (after
+--prefix) with the value as its secret. Use - to read stdin, with the master
+password in API_VAULT_PASSWORD.
+
+Blank lines, # comments and a leading "export " are ignored. Values may be
+'single-quoted' (taken literally) or "double-quoted" (\n, \", \\ and \$
+escapes), and quoted values may span lines, e.g. a PEM key. An unquoted
+value ends at " #".
+
+Names that already exist in the vault, and lines with an empty value, are
+skipped with a warning; the rest are stored. --dry-run lists what would be
+added without writing anything.`,
+	Example: "  api-vault import-env .env --type openai --prefix dev-",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		apiType, _ := cmd.Flags().GetString("type")
+		prefix, _ := cmd.Flags().GetString("prefix")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if apiType == "" {
+			apiType = cfg.DefaultType
+		}
+
+		data, err := readImportFile(args[0])
+		if err != nil {
+			return err
+		}
+		entries, err := parseDotEnv(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("read %s: %w", args[0], err)
+		}
+
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		db.SetMaxSecretSize(cfg.MaxSecretSize)
+
+		added, skipped, failed := 0, 0, 0
+		for _, e := range entries {
+			if errors.Is(e.err, errEmptyEnvValue) {
+				skipped++
+				fmt.Fprintf(os.Stderr, "  - %s: empty value, skipped\n", prefix+e.key)
+				continue
+			}
+			if e.err != nil {
+				failed++
+				fmt.Fprintf(os.Stderr, "  ✗ line %d: %v\n", e.line, e.err)
+				continue
+			}
+			name := prefix + e.key
+			if dryRun {
+				_, err := db.GetMetadata(name)
+				switch {
+				case err == nil:
+					skipped++
+					fmt.Fprintf(os.Stderr, "  - %s: already exists, would skip\n", name)
+				case errors.Is(err, core.ErrNotFound):
+					added++
+					fmt.Fprintf(os.Stderr, "  + %s (%d chars)\n", name, len(e.value))
+				default:
+					return fmt.Errorf("check %q: %w", name, err)
+				}
+				continue
+			}
+
+			value := e.value
+			err := db.AddCredentialV2(&core.Credential{Name: name, APIType: apiType, SecretKey: &value})
+			switch {
+			case err == nil:
+				added++
+				fmt.Fprintf(os.Stderr, "  ✓ %s\n", name)
+			case errors.Is(err, core.ErrDuplicate):
+				skipped++
+				fmt.Fprintf(os.Stderr, "  - %s: already exists, skipped\n", name)
+			case errors.Is(err, core.ErrTooLarge):
+				failed++
+				fmt.Fprintf(os.Stderr, "  ✗ line %d: %s: %v\n", e.line, name, err)
+			default:
+				return fmt.Errorf("import %q: %w", name, err)
+			}
+		}
+
+		if dryRun {
+			fmt.Fprintf(os.Stderr, "Would import %d credentials (%d skipped, %d failed); nothing written\n", added, skipped, failed)
+		} else {
+			fmt.Fprintf(os.Stderr, "Imported %d credentials (%d skipped, %d failed)\n", added, skipped, failed)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d lines failed", failed, len(entries))
+		}
+		return nil
+	},
+}
+
+// errEmptyEnvValue marks a KEY= line; there is no secret to store.
+var errEmptyEnvValue = errors.New("empty value")
+
+// envEntry is one KEY=value assignment, or a line that didn't parse.
+type envEntry struct {
+	line       int
+	key, value string
+	err        error
+}
+
+// parseDotEnv reads .env assignments. A key assigned twice keeps its first
+// value; the repeat is reported as an error entry.
+func parseDotEnv(r io.Reader) ([]envEntry, error) {
+	var entries []envEntry
+	seen := make(map[string]int)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		text := strings.TrimSpace(strings.TrimSuffix(sc.Text(), "\r"))
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		start := n
+		text = strings.TrimPrefix(text, "export ")
+		key, rest, ok := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !isEnvName(key) {
+			entries = append(entries, envEntry{line: start, err: errors.New("expected KEY=value")})
+			continue
+		}
+
+		rest = strings.TrimLeft(rest, " \t")
+		var value string
+		var err error
+		if q := firstByte(rest); q == '"' || q == '\'' {
+			// A quoted value runs to its closing quote, across lines if need be.
+			body := rest[1:]
+			for {
+				var done bool
+				if value, done, err = unquoteEnv(body, q); done || err != nil {
+					break
+				}
+				if !sc.Scan() {
+					err = fmt.Errorf("unterminated %c quote", q)
+					break
+				}
+				n++
+				body += "\n" + strings.TrimSuffix(sc.Text(), "\r")
+			}
+		} else {
+			if i := strings.Index(rest, " #"); i >= 0 {
+				rest = rest[:i]
+			}
+			value = strings.TrimSpace(rest)
+		}
+
+		switch {
+		case err != nil:
+		case value == "":
+			err = errEmptyEnvValue
+		case seen[key] > 0:
+			err = fmt.Errorf("%s: already set on line %d", key, seen[key])
+		default:
+			seen[key] = start
+		}
+		entries = append(entries, envEntry{line: start, key: key, value: value, err: err})
+	}
+	return entries, sc.Err()
+}
+
+// unquoteEnv decodes body, the text after an opening quote q. done is false
+// while the closing quote hasn't been seen. Only whitespace or a comment
+// may follow it.
+func unquoteEnv(body string, q byte) (value string, done bool, err error) {
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case c == q:
+			if tail := strings.TrimSpace(body[i+1:]); tail != "" && !strings.HasPrefix(tail, "#") {
+				return "", true, fmt.Errorf("unexpected %q after closing quote", tail)
+			}
+			return b.String(), true, nil
+		case c == '\\' && q == '"' && i+1 < len(body):
+			i++
+			switch body[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$':
+				b.WriteByte(body[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(body[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", false, nil
+}
+
+// isEnvName reports whether s is a valid environment variable name.
+func isEnvName(s string) bool {
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		return false
+	}
+	for _, r := range s {
+		if r != '_' && (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func firstByte(s string) byte {
+	if s == "" {
+		return 0
+	}
+	return s[0]
+}
+
+func init() {
+	importEnvCmd.Flags().StringP("type", "t", "", "API type for every imported credential")
+	importEnvCmd.Flags().String("prefix", "", "Prepend this to every credential name")
+	importEnvCmd.Flags().Bool("dry-run", false, "List what would be imported without writing")
+	rootCmd.AddCommand(importEnvCmd)
+}
@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+var importKeychainCmd = &cobra.Command{
+	Use:   "import-keychain [dump-file]",
+	Short: "Import generic passwords from the macOS Keychain",
+	Long: `Import the Keychain's generic passwords (class genp) as credentials: the
+item's service becomes the credential name (after --prefix), its password
+the secret, and its account the notes, as "keychain account: <account>".
+Internet passwords, keys, certificates and items without a service are
+skipped.
+
+Without a file, the items are listed with /usr/bin/security and each
+selected password is read with 'security find-generic-password', so macOS
+asks whether to allow access (and to unlock the keychain if it is locked).
+Over SSH there is no dialog: run 'security unlock-keychain' first.
+
+A file is the output of 'security dump-keychain -d', which holds the
+passwords already; use - for stdin, with the master password in
+API_VAULT_PASSWORD. This also works off macOS.
+
+--filter keeps items whose service matches a regular expression. Names
+already in the vault are skipped with a warning. --dry-run lists what would
+be imported without reading any password or writing anything.`,
+	Example: "  api-vault import-keychain --filter '^(openai|stripe)' --dry-run\n  security dump-keychain -d > dump.txt && api-vault import-keychain dump.txt",
+	Args:    cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		apiType, _ := cmd.Flags().GetString("type")
+		prefix, _ := cmd.Flags().GetString("prefix")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		keychain, _ := cmd.Flags().GetString("keychain")
+		pattern, _ := cmd.Flags().GetString("filter")
+		if apiType == "" {
+			apiType = cfg.DefaultType
+		}
+		var filter *regexp.Regexp
+		if pattern != "" {
+			var err error
+			if filter, err = regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("--filter: %w", err)
+			}
+		}
+
+		var dump []byte
+		var err error
+		if len(args) == 1 {
+			dump, err = readImportFile(args[0])
+		} else {
+			dump, err = dumpKeychain(keychain)
+		}
+		if err != nil {
+			return err
+		}
+		items, err := parseKeychainDump(bytes.NewReader(dump))
+		if err != nil {
+			return fmt.Errorf("read keychain dump: %w", err)
+		}
+
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		db.SetMaxSecretSize(cfg.MaxSecretSize)
+
+		added, skipped, failed, ignored := 0, 0, 0, 0
+		for _, it := range items {
+			if it.class != "genp" || it.service == "" {
+				ignored++
+				continue
+			}
+			if filter != nil && !filter.MatchString(it.service) {
+				continue
+			}
+			name := prefix + it.service
+			if dryRun {
+				_, err := db.GetMetadata(name)
+				switch {
+				case err == nil:
+					skipped++
+					fmt.Fprintf(os.Stderr, "  - %s: already exists, would skip\n", name)
+				case errors.Is(err, core.ErrNotFound):
+					added++
+					fmt.Fprintf(os.Stderr, "  + %s (account %s)\n", name, orNone(it.account))
+				default:
+					return fmt.Errorf("check %q: %w", name, err)
+				}
+				continue
+			}
+
+			secret := it.secret
+			if secret == nil {
+				s, err := readKeychainPassword(keychain, it.service, it.account)
+				if err != nil {
+					failed++
+					fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", name, err)
+					continue
+				}
+				secret = &s
+			}
+			if *secret == "" {
+				skipped++
+				fmt.Fprintf(os.Stderr, "  - %s: empty password, skipped\n", name)
+				continue
+			}
+			var meta string
+			if it.account != "" {
+				meta = "keychain account: " + it.account
+			}
+			err := db.AddCredentialV2(&core.Credential{Name: name, APIType: apiType, SecretKey: secret, Metadata: meta})
+			switch {
+			case err == nil:
+				added++
+				fmt.Fprintf(os.Stderr, "  ✓ %s\n", name)
+			case errors.Is(err, core.ErrDuplicate):
+				skipped++
+				fmt.Fprintf(os.Stderr, "  - %s: already exists, skipped\n", name)
+			case errors.Is(err, core.ErrTooLarge):
+				failed++
+				fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", name, err)
+			default:
+				return fmt.Errorf("import %q: %w", name, err)
+			}
+		}
+
+		if ignored > 0 {
+			fmt.Fprintf(os.Stderr, "Ignored %d items that aren't generic passwords\n", ignored)
+		}
+		if dryRun {
+			fmt.Fprintf(os.Stderr, "Would import %d credentials (%d skipped); nothing written\n", added, skipped)
+		} else {
+			fmt.Fprintf(os.Stderr, "Imported %d credentials (%d skipped, %d failed)\n", added, skipped, failed)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d items failed", failed)
+		}
+		return nil
+	},
+}
+
+// securityPath is the macOS keychain tool.
+const securityPath = "/usr/bin/security"
+
+// dumpKeychain lists keychain's items (the default search list when "")
+// without their passwords.
+func dumpKeychain(keychain string) ([]byte, error) {
+	if runtime.GOOS != "darwin" {
+		return nil, fmt.Errorf("reading the Keychain needs macOS; pass the output of 'security dump-keychain -d' as a file instead")
+	}
+	args := []string{"dump-keychain"}
+	if keychain != "" {
+		args = append(args, keychain)
+	}
+	out, err := exec.Command(securityPath, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("security dump-keychain: %w", securityError(err))
+	}
+	return out, nil
+}
+
+// readKeychainPassword reads one generic password. macOS may show its
+// access (or unlock) dialog meanwhile.
+func readKeychainPassword(keychain, service, account string) (string, error) {
+	args := []string{"find-generic-password", "-s", service, "-a", account, "-w"}
+	if keychain != "" {
+		args = append(args, keychain)
+	}
+	out, err := exec.Command(securityPath, args...).Output()
+	if err != nil {
+		return "", securityError(err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// securityError turns a failed security run into its stderr message, with
+// a hint when the keychain is locked and can't prompt (e.g. over SSH).
+func securityError(err error) error {
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) {
+		return err
+	}
+	msg := strings.TrimSpace(string(exit.Stderr))
+	if strings.Contains(msg, "User interaction is not allowed") {
+		return fmt.Errorf("%s (keychain locked? run 'security unlock-keychain' first)", msg)
+	}
+	if msg == "" {
+		return err
+	}
+	return errors.New(msg)
+}
+
+// keychainItem is one item of a 'security dump-keychain' listing.
+type keychainItem struct {
+	class            string // "genp" for generic passwords
+	service, account string
+	secret           *string // from a -d dump; nil when not included
+}
+
+// keychainAttr matches an attribute line such as
+// `    "svce"<blob>="api.openai.com"`.
+var keychainAttr = regexp.MustCompile(`^\s+"(\w{4})"<\w+>=(.*)$`)
+
+// parseKeychainDump reads the items of a 'security dump-keychain' listing,
+// with -d or without.
+func parseKeychainDump(r io.Reader) ([]keychainItem, error) {
+	var items []keychainItem
+	var cur *keychainItem
+	inData := false
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "keychain: "):
+			items = append(items, keychainItem{})
+			cur, inData = &items[len(items)-1], false
+		case cur == nil:
+		case strings.HasPrefix(line, "class: "):
+			cur.class, _ = keychainValue(strings.TrimPrefix(line, "class: "))
+		case line == "data:":
+			inData = true
+		case inData:
+			if v, ok := keychainValue(line); ok {
+				cur.secret = &v
+			}
+			inData = false
+		default:
+			m := keychainAttr.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			// Some apps store attributes NUL-terminated.
+			v, _ := keychainValue(m[2])
+			v = strings.TrimRight(v, "\x00")
+			switch m[1] {
+			case "svce":
+				cur.service = v
+			case "acct":
+				cur.account = v
+			}
+		}
+	}
+	return items, sc.Err()
+}
+
+// keychainValue decodes a dumped value: "text" with \ooo octal escapes, or
+// 0xHEX optionally followed by its quoted rendering. ok is false for
+// <NULL> or anything unrecognized.
+func keychainValue(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0x") {
+		h, _, _ := strings.Cut(s[2:], " ")
+		b, err := hex.DecodeString(h)
+		return string(b), err == nil
+	}
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", false
+	}
+	s = s[1 : len(s)-1]
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && isOctal(s[i+1:i+4]) {
+			n, _ := strconv.ParseUint(s[i+1:i+4], 8, 8)
+			b.WriteByte(byte(n))
+			i += 3
+			continue
+		}
+		if s[i] == '\\' && i+1 < len(s) && (s[i+1] == '\\' || s[i+1] == '"') {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String(), true
+}
+
+func isOctal(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '7' {
+			return false
+		}
+	}
+	return true
+}
+
+func init() {
+	importKeychainCmd.Flags().StringP("type", "t", "", "API type for every imported credential")
+	importKeychainCmd.Flags().String("prefix", "", "Prepend this to every credential name")
+	importKeychainCmd.Flags().String("filter", "", "Only import items whose service matches this regular expression")
+	importKeychainCmd.Flags().String("keychain", "", "Keychain file to read (default: the user's search list)")
+	importKeychainCmd.Flags().Bool("dry-run", false, "List what would be imported without reading passwords or writing")
+	rootCmd.AddCommand(importKeychainCmd)
+}
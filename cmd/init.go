@@ -12,38 +12,125 @@ var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Create a new encrypted vault",
 	Args:  cobra.NoArgs,
+	Long: `Create a new encrypted vault.
+
+--kdf-iter and --page-size tune SQLCipher's own key derivation and page size.
+They are fixed at creation and recorded next to the vault in <vault>.cipher,
+which must stay alongside the vault file.
+
+--argon-time, --argon-memory and --argon-threads set the Argon2id cost of
+the field key, e.g. lower on a small device. They are stored in the vault;
+raise them later with 'api-vault upgrade-kdf'. --kdf scrypt derives the
+field key with scrypt instead (N=2^15, r=8, about 32 MiB), for machines that
+can't spare Argon2id's 64 MiB per open.
+
+--wal switches the vault to SQLite's write-ahead log, so readers and the
+writer don't block each other. It is recorded in the vault and reapplied on
+every open. While the vault is open, <vault>-wal and <vault>-shm sit next to
+it; copy them too if you copy the vault by hand (api-vault's own backups
+checkpoint first). WAL doesn't work on network filesystems. Off by default.
+
+A master password that is short, common or repetitive is refused with the
+reasons; --force creates the vault anyway.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		kdfIter, _ := cmd.Flags().GetInt("kdf-iter")
+		pageSize, _ := cmd.Flags().GetInt("page-size")
+		wal, _ := cmd.Flags().GetBool("wal")
+		force, _ := cmd.Flags().GetBool("force")
+		kdfName, _ := cmd.Flags().GetString("kdf")
+
+		kdf := core.DefaultArgon2Params()
+		if cmd.Flags().Changed("argon-time") {
+			kdf.Time, _ = cmd.Flags().GetUint32("argon-time")
+		}
+		if cmd.Flags().Changed("argon-threads") {
+			kdf.Threads, _ = cmd.Flags().GetUint8("argon-threads")
+		}
+		if cmd.Flags().Changed("argon-memory") {
+			s, _ := cmd.Flags().GetString("argon-memory")
+			var err error
+			if kdf.Memory, err = parseMemoryKiB(s); err != nil {
+				return err
+			}
+		}
+		if err := kdf.Validate(); err != nil {
+			return err
+		}
+		var deriver core.KeyDeriver = kdf
+		switch kdfName {
+		case core.KDFArgon2id:
+		case core.KDFScrypt:
+			for _, f := range []string{"argon-time", "argon-memory", "argon-threads"} {
+				if cmd.Flags().Changed(f) {
+					return fmt.Errorf("--%s only applies to --kdf argon2id", f)
+				}
+			}
+			deriver = core.DefaultScryptParams()
+		default:
+			return fmt.Errorf("--kdf must be argon2id or scrypt, got %q", kdfName)
+		}
+
 		if _, err := os.Stat(vaultPath); err == nil {
 			return fmt.Errorf("vault already exists at %s", vaultPath)
 		}
 
-		pw, err := readPassword("Choose master password: ")
-		if err != nil {
-			return err
-		}
-		pw2, err := readPassword("Confirm master password: ")
-		if err != nil {
-			return err
+		pw := os.Getenv("API_VAULT_PASSWORD")
+		if pw == "" {
+			var err error
+			pw, err = promptHiddenTwice("Choose master password: ", "Confirm master password: ", "passwords do not match")
+			if err != nil {
+				return err
+			}
 		}
-		if pw != pw2 {
-			return fmt.Errorf("passwords do not match")
+
+		if score, warnings := core.EstimatePasswordStrength(pw); score < core.StrongPasswordScore {
+			fmt.Fprintf(os.Stderr, "Weak master password (strength %d/4):\n", score)
+			for _, w := range warnings {
+				fmt.Fprintf(os.Stderr, "  - %s\n", w)
+			}
+			if !force {
+				return fmt.Errorf("master password is too weak; choose a longer one or pass --force")
+			}
 		}
 
 		if err := os.MkdirAll(vaultDir, 0700); err != nil {
 			return fmt.Errorf("create vault directory: %w", err)
 		}
 
-		db, err := core.NewDatabase(vaultPath, pw)
+		cs := core.CipherSettings{KDFIter: kdfIter, PageSize: pageSize}
+		opts := []core.Option{core.WithCipherSettings(cs), core.WithKDF(deriver)}
+		if wal {
+			opts = append(opts, core.WithWAL())
+		}
+		db, err := core.NewDatabase(vaultPath, pw, opts...)
 		if err != nil {
 			return fmt.Errorf("create vault: %w", err)
 		}
 		db.Close()
 
+		// SQLite creates files with the process umask; tighten to owner-only.
+		for _, p := range []string{vaultPath, vaultPath + ".cipher"} {
+			if err := os.Chmod(p, 0600); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("set vault permissions: %w", err)
+			}
+		}
+
 		fmt.Fprintf(os.Stderr, "Vault created at %s\n", vaultPath)
+		if weakKDF(deriver) && !cfg.WeakKDFOK {
+			fmt.Fprintln(os.Stderr, "Note: these Argon2id parameters are below the defaults; set weak_kdf_ok in the config file (or API_VAULT_WEAK_KDF_OK=1) to skip the reminder on each open")
+		}
 		return nil
 	},
 }
 
 func init() {
+	initCmd.Flags().Int("kdf-iter", 0, "SQLCipher PBKDF2 iterations (default: SQLCipher's 256000)")
+	initCmd.Flags().Int("page-size", 0, "SQLCipher page size in bytes (default: 4096)")
+	initCmd.Flags().String("kdf", core.KDFArgon2id, "Field key derivation: argon2id or scrypt")
+	initCmd.Flags().Uint32("argon-time", 0, "Argon2id passes for the field key")
+	initCmd.Flags().String("argon-memory", "", "Argon2id memory, e.g. 64MB")
+	initCmd.Flags().Uint8("argon-threads", 0, "Argon2id parallelism")
+	initCmd.Flags().Bool("wal", false, "Use write-ahead logging (see above)")
+	initCmd.Flags().Bool("force", false, "Create the vault even with a weak master password")
 	rootCmd.AddCommand(initCmd)
 }
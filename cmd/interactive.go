@@ -1,20 +1,27 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/atotto/clipboard"
 	"github.com/busyrockin/api-vault/core"
+	"github.com/busyrockin/api-vault/rotation"
 	"github.com/busyrockin/api-vault/ui"
 )
 
 type credential struct {
-	name    string
-	apiType string
-	created time.Time
+	name     string
+	apiType  string
+	icon     string
+	kind     core.SecretKind
+	created  time.Time
+	rotated  *time.Time
+	expires  *time.Time
+	disabled bool
 }
 
 type interactiveModel struct {
@@ -24,15 +31,57 @@ type interactiveModel struct {
 	filter      string
 	viewing     bool
 	viewContent string
+	viewKind    core.SecretKind
 	adding      bool
 	setup       setupModel
+	editing     bool
+	edit        editModel
 	status      string
 	err         error
+
+	// revealed shows the whole secret on the view screen until toggled off
+	// or revealTimeout passes; revealSeq discards the ticks of earlier
+	// reveals.
+	revealed      bool
+	revealSeq     int
+	revealTimeout time.Duration
+
+	// marked holds names selected with space for bulk delete (D), which
+	// asks for confirmation first.
+	marked          map[string]bool
+	confirmingBatch bool
+
+	// rotating names the credential whose rotation (r) is running in the
+	// background; spinner is the frame shown meanwhile. quitAfterRotate is
+	// set by q during a rotation, which must not be cut off half-saved.
+	rotating        string
+	spinner         int
+	quitAfterRotate bool
+
+	// path and modTime let the model notice writes from other processes.
+	path    string
+	modTime time.Time
+}
+
+// vaultPollInterval is how often interactive mode checks the vault file for
+// changes made outside this session.
+const vaultPollInterval = 2 * time.Second
+
+type vaultPollMsg struct{}
+
+func pollVault() tea.Cmd {
+	return tea.Tick(vaultPollInterval, func(time.Time) tea.Msg { return vaultPollMsg{} })
 }
 
 func newInteractiveModel(db *core.Database) (interactiveModel, error) {
 	m := interactiveModel{
-		db: db,
+		db:            db,
+		path:          vaultPath,
+		marked:        make(map[string]bool),
+		revealTimeout: time.Duration(cfg.RevealTimeout) * time.Second,
+	}
+	if fi, err := os.Stat(m.path); err == nil {
+		m.modTime = fi.ModTime()
 	}
 
 	if err := m.loadCredentials(); err != nil {
@@ -48,12 +97,28 @@ func (m *interactiveModel) loadCredentials() error {
 		return err
 	}
 
+	exists := make(map[string]bool, len(creds))
 	m.credentials = make([]credential, len(creds))
 	for i, c := range creds {
+		exists[c.Name] = true
+		icon := c.Config["icon"]
+		if icon == "" {
+			icon = ui.Icon(c.APIType)
+		}
 		m.credentials[i] = credential{
-			name:    c.Name,
-			apiType: c.APIType,
-			created: c.CreatedAt,
+			name:     c.Name,
+			apiType:  c.APIType,
+			icon:     icon,
+			kind:     core.SecretKind(c.Config[core.SecretKindConfigKey]),
+			created:  c.CreatedAt,
+			rotated:  c.LastRotated,
+			expires:  c.ExpiresAt,
+			disabled: c.Disabled,
+		}
+	}
+	for name := range m.marked {
+		if !exists[name] {
+			delete(m.marked, name)
 		}
 	}
 
@@ -76,23 +141,109 @@ func (m *interactiveModel) filteredCredentials() []credential {
 	return filtered
 }
 
+// refresh reloads the list, keeping the cursor on the same credential when
+// it still exists. A vault rekeyed or replaced underneath us can no longer
+// be read through the open handle, so that is reported instead of crashing.
+func (m *interactiveModel) refresh() {
+	var current string
+	if filtered := m.filteredCredentials(); m.cursor < len(filtered) {
+		current = filtered[m.cursor].name
+	}
+
+	if err := m.loadCredentials(); err != nil {
+		m.err = fmt.Errorf("reload failed (vault rekeyed or replaced? restart interactive mode): %w", err)
+		return
+	}
+	m.err = nil
+
+	filtered := m.filteredCredentials()
+	m.cursor = 0
+	for i, c := range filtered {
+		if c.name == current {
+			m.cursor = i
+			break
+		}
+	}
+}
+
 func (m interactiveModel) Init() tea.Cmd {
-	return nil
+	return pollVault()
 }
 
 func (m interactiveModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// Handled in every mode so the poll loop never stops.
+	if _, ok := msg.(vaultPollMsg); ok {
+		fi, err := os.Stat(m.path)
+		switch {
+		case err != nil:
+			m.err = fmt.Errorf("vault file %s is gone: %w", m.path, err)
+		case !fi.ModTime().Equal(m.modTime) && !m.adding && !m.viewing && !m.editing:
+			m.modTime = fi.ModTime()
+			m.refresh()
+		}
+		return m, pollVault()
+	}
+
+	switch msg := msg.(type) {
+	case spinnerMsg:
+		if m.rotating == "" {
+			return m, nil
+		}
+		m.spinner++
+		return m, spin()
+	case hideRevealMsg:
+		if msg.seq == m.revealSeq {
+			m.revealed = false
+		}
+		return m, nil
+	case rotateDoneMsg:
+		m.rotating = ""
+		m.refresh()
+		if msg.err != nil {
+			m.err = fmt.Errorf("rotate %s: %w", msg.name, msg.err)
+		} else {
+			m.status = fmt.Sprintf("✓ Rotated %s (%s)", msg.name, strings.Join(msg.fields, ", "))
+		}
+		if m.quitAfterRotate {
+			return m, tea.Quit
+		}
+		return m, nil
+	}
+
 	if m.adding {
 		return m.updateAdding(msg)
 	}
 	if m.viewing {
 		return m.updateViewing(msg)
 	}
+	if m.editing {
+		return m.updateEditing(msg)
+	}
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		// Clear status on any keypress
 		m.status = ""
 
+		if m.confirmingBatch {
+			m.confirmingBatch = false
+			if msg.String() == "y" || msg.String() == "Y" {
+				m.deleteMarked()
+			} else {
+				m.status = "Delete cancelled"
+			}
+			return m, nil
+		}
+
+		if m.rotating != "" {
+			// Keys wait for the rotation; quitting is deferred until it's
+			// saved, so the old key isn't lost with the new one unsaved.
+			if msg.String() == "ctrl+c" || msg.String() == "q" {
+				m.quitAfterRotate = true
+			}
+			return m, nil
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "q":
 			return m, tea.Quit
@@ -118,12 +269,22 @@ func (m interactiveModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return m, nil
 				}
 
-				if err := clipboard.WriteAll(key); err != nil {
+				if err := writeClipboard(key); err != nil {
 					m.err = fmt.Errorf("failed to copy to clipboard: %w", err)
 				}
 
 				m.viewing = true
 				m.viewContent = key
+				m.viewKind = cred.kind
+				if m.viewKind == "" {
+					m.viewKind = core.DetectSecretType(key)
+				}
+			}
+
+		case "R":
+			m.refresh()
+			if m.err == nil {
+				m.status = "✓ Refreshed"
 			}
 
 		case "a":
@@ -131,6 +292,28 @@ func (m interactiveModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.setup = newSetupModel(m.db)
 			return m, nil
 
+		case "e":
+			filtered := m.filteredCredentials()
+			if len(filtered) > 0 {
+				edit, err := newEditModel(m.db, filtered[m.cursor].name)
+				if err != nil {
+					m.err = err
+					return m, nil
+				}
+				m.editing = true
+				m.edit = edit
+			}
+
+		case "r":
+			filtered := m.filteredCredentials()
+			if len(filtered) > 0 {
+				cred := filtered[m.cursor]
+				m.err = nil
+				m.rotating = cred.name
+				m.spinner = 0
+				return m, tea.Batch(rotateInBackground(m.db, cred.name), spin())
+			}
+
 		case "d":
 			filtered := m.filteredCredentials()
 			if len(filtered) > 0 {
@@ -148,6 +331,22 @@ func (m interactiveModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 
+		case " ":
+			filtered := m.filteredCredentials()
+			if len(filtered) > 0 {
+				name := filtered[m.cursor].name
+				if m.marked[name] {
+					delete(m.marked, name)
+				} else {
+					m.marked[name] = true
+				}
+			}
+
+		case "D":
+			if len(m.marked) > 0 {
+				m.confirmingBatch = true
+			}
+
 		case "backspace":
 			if len(m.filter) > 0 {
 				m.filter = m.filter[:len(m.filter)-1]
@@ -165,6 +364,62 @@ func (m interactiveModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// spinnerFrames animate the status line while a rotation runs.
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+type spinnerMsg struct{}
+
+func spin() tea.Cmd {
+	return tea.Tick(100*time.Millisecond, func(time.Time) tea.Msg { return spinnerMsg{} })
+}
+
+// rotateDoneMsg reports a rotation started with r.
+type rotateDoneMsg struct {
+	name   string
+	fields []string
+	err    error
+}
+
+// rotateInBackground rotates name with its api type's plugin off the UI
+// goroutine. Nothing can be prompted for, so rotateOne's pluginConfig
+// supplies all plugin config from the credential's stored Config and
+// API_VAULT_ROTATE_<FIELD>; a plugin that needs more reports it as an error.
+func rotateInBackground(db *core.Database, name string) tea.Cmd {
+	return func() tea.Msg {
+		reg := rotation.GetGlobalRegistry()
+		opts := rotateOpts{rotatedBy: rotatedByDefault()}
+		ctx, cancel := context.WithTimeout(context.Background(), rotateTimeout)
+		defer cancel()
+		_, result, err := rotateOne(ctx, db, reg, name, opts)
+		if err != nil {
+			return rotateDoneMsg{name: name, err: err}
+		}
+		return rotateDoneMsg{name: name, fields: rotatedFields(result)}
+	}
+}
+
+// deleteMarked deletes every marked credential, reporting how many went and
+// the first failure, then reloads the list.
+func (m *interactiveModel) deleteMarked() {
+	deleted := 0
+	var firstErr error
+	for name := range m.marked {
+		if err := m.db.DeleteCredential(name); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("delete %q: %w", name, err)
+			}
+			continue
+		}
+		deleted++
+		delete(m.marked, name)
+	}
+	m.refresh()
+	if firstErr != nil {
+		m.err = firstErr
+	}
+	m.status = fmt.Sprintf("✓ Deleted %d credentials", deleted)
+}
+
 func (m interactiveModel) updateAdding(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
@@ -191,6 +446,30 @@ func (m interactiveModel) updateAdding(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+func (m interactiveModel) updateEditing(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			m.editing = false
+			m.status = "Edit cancelled"
+			return m, nil
+		case "ctrl+c":
+			return m, tea.Quit
+		}
+	}
+
+	m.edit, _ = m.edit.Update(msg)
+
+	if m.edit.done {
+		m.editing = false
+		m.status = fmt.Sprintf("✓ Updated %s", m.edit.name)
+		m.refresh()
+	}
+
+	return m, nil
+}
+
 func (m interactiveModel) updateViewing(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
@@ -198,12 +477,24 @@ func (m interactiveModel) updateViewing(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "ctrl+c", "q", "esc", "enter":
 			m.viewing = false
 			m.viewContent = ""
+			m.revealed = false
+			return m, nil
+		case " ", "v":
+			m.revealed = !m.revealed
+			m.revealSeq++
+			if m.revealed && m.revealTimeout > 0 {
+				seq := m.revealSeq
+				return m, tea.Tick(m.revealTimeout, func(time.Time) tea.Msg { return hideRevealMsg{seq} })
+			}
 			return m, nil
 		}
 	}
 	return m, nil
 }
 
+// hideRevealMsg ends the reveal numbered seq.
+type hideRevealMsg struct{ seq int }
+
 func (m interactiveModel) View() string {
 	if m.adding {
 		return m.setup.View()
@@ -211,6 +502,9 @@ func (m interactiveModel) View() string {
 	if m.viewing {
 		return m.renderViewing()
 	}
+	if m.editing {
+		return m.edit.View()
+	}
 
 	var b strings.Builder
 
@@ -221,7 +515,15 @@ func (m interactiveModel) View() string {
 	b.WriteString("\n\n")
 
 	// Status message
-	if m.status != "" {
+	if m.rotating != "" {
+		frame := spinnerFrames[m.spinner%len(spinnerFrames)]
+		line := fmt.Sprintf("%s Rotating %s…", frame, m.rotating)
+		if m.quitAfterRotate {
+			line += " (quitting when done)"
+		}
+		b.WriteString(ui.SubtitleStyle.Render(line))
+		b.WriteString("\n\n")
+	} else if m.status != "" {
 		b.WriteString(ui.Success.Render(m.status))
 		b.WriteString("\n\n")
 	}
@@ -244,10 +546,21 @@ func (m interactiveModel) View() string {
 		b.WriteString(ui.NormalStyle.Render("No credentials found"))
 	} else {
 		for i, cred := range filtered {
-			status := m.getStatus(cred.created)
+			status := m.getStatus(cred)
 			statusStr := m.formatStatus(status)
 
-			line := fmt.Sprintf("%s  %s  %s", statusStr, cred.name, ui.Muted.Render(cred.apiType))
+			age := ui.Muted.Render(fmt.Sprintf("%-14s", core.HumanAge(cred.created)))
+			line := fmt.Sprintf("%s %s  %s %s  %s", statusStr, age, cred.icon, cred.name, ui.Muted.Render(cred.apiType))
+			if cred.disabled {
+				line = ui.Muted.Render(fmt.Sprintf("[-]  %s %s  %s (disabled)", cred.icon, cred.name, cred.apiType))
+			}
+			if len(m.marked) > 0 {
+				box := "☐ "
+				if m.marked[cred.name] {
+					box = "☑ "
+				}
+				line = box + line
+			}
 
 			if i == m.cursor {
 				b.WriteString(ui.SelectedStyle.Render("❯ " + line))
@@ -260,7 +573,11 @@ func (m interactiveModel) View() string {
 
 	// Help
 	b.WriteString("\n")
-	b.WriteString(ui.HelpStyle.Render("[↑↓/jk] Navigate  [Enter] Copy  [a] Add  [d] Delete  [Type] Filter  [q] Quit"))
+	if m.confirmingBatch {
+		b.WriteString(ui.StatusWarningStyle.Render(fmt.Sprintf("Delete %d marked credentials? [y/N]", len(m.marked))))
+		return ui.BoxStyle.Render(b.String())
+	}
+	b.WriteString(ui.HelpStyle.Render("[↑↓/jk] Navigate  [Enter] Copy  [a] Add  [e] Edit  [r] Rotate  [d] Delete  [Space] Mark  [D] Delete marked  [R] Refresh  [Type] Filter  [q] Quit"))
 
 	return ui.BoxStyle.Render(b.String())
 }
@@ -272,24 +589,47 @@ func (m interactiveModel) renderViewing() string {
 	b.WriteString("\n\n")
 	b.WriteString(ui.Success.Render("✓ Copied to clipboard"))
 	b.WriteString("\n\n")
-	b.WriteString(ui.SubtitleStyle.Render("Preview:"))
+	label := "Preview:"
+	if kind := m.viewKind.Describe(); kind != "" {
+		label = fmt.Sprintf("Preview (%s):", kind)
+	}
+	b.WriteString(ui.SubtitleStyle.Render(label))
 	b.WriteString("\n")
 
 	// Show first and last few chars
 	preview := m.viewContent
-	if len(preview) > 40 {
+	if m.revealed {
+		warning := "⚠ Shown in plaintext"
+		if m.revealTimeout > 0 {
+			warning += fmt.Sprintf(" (hides after %s)", m.revealTimeout)
+		}
+		b.WriteString(ui.StatusWarningStyle.Render(warning))
+		b.WriteString("\n")
+	} else if len(preview) > 40 {
 		preview = preview[:15] + "..." + preview[len(preview)-15:]
 	}
 	b.WriteString(ui.NormalStyle.Render(preview))
 
 	b.WriteString("\n\n")
-	b.WriteString(ui.HelpStyle.Render("[Enter/Esc] Back"))
+	toggle := "[Space/v] Reveal"
+	if m.revealed {
+		toggle = "[Space/v] Hide"
+	}
+	b.WriteString(ui.HelpStyle.Render(toggle + "  [Enter/Esc] Back"))
 
 	return ui.BoxStyle.Render(b.String())
 }
 
-func (m interactiveModel) getStatus(created time.Time) string {
-	age := time.Since(created)
+func (m interactiveModel) getStatus(cred credential) string {
+	if cred.expires != nil && time.Now().After(*cred.expires) {
+		return "expired"
+	}
+	// A rotation resets the key's age.
+	base := cred.created
+	if cred.rotated != nil {
+		base = *cred.rotated
+	}
+	age := time.Since(base)
 
 	if age < 7*24*time.Hour {
 		return "recent"
@@ -311,6 +651,8 @@ func (m interactiveModel) formatStatus(status string) string {
 		return ui.StatusWarningStyle.Render("[⚠]")
 	case "old":
 		return ui.StatusErrorStyle.Render("[✗]")
+	case "expired":
+		return ui.StatusErrorStyle.Render("[!]")
 	default:
 		return "[?]"
 	}
@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/busyrockin/api-vault/rotation"
+	"github.com/busyrockin/api-vault/ui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func pressKeys(m tea.Model, ks ...string) tea.Model {
+	for _, k := range ks {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case "backspace":
+			msg = tea.KeyMsg{Type: tea.KeyBackspace}
+		}
+		m, _ = m.Update(msg)
+	}
+	return m
+}
+
+func TestInteractiveEdit(t *testing.T) {
+	db := tempVault(t)
+	sk, env := "secret", "dev"
+	if err := db.AddCredentialV2(&core.Credential{Name: "svc", APIType: "openai", SecretKey: &sk, Environment: &env}); err != nil {
+		t.Fatalf("AddCredentialV2: %v", err)
+	}
+	m, err := newInteractiveModel(db)
+	if err != nil {
+		t.Fatalf("newInteractiveModel: %v", err)
+	}
+
+	got := pressKeys(m, "e", "x", "esc").(interactiveModel)
+	if got.editing {
+		t.Fatal("still editing after esc")
+	}
+	if c, _ := db.GetMetadata("svc"); c.APIType != "openai" {
+		t.Fatalf("cancelled edit saved api type %q", c.APIType)
+	}
+
+	// Type field: "openai" → "openai2"; environment: "dev" → "prod".
+	got = pressKeys(m, "e", "2", "enter", "backspace", "backspace", "backspace", "p", "r", "o", "d", "enter").(interactiveModel)
+	if got.editing || got.status != "✓ Updated svc" {
+		t.Fatalf("after save: editing %v, status %q, err %v", got.editing, got.status, got.edit.err)
+	}
+	c, err := db.GetMetadata("svc")
+	if err != nil {
+		t.Fatalf("GetMetadata: %v", err)
+	}
+	if c.APIType != "openai2" || deref(c.Environment) != "prod" {
+		t.Fatalf("saved type %q env %q, want openai2 prod", c.APIType, deref(c.Environment))
+	}
+	if got.credentials[0].apiType != "openai2" {
+		t.Fatalf("list not refreshed: %+v", got.credentials[0])
+	}
+	if v, err := db.GetCredential("svc"); err != nil || v != sk {
+		t.Fatalf("secret after edit = %q, %v", v, err)
+	}
+}
+
+func TestInteractiveRotate(t *testing.T) {
+	db := tempVault(t)
+	old, noPlugin := "old-secret", "x"
+	db.AddCredentialV2(&core.Credential{Name: "svc", APIType: "tui-fake", SecretKey: &old, Config: map[string]string{"org": "o-1"}})
+	db.AddCredentialV2(&core.Credential{Name: "zzz", APIType: "nothing-registered", SecretKey: &noPlugin})
+
+	secret := "new-secret"
+	reg := rotation.GetGlobalRegistry()
+	plugin := rotation.NewTestPlugin("tui-fake", &rotation.Result{NewSecretKey: &secret})
+	plugin.Schema = rotation.ConfigSchema{Fields: []rotation.ConfigField{{Name: "org", Required: true}}}
+	reg.Register(plugin)
+	t.Cleanup(func() { reg.Unregister("tui-fake") })
+
+	m, err := newInteractiveModel(db)
+	if err != nil {
+		t.Fatalf("newInteractiveModel: %v", err)
+	}
+	// rotate runs r on the selected row and feeds the background command's
+	// result back, as the Bubble Tea runtime would.
+	rotate := func(m tea.Model) interactiveModel {
+		m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+		if got := m.(interactiveModel); got.rotating == "" || !strings.Contains(got.View(), "Rotating") {
+			t.Fatal("no spinner while rotating")
+		}
+		batch := cmd().(tea.BatchMsg)
+		m, _ = m.Update(batch[0]())
+		return m.(interactiveModel)
+	}
+
+	got := rotate(m)
+	if got.rotating != "" || got.err != nil || !strings.HasPrefix(got.status, "✓ Rotated svc") {
+		t.Fatalf("after rotate: rotating %q, status %q, err %v", got.rotating, got.status, got.err)
+	}
+	if v, err := db.GetCredential("svc"); err != nil || v != secret {
+		t.Fatalf("secret after rotate = %q, %v", v, err)
+	}
+	if got.credentials[0].rotated == nil {
+		t.Fatal("list not refreshed after rotate")
+	}
+	if cfgs := plugin.Configs(); len(cfgs) != 1 || cfgs[0]["org"] != "o-1" {
+		t.Fatalf("plugin config = %v, want the stored org", cfgs)
+	}
+
+	// q during a rotation waits for it to be saved, then quits.
+	qm, cmd := tea.Model(got).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	done := cmd().(tea.BatchMsg)[0]
+	qm, cmd = qm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if cmd != nil || !strings.Contains(qm.View(), "quitting when done") {
+		t.Fatal("q should wait for the rotation")
+	}
+	if _, cmd = qm.Update(done()); cmd == nil {
+		t.Fatal("no quit after the rotation finished")
+	} else if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Fatal("rotateDoneMsg should quit after q")
+	}
+
+	got = rotate(pressKeys(got, "j"))
+	if !errors.Is(got.err, core.ErrNoPlugin) {
+		t.Fatalf("no plugin: err %v, want ErrNoPlugin", got.err)
+	}
+}
+
+func TestInteractiveReveal(t *testing.T) {
+	db := tempVault(t)
+	secret := "sk-" + strings.Repeat("abcdefgh", 6)
+	db.AddCredential("svc", secret, "openai")
+	m, err := newInteractiveModel(db)
+	if err != nil {
+		t.Fatalf("newInteractiveModel: %v", err)
+	}
+
+	got := pressKeys(m, "enter").(interactiveModel)
+	if !got.viewing || strings.Contains(got.View(), secret) {
+		t.Fatal("view screen shows the whole secret before reveal")
+	}
+
+	next, hide := got.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	got = next.(interactiveModel)
+	if !got.revealed || !strings.Contains(got.View(), secret) || !strings.Contains(got.View(), "plaintext") {
+		t.Fatal("space did not reveal the secret with a warning")
+	}
+	if hide == nil {
+		t.Fatal("no auto-hide scheduled")
+	}
+
+	// Hide and reveal again: the first reveal's tick must not hide the second.
+	stale := hideRevealMsg{got.revealSeq}
+	got = pressKeys(got, "v", "v").(interactiveModel)
+	next, _ = got.Update(stale)
+	if got = next.(interactiveModel); !got.revealed {
+		t.Fatal("stale tick hid the current reveal")
+	}
+	next, _ = got.Update(hideRevealMsg{got.revealSeq})
+	if got = next.(interactiveModel); got.revealed || strings.Contains(got.View(), secret) {
+		t.Fatal("auto-hide did not hide the secret")
+	}
+
+	got = pressKeys(got, "v", "esc").(interactiveModel)
+	if got.viewing || got.revealed {
+		t.Fatal("leaving the view screen kept the reveal")
+	}
+}
+
+func TestInteractiveIcons(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.db")
+	db, err := core.NewDatabase(path, "test-password")
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.AddCredential("a-openai", "sk", "OpenAI")
+	db.AddCredential("b-other", "sk", "no-such-provider")
+
+	t.Setenv("API_VAULT_PASSWORD", "test-password")
+	if _, err := runCLI(t, "add", "c-custom", "--secret", "sk", "--type", "openai", "--icon", "🦄",
+		"--vault-path", path, "--insecure-permissions"); err != nil {
+		t.Fatalf("add --icon: %v", err)
+	}
+	if c, _ := db.GetMetadata("c-custom"); c.Config["icon"] != "🦄" {
+		t.Fatalf("add --icon stored config %v", c.Config)
+	}
+
+	m, err := newInteractiveModel(db)
+	if err != nil {
+		t.Fatalf("newInteractiveModel: %v", err)
+	}
+	view := m.View()
+	for _, want := range []string{
+		"🤖 a-openai",                // by api_type, ignoring case
+		ui.DefaultIcon + " b-other", // unknown api_type
+		"🦄 c-custom",                // Config["icon"] beats the api_type's
+	} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view lacks %q:\n%s", want, view)
+		}
+	}
+}
+
+func TestInteractiveReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.db")
+	db, err := core.NewDatabase(path, "test-password")
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.AddCredential("b", "sk", "t")
+	db.AddCredential("c", "sk", "t")
+	old := vaultPath
+	vaultPath = path
+	t.Cleanup(func() { vaultPath = old })
+
+	// other writes the vault as another process would.
+	other, err := core.NewDatabase(path, "test-password")
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() { other.Close() })
+	touch := func() {
+		t.Helper()
+		later := time.Now().Add(time.Minute)
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := newInteractiveModel(db)
+	if err != nil {
+		t.Fatalf("newInteractiveModel: %v", err)
+	}
+	got := pressKeys(m, "j").(interactiveModel) // on c
+
+	other.AddCredential("a", "sk", "t")
+	touch()
+	next, cmd := got.Update(vaultPollMsg{})
+	got = next.(interactiveModel)
+	if cmd == nil {
+		t.Fatal("the poll stopped")
+	}
+	if len(got.credentials) != 3 || got.filteredCredentials()[got.cursor].name != "c" {
+		t.Fatalf("after poll: %d credentials, cursor on %q; want 3 with the cursor still on c",
+			len(got.credentials), got.filteredCredentials()[got.cursor].name)
+	}
+
+	// Nothing reloads under the view screen; the change is picked up after.
+	got = pressKeys(got, "enter").(interactiveModel)
+	other.AddCredential("d", "sk", "t")
+	touch()
+	next, _ = got.Update(vaultPollMsg{})
+	if got = next.(interactiveModel); len(got.credentials) != 3 {
+		t.Fatalf("reloaded while viewing: %d credentials", len(got.credentials))
+	}
+	got = pressKeys(got, "esc", "R").(interactiveModel)
+	if len(got.credentials) != 4 || got.status != "✓ Refreshed" {
+		t.Fatalf("after R: %d credentials, status %q", len(got.credentials), got.status)
+	}
+
+	os.Rename(path, path+".moved")
+	next, _ = got.Update(vaultPollMsg{})
+	if got = next.(interactiveModel); got.err == nil || !strings.Contains(got.err.Error(), "is gone") {
+		t.Fatalf("vault removed: err %v", got.err)
+	}
+}
+
+func TestInteractiveBulkDelete(t *testing.T) {
+	db := tempVault(t)
+	for _, name := range []string{"a", "b", "c"} {
+		db.AddCredential(name, "sk", "t")
+	}
+	m, err := newInteractiveModel(db)
+	if err != nil {
+		t.Fatalf("newInteractiveModel: %v", err)
+	}
+
+	// Mark a and c; b is marked and unmarked again.
+	got := pressKeys(m, " ", "j", " ", " ", "j", " ").(interactiveModel)
+	if len(got.marked) != 2 || !got.marked["a"] || !got.marked["c"] {
+		t.Fatalf("marked = %v, want a and c", got.marked)
+	}
+	if view := got.View(); !strings.Contains(view, "☑ ") || !strings.Contains(view, "☐ ") {
+		t.Fatalf("marks not shown:\n%s", view)
+	}
+
+	got = pressKeys(got, "D").(interactiveModel)
+	if !got.confirmingBatch || !strings.Contains(got.View(), "Delete 2 marked credentials? [y/N]") {
+		t.Fatal("D should ask before deleting")
+	}
+	got = pressKeys(got, "n").(interactiveModel)
+	if got.confirmingBatch || got.status != "Delete cancelled" || len(got.marked) != 2 || len(got.credentials) != 3 {
+		t.Fatalf("after n: status %q, %d marked, %d credentials", got.status, len(got.marked), len(got.credentials))
+	}
+
+	got = pressKeys(got, "D", "y").(interactiveModel)
+	if got.err != nil || got.status != "✓ Deleted 2 credentials" || len(got.marked) != 0 {
+		t.Fatalf("after y: status %q, err %v, marked %v", got.status, got.err, got.marked)
+	}
+	if len(got.credentials) != 1 || got.credentials[0].name != "b" {
+		t.Fatalf("credentials left = %+v, want only b", got.credentials)
+	}
+	if _, err := db.GetCredential("a"); !errors.Is(err, core.ErrNotFound) {
+		t.Errorf("a still in the vault: %v", err)
+	}
+
+	// D with nothing marked does nothing, and marks on credentials deleted
+	// elsewhere are dropped on reload.
+	if got = pressKeys(got, "D").(interactiveModel); got.confirmingBatch {
+		t.Fatal("D with nothing marked asked to delete")
+	}
+	got = pressKeys(got, " ").(interactiveModel)
+	db.DeleteCredential("b")
+	if got = pressKeys(got, "R").(interactiveModel); len(got.marked) != 0 {
+		t.Fatalf("stale marks kept: %v", got.marked)
+	}
+}
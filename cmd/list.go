@@ -1,10 +1,17 @@
 package cmd
 
 import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
+	"time"
 
+	"github.com/busyrockin/api-vault/core"
+	"github.com/busyrockin/api-vault/ui"
 	"github.com/spf13/cobra"
 )
 
@@ -15,6 +22,9 @@ var listCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		interactive, _ := cmd.Flags().GetBool("interactive")
 		if interactive {
+			if outputJSON() {
+				return fmt.Errorf("--interactive has no JSON output")
+			}
 			return runInteractive()
 		}
 
@@ -24,27 +34,183 @@ var listCmd = &cobra.Command{
 		}
 		defer db.Close()
 
-		creds, err := db.ListCredentials()
+		tags, _ := cmd.Flags().GetStringArray("tag")
+		each := db.EachCredential
+		if len(tags) > 0 {
+			each = func(fn func(core.Credential) error) error { return eachTagged(db, tags, fn) }
+		}
+
+		format, _ := cmd.Flags().GetString("format")
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			if cmd.Flags().Changed("format") && format != "json" {
+				return fmt.Errorf("--json conflicts with --format %s", format)
+			}
+			format = "json"
+		}
+		switch format {
+		case "table":
+		case "json":
+			return streamJSON(each)
+		case "csv":
+			return streamCSV(each)
+		default:
+			return fmt.Errorf("--format must be table, json or csv, got %q", format)
+		}
+		namesOnly, _ := cmd.Flags().GetBool("names-only")
+		if nul, _ := cmd.Flags().GetBool("null"); nul {
+			return streamNames(each, 0)
+		}
+		if namesOnly {
+			return streamNames(each, '\n')
+		}
+
+		var creds []core.Credential
+		err = each(func(c core.Credential) error {
+			creds = append(creds, c)
+			return nil
+		})
 		if err != nil {
 			return fmt.Errorf("list credentials: %w", err)
 		}
 
 		if len(creds) == 0 {
-			fmt.Fprintln(os.Stderr, "No credentials stored.")
+			if len(tags) > 0 {
+				fmt.Fprintln(os.Stderr, "No credentials with those tags.")
+			} else {
+				fmt.Fprintln(os.Stderr, "No credentials stored.")
+			}
 			return nil
 		}
 
+		relative, _ := cmd.Flags().GetBool("relative")
+
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "NAME\tTYPE\tCREATED")
+		fmt.Fprintln(w, "NAME\tTYPE\tCREATED\tTAGS")
 		for _, c := range creds {
-			fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, c.APIType, c.CreatedAt.Format("2006-01-02"))
+			created := c.CreatedAt.Format("2006-01-02")
+			if relative {
+				created = core.HumanAge(c.CreatedAt)
+			}
+			var note string
+			switch {
+			case c.Disabled:
+				note = "  " + ui.Muted.Render("(disabled)")
+			case c.Protected:
+				note = "  " + ui.Muted.Render("(passphrase)")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s%s\n", c.Name, c.APIType, created, strings.Join(c.Tags, ","), note)
 		}
 		w.Flush()
 		return nil
 	},
 }
 
+// listEntry is the --format json shape of a credential. No secrets.
+type listEntry struct {
+	Name         string     `json:"name"`
+	APIType      string     `json:"api_type"`
+	Environment  *string    `json:"environment,omitempty"`
+	Metadata     string     `json:"metadata,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	LastRotated  *time.Time `json:"last_rotated,omitempty"`
+	LastAccessed *time.Time `json:"last_accessed,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	Disabled     bool       `json:"disabled,omitempty"`
+	Protected    bool       `json:"protected,omitempty"`
+	SecretKind   string     `json:"secret_kind,omitempty"`
+	Tags         []string   `json:"tags,omitempty"`
+}
+
+// eachFunc walks credentials in name order; db.EachCredential or a
+// filtered equivalent.
+type eachFunc func(fn func(core.Credential) error) error
+
+// eachTagged is an eachFunc over the credentials carrying every tag.
+func eachTagged(db *core.Database, tags []string, fn func(core.Credential) error) error {
+	creds, err := db.ListByTag(tags...)
+	if err != nil {
+		return err
+	}
+	for _, c := range creds {
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamJSON writes the credential list as a JSON array one element at a
+// time, so memory use stays flat regardless of vault size.
+func streamJSON(each eachFunc) error {
+	enc := json.NewEncoder(os.Stdout)
+	sep := "["
+	err := each(func(c core.Credential) error {
+		fmt.Print(sep)
+		sep = ","
+		return enc.Encode(listEntry{c.Name, c.APIType, c.Environment, c.Metadata, c.CreatedAt, c.UpdatedAt, c.LastRotated, c.LastAccessed, c.ExpiresAt,
+			c.Disabled, c.Protected, c.Config[core.SecretKindConfigKey], c.Tags})
+	})
+	if err != nil {
+		return fmt.Errorf("list credentials: %w", err)
+	}
+	if sep == "[" {
+		fmt.Print(sep)
+	}
+	fmt.Println("]")
+	return nil
+}
+
+// csvHeader is the --format csv header; timestamps are RFC 3339 and an
+// empty last_rotated or last_accessed means never.
+var csvHeader = []string{"name", "api_type", "environment", "created_at", "updated_at", "last_rotated", "last_accessed"}
+
+// streamCSV writes the credential list as CSV, one row at a time. No
+// secrets.
+func streamCSV(each eachFunc) error {
+	w := csv.NewWriter(os.Stdout)
+	w.Write(csvHeader)
+	err := each(func(c core.Credential) error {
+		var env, rotated, accessed string
+		if c.Environment != nil {
+			env = *c.Environment
+		}
+		if c.LastRotated != nil {
+			rotated = c.LastRotated.Format(time.RFC3339)
+		}
+		if c.LastAccessed != nil {
+			accessed = c.LastAccessed.Format(time.RFC3339)
+		}
+		return w.Write([]string{c.Name, c.APIType, env, c.CreatedAt.Format(time.RFC3339), c.UpdatedAt.Format(time.RFC3339), rotated, accessed})
+	})
+	if err != nil {
+		return fmt.Errorf("list credentials: %w", err)
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// streamNames writes one credential name per line (or per NUL with sep 0,
+// for xargs -0), with no header or styling.
+func streamNames(each eachFunc, sep byte) error {
+	w := bufio.NewWriter(os.Stdout)
+	err := each(func(c core.Credential) error {
+		w.WriteString(c.Name)
+		return w.WriteByte(sep)
+	})
+	if err != nil {
+		return fmt.Errorf("list credentials: %w", err)
+	}
+	return w.Flush()
+}
+
 func init() {
 	rootCmd.AddCommand(listCmd)
 	listCmd.Flags().BoolP("interactive", "i", false, "Run in interactive mode")
+	listCmd.Flags().String("format", "table", "Output format: table, json or csv")
+	listCmd.Flags().Bool("json", false, "Same as --format json")
+	listCmd.Flags().Bool("names-only", false, "Print only names, one per line, for shell loops")
+	listCmd.Flags().BoolP("null", "0", false, "Print only names, NUL-separated, for xargs -0")
+	listCmd.Flags().StringArray("tag", nil, "Only credentials with this tag (repeatable; all must match, any case)")
+	listCmd.Flags().Bool("relative", false, `Show ages ("3 months ago") instead of dates`)
 }
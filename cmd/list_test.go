@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/busyrockin/api-vault/core"
+)
+
+// listVault makes a vault for list tests and returns the flags selecting it.
+func listVault(t *testing.T) []string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vault.db")
+	db, err := core.NewDatabase(path, "test-password")
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	sk, env := "sk-never-listed", "prod"
+	for _, c := range []*core.Credential{
+		{Name: "github", APIType: "github", SecretKey: &sk, Tags: []string{"ci"}},
+		{Name: "openai prod", APIType: "openai", SecretKey: &sk, Environment: &env, Tags: []string{"ci", "ml"}},
+		{Name: "stripe", APIType: "stripe", SecretKey: &sk},
+	} {
+		if err := db.AddCredentialV2(c); err != nil {
+			t.Fatalf("AddCredentialV2: %v", err)
+		}
+	}
+	db.Close()
+	t.Setenv("API_VAULT_PASSWORD", "test-password")
+	return []string{"--vault-path", path, "--insecure-permissions"}
+}
+
+func TestListNamesOnly(t *testing.T) {
+	vault := listVault(t)
+	list := func(args ...string) string {
+		t.Helper()
+		out, err := runCLI(t, append(append([]string{"list"}, args...), vault...)...)
+		if err != nil {
+			t.Fatalf("list %v: %v", args, err)
+		}
+		return out
+	}
+
+	if got := list("--names-only"); got != "github\nopenai prod\nstripe\n" {
+		t.Errorf("--names-only = %q", got)
+	}
+	// Names with spaces survive xargs -0.
+	if got := list("-0"); got != "github\x00openai prod\x00stripe\x00" {
+		t.Errorf("-0 = %q", got)
+	}
+	if got := list("--null", "--tag", "ML"); got != "openai prod\x00" {
+		t.Errorf("-0 --tag ML = %q", got)
+	}
+	if got := list("--names-only", "--tag", "nothing"); got != "" {
+		t.Errorf("--names-only for no matches = %q, want nothing at all", got)
+	}
+}
+
+func TestListFormats(t *testing.T) {
+	vault := listVault(t)
+	list := func(args ...string) (string, error) {
+		return runCLI(t, append(append([]string{"list"}, args...), vault...)...)
+	}
+
+	jsonOut, err := list("--format", "json")
+	if err != nil {
+		t.Fatalf("--format json: %v", err)
+	}
+	var entries []listEntry
+	if err := json.Unmarshal([]byte(jsonOut), &entries); err != nil {
+		t.Fatalf("--format json printed %q: %v", jsonOut, err)
+	}
+	if len(entries) != 3 || entries[1].Name != "openai prod" || entries[1].APIType != "openai" ||
+		deref(entries[1].Environment) != "prod" || !slices.Equal(entries[1].Tags, []string{"ci", "ml"}) || entries[1].CreatedAt.IsZero() {
+		t.Fatalf("json entries = %+v", entries)
+	}
+	if out, _ := list("--json"); out != jsonOut {
+		t.Errorf("--json differs from --format json:\n%s\n%s", out, jsonOut)
+	}
+	if out, err := list("--json", "--tag", "nothing"); err != nil || out != "[]\n" {
+		t.Errorf("json with no matches = %q, %v", out, err)
+	}
+
+	out, err := list("--format", "csv", "--tag", "ci")
+	if err != nil {
+		t.Fatalf("--format csv: %v", err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("--format csv printed %q: %v", out, err)
+	}
+	if len(rows) != 3 || !slices.Equal(rows[0], csvHeader) || rows[2][0] != "openai prod" || rows[2][2] != "prod" || rows[2][5] != "" {
+		t.Fatalf("csv rows = %q", rows)
+	}
+	if _, err := time.Parse(time.RFC3339, rows[1][3]); err != nil {
+		t.Errorf("created_at %q isn't RFC 3339: %v", rows[1][3], err)
+	}
+
+	if strings.Contains(out+jsonOut, "sk-never-listed") {
+		t.Fatal("a secret appeared in list output")
+	}
+	if _, err := list("--format", "yaml"); err == nil {
+		t.Error("--format yaml should be refused")
+	}
+	if _, err := list("--json", "--format", "csv"); err == nil {
+		t.Error("--json with --format csv should be refused")
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var wrapOutput sync.Once
+
+// runCLI runs the api-vault command line with args, as Execute would, and
+// returns what it wrote to stdout. Flags are reset afterwards.
+func runCLI(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	wrapOutput.Do(func() { withOutput(rootCmd) })
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	rootCmd.SetArgs(args)
+	err = rootCmd.Execute()
+	if err != nil && outputJSON() {
+		PrintError(os.Stderr, err)
+	}
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+
+	rootCmd.SetArgs(nil)
+	resetFlags(rootCmd)
+	return string(out), err
+}
+
+func resetFlags(c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if s, ok := f.Value.(pflag.SliceValue); ok {
+			s.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	c.Flags().VisitAll(reset)
+	c.PersistentFlags().VisitAll(reset)
+	for _, sub := range c.Commands() {
+		resetFlags(sub)
+	}
+}
+
+func TestOutputJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.db")
+	db, err := core.NewDatabase(path, "test-password")
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	secret, u := "sk-secret", "https://api.example.com"
+	if err := db.AddCredentialV2(&core.Credential{Name: "svc", APIType: "t", SecretKey: &secret, URL: &u}); err != nil {
+		t.Fatalf("AddCredentialV2: %v", err)
+	}
+	db.Close()
+	t.Setenv("API_VAULT_PASSWORD", "test-password")
+	t.Setenv("API_VAULT_NO_AGENT", "1")
+	vault := []string{"--vault-path", path, "--insecure-permissions", "-o", "json"}
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"get", "svc", "--field", "url"}, `{"url":"https://api.example.com"}` + "\n"},
+		{[]string{"get", "svc"}, `{"secret":"sk-secret","url":"https://api.example.com"}` + "\n"},
+		{[]string{"rename", "svc", "svc2"}, `{"ok":true}` + "\n"},
+		{[]string{"show", "svc2"}, `{"ok":false,"error":"--output json isn't supported by 'api-vault show'","code":"error"}` + "\n"},
+	} {
+		out, _ := runCLI(t, append(tc.args, vault...)...)
+		if out != tc.want {
+			t.Errorf("%v: stdout = %q, want %q", tc.args, out, tc.want)
+		}
+	}
+}
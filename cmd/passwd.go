@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var passwdCmd = &cobra.Command{
+	Use:   "passwd",
+	Short: "Change the master password",
+	Long: `Change the master password. The vault file is re-keyed and every stored
+key is re-encrypted under a key derived from the new password.
+
+The new password is read from API_VAULT_NEW_PASSWORD or prompted for twice.
+--dry-run checks that every stored key decrypts and estimates how long the
+re-key would take, without changing anything.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		db, old, err := openVaultPassword()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		if dryRun {
+			rep, err := db.CheckRekey(old)
+			if err != nil {
+				return fmt.Errorf("dry run: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "Credentials: %d (%d encrypted fields)\n", rep.Credentials, rep.Blobs)
+			fmt.Fprintf(os.Stderr, "Vault size:  %d bytes\n", rep.FileSize)
+			fmt.Fprintf(os.Stderr, "Estimated re-key time: %s\n", rep.Estimate.Round(time.Millisecond))
+			if !rep.OK() {
+				for _, name := range rep.Failed {
+					fmt.Fprintf(os.Stderr, "  ✗ %s: cannot decrypt\n", name)
+				}
+				return fmt.Errorf("%d credential(s) would block the password change", len(rep.Failed))
+			}
+			fmt.Fprintln(os.Stderr, "✓ All fields decrypt; the password change would succeed.")
+			return nil
+		}
+
+		newPw, err := readNewPassword()
+		if err != nil {
+			return err
+		}
+		if err := db.ChangeMasterPassword(old, newPw); err != nil {
+			return fmt.Errorf("change password: %w", err)
+		}
+		fmt.Fprintln(os.Stderr, "Master password changed.")
+		return nil
+	},
+}
+
+func readNewPassword() (string, error) {
+	if pw := os.Getenv("API_VAULT_NEW_PASSWORD"); pw != "" {
+		return pw, nil
+	}
+	return promptHiddenTwice("New master password: ", "Confirm new password: ", "passwords do not match")
+}
+
+func init() {
+	passwdCmd.Flags().Bool("dry-run", false, "Verify the vault can be re-keyed without changing anything")
+	rootCmd.AddCommand(passwdCmd)
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/busyrockin/api-vault/ui"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
+)
+
+var errPromptCanceled = errors.New("canceled")
+
+// passwordModel is a masked password input. With two prompts it asks for
+// the value twice and starts over when the entries differ.
+type passwordModel struct {
+	prompts  []string
+	mismatch string // error shown when the confirmation differs
+	stage    int
+	first    string
+	input    []rune
+	value    string
+	err      string
+	canceled bool
+	done     bool
+}
+
+func newPasswordModel(mismatch string, prompts ...string) passwordModel {
+	return passwordModel{prompts: prompts, mismatch: mismatch}
+}
+
+func (m passwordModel) Init() tea.Cmd { return nil }
+
+func (m passwordModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch key.Type {
+	case tea.KeyCtrlC, tea.KeyEsc:
+		m.canceled = true
+		return m, tea.Quit
+
+	case tea.KeyEnter:
+		if len(m.input) == 0 {
+			m.err = "password cannot be empty"
+			return m, nil
+		}
+		entered := string(m.input)
+		m.input = nil
+		m.err = ""
+		if m.stage == 0 && len(m.prompts) > 1 {
+			m.first = entered
+			m.stage = 1
+			return m, nil
+		}
+		if m.stage == 1 && entered != m.first {
+			m.stage, m.first = 0, ""
+			m.err = m.mismatch + ", try again"
+			return m, nil
+		}
+		m.value = entered
+		m.done = true
+		return m, tea.Quit
+
+	case tea.KeyBackspace:
+		if len(m.input) > 0 {
+			m.input = m.input[:len(m.input)-1]
+		}
+
+	case tea.KeyCtrlU:
+		m.input = nil
+
+	case tea.KeySpace:
+		m.input = append(m.input, ' ')
+
+	case tea.KeyRunes:
+		m.input = append(m.input, key.Runes...)
+	}
+	return m, nil
+}
+
+func (m passwordModel) View() string {
+	if m.done || m.canceled {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(ui.Primary.Render(m.prompts[m.stage]))
+	b.WriteString(ui.Muted.Render(strings.Repeat("•", len(m.input))))
+	b.WriteString("█\n")
+	if m.err != "" {
+		b.WriteString(ui.StatusErrorStyle.Render("✗ "+m.err) + "\n")
+	}
+	b.WriteString(ui.HelpStyle.Render("enter: submit • ctrl+u: clear • esc: cancel"))
+	return b.String()
+}
+
+// promptHidden reads a password without echoing it. On a terminal it uses
+// the masked bubbletea input so prompts match the TUI; otherwise it falls
+// back to term.ReadPassword.
+func promptHidden(prompt string) (string, error) {
+	if !promptIsTTY() {
+		return readHidden(prompt)
+	}
+	return runPasswordPrompt(newPasswordModel("", prompt))
+}
+
+// promptHiddenTwice reads a password and its confirmation. The TUI re-asks
+// on a mismatch; the fallback fails with mismatch as the error.
+func promptHiddenTwice(prompt, confirmPrompt, mismatch string) (string, error) {
+	if promptIsTTY() {
+		return runPasswordPrompt(newPasswordModel(mismatch, prompt, confirmPrompt))
+	}
+	pw, err := readHidden(prompt)
+	if err != nil {
+		return "", err
+	}
+	again, err := readHidden(confirmPrompt)
+	if err != nil {
+		return "", err
+	}
+	if again != pw {
+		return "", errors.New(mismatch)
+	}
+	return pw, nil
+}
+
+func promptIsTTY() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+func runPasswordPrompt(m passwordModel) (string, error) {
+	final, err := tea.NewProgram(m, tea.WithOutput(os.Stderr)).Run()
+	if err != nil {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+	pm := final.(passwordModel)
+	if pm.canceled {
+		return "", fmt.Errorf("read password: %w", errPromptCanceled)
+	}
+	return pm.value, nil
+}
+
+func readHidden(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	b, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+	if len(b) == 0 {
+		return "", fmt.Errorf("password cannot be empty")
+	}
+	return string(b), nil
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func typeInto(m passwordModel, s string) passwordModel {
+	for _, r := range s {
+		next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		m = next.(passwordModel)
+	}
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	return next.(passwordModel)
+}
+
+func TestPasswordModelConfirm(t *testing.T) {
+	m := newPasswordModel("passwords do not match", "Password: ", "Confirm: ")
+
+	m = typeInto(m, "")
+	if m.err == "" || m.stage != 0 {
+		t.Fatalf("empty entry: err=%q stage=%d", m.err, m.stage)
+	}
+
+	m = typeInto(typeInto(m, "hunter2"), "hunter3")
+	if m.done || m.stage != 0 || m.err == "" {
+		t.Fatalf("mismatch should restart: done=%v stage=%d err=%q", m.done, m.stage, m.err)
+	}
+
+	m = typeInto(typeInto(m, "hunter2"), "hunter2")
+	if !m.done || m.value != "hunter2" {
+		t.Fatalf("done=%v value=%q", m.done, m.value)
+	}
+	if v := m.View(); v != "" {
+		t.Errorf("finished prompt still renders %q", v)
+	}
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+var purgeCmd = &cobra.Command{
+	Use:   "purge --expired",
+	Short: "Delete expired credentials",
+	Long: `Delete every credential whose expiry (add --expires/--ttl) has passed,
+along with its rotation history. --dry-run lists them without deleting.
+You are asked to confirm unless --yes is given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		expired, _ := cmd.Flags().GetBool("expired")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		yes, _ := cmd.Flags().GetBool("yes")
+		if !expired {
+			return fmt.Errorf("nothing to purge; pass --expired")
+		}
+
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		now := time.Now()
+		if dryRun || !yes {
+			creds, err := db.ListExpiring(0)
+			if err != nil {
+				return fmt.Errorf("list credentials: %w", err)
+			}
+			n := 0
+			for _, c := range creds {
+				if c.Expired(now) {
+					n++
+					fmt.Fprintf(os.Stderr, "  %s (expired %s)\n", c.Name, c.ExpiresAt.Format("2006-01-02"))
+				}
+			}
+			switch {
+			case n == 0:
+				fmt.Fprintln(os.Stderr, "No expired credentials.")
+				return nil
+			case dryRun:
+				fmt.Fprintf(os.Stderr, "Would delete %d expired credential(s)\n", n)
+				return nil
+			case !confirm(fmt.Sprintf("Delete %d expired credential(s)?", n)):
+				fmt.Fprintln(os.Stderr, "Aborted.")
+				return nil
+			}
+		}
+
+		n, err := db.PurgeExpiredFunc(now, func(name string, stats core.DeleteStats) {
+			if stats.Rotations > 0 {
+				fmt.Fprintf(os.Stderr, "- Deleted %q and %d rotation records\n", name, stats.Rotations)
+			} else {
+				fmt.Fprintf(os.Stderr, "- Deleted %q\n", name)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("purge: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "✓ Purged %d expired credential(s)\n", n)
+		return nil
+	},
+}
+
+func init() {
+	purgeCmd.Flags().Bool("expired", false, "Delete credentials past their expiry")
+	purgeCmd.Flags().Bool("dry-run", false, "List what would be deleted without deleting")
+	purgeCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+	rootCmd.AddCommand(purgeCmd)
+}
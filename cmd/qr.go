@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/skip2/go-qrcode"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// maxQRSecret is the longest secret qr renders, in bytes. It fits a
+// version 26 code at medium error correction, about the largest a phone
+// reliably scans off a terminal; the format itself goes up to 2331.
+const maxQRSecret = 1000
+
+var qrCmd = &cobra.Command{
+	Use:   "qr <name>",
+	Short: "Show a secret as a QR code in the terminal",
+	Long: `Render a credential's secret as a QR code drawn with Unicode half blocks,
+for moving a short token or TOTP seed to a phone without the clipboard.
+
+The code is only ever written to the terminal: qr refuses to run when
+stdout is redirected. It is the secret in plaintext on screen, so clear the
+screen (and the scrollback) once it's scanned.
+
+--size scales each module to that many character cells (1–4); try 2 if
+the phone struggles with a small font. Secrets over 1000 bytes are refused.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		size, _ := cmd.Flags().GetInt("size")
+		if size < 1 || size > 4 {
+			return fmt.Errorf("--size must be 1 to 4, got %d", size)
+		}
+		if !term.IsTerminal(int(os.Stdout.Fd())) {
+			return fmt.Errorf("stdout is not a terminal; qr only draws the secret on screen (use 'api-vault get' to pipe it)")
+		}
+
+		name := args[0]
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		key, err := db.GetCredential(name)
+		if errors.Is(err, core.ErrPassphraseRequired) {
+			key, err = unlockSecret(db, name)
+		}
+		db.Close()
+		if err != nil {
+			return getErr(name, err)
+		}
+		if key == "" {
+			return fmt.Errorf("credential %q has no secret", name)
+		}
+
+		code, err := secretQR(key)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Warning: the QR code below is the secret in plaintext; clear the screen when done")
+		fmt.Print(renderQR(code.Bitmap(), size))
+		return nil
+	},
+}
+
+// secretQR encodes secret at medium error correction, refusing anything
+// over maxQRSecret.
+func secretQR(secret string) (*qrcode.QRCode, error) {
+	if len(secret) > maxQRSecret {
+		return nil, fmt.Errorf("secret is %d bytes; a QR code holds at most %d here", len(secret), maxQRSecret)
+	}
+	code, err := qrcode.New(secret, qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("encode QR code: %w", err)
+	}
+	return code, nil
+}
+
+// renderQR draws bitmap (true for dark modules) with each module scaled to
+// size×size half-block pixels, two pixel rows per line. Colors are set
+// explicitly, dark on white, so the code scans on a dark terminal too.
+func renderQR(bitmap [][]bool, size int) string {
+	dark := func(y, x int) bool {
+		y, x = y/size, x/size
+		return y < len(bitmap) && bitmap[y][x]
+	}
+	height, width := len(bitmap)*size, 0
+	if len(bitmap) > 0 {
+		width = len(bitmap[0]) * size
+	}
+
+	var b strings.Builder
+	for y := 0; y < height; y += 2 {
+		b.WriteString("\x1b[30;47m")
+		for x := 0; x < width; x++ {
+			switch top, bottom := dark(y, x), dark(y+1, x); {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+	return b.String()
+}
+
+func init() {
+	qrCmd.Flags().Int("size", 1, "Character cells per QR module, 1 to 4")
+	rootCmd.AddCommand(qrCmd)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderQR(t *testing.T) {
+	bitmap := [][]bool{
+		{true, false},
+		{true, true},
+		{false, true},
+	}
+	line := func(cells string) string { return "\x1b[30;47m" + cells + "\x1b[0m\n" }
+
+	if got, want := renderQR(bitmap, 1), line("█▄")+line(" ▀"); got != want {
+		t.Errorf("size 1:\n got %q\nwant %q", got, want)
+	}
+	want := line("██  ") + line("████") + line("  ██")
+	if got := renderQR(bitmap, 2); got != want {
+		t.Errorf("size 2:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestSecretQRLimit(t *testing.T) {
+	if _, err := secretQR(strings.Repeat("x", maxQRSecret)); err != nil {
+		t.Errorf("secret at the limit: %v", err)
+	}
+	_, err := secretQR(strings.Repeat("x", maxQRSecret+1))
+	if err == nil || !strings.Contains(err.Error(), "at most 1000") {
+		t.Errorf("secret over the limit: got %v", err)
+	}
+}
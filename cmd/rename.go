@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+var renameCmd = &cobra.Command{
+	Use:   "rename <old> <new>",
+	Short: "Rename a credential, keeping its history",
+	Long: `Rename a credential in place. Its secret, rotation history, tags and
+timestamps stay as they are, unlike deleting and re-adding it.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, to := args[0], args[1]
+		if from == to {
+			return fmt.Errorf("<old> and <new> are the same")
+		}
+
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		if err := db.RenameCredential(from, to); err != nil {
+			switch {
+			case errors.Is(err, core.ErrNotFound):
+				return userErr(err, "credential %q not found", from)
+			case errors.Is(err, core.ErrDuplicate):
+				return userErr(err, "credential %q already exists", to)
+			case errors.Is(err, core.ErrRotationInProgress):
+				return userErr(err, "credential %q is being rotated; try again when it finishes", from)
+			}
+			return fmt.Errorf("rename credential: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Renamed %q to %q\n", from, to)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renameCmd)
+}
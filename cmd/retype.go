@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var retypeCmd = &cobra.Command{
+	Use:   "retype <from> <to>",
+	Short: "Change the API type of every credential with a given type",
+	Long: `Rename an API type across the vault, e.g. to normalize open-ai and
+openai_api to openai after importing from several sources. Matching on <from>
+is exact. Rotation plugin lookup ignores case but not spelling, so this is how
+to make a plugin apply to such credentials.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, to := args[0], args[1]
+		if from == to {
+			return fmt.Errorf("<from> and <to> are the same")
+		}
+
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		n, err := db.RemapAPIType(from, to)
+		if err != nil {
+			return fmt.Errorf("retype: %w", err)
+		}
+		if n == 0 {
+			fmt.Fprintf(os.Stderr, "No credentials have type %q\n", from)
+			return nil
+		}
+		fmt.Fprintf(os.Stderr, "Changed %d credentials from %q to %q\n", n, from, to)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(retypeCmd)
+}
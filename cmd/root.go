@@ -1,21 +1,74 @@
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
 
 const version = "0.1.0"
 
 var rootCmd = &cobra.Command{
-	Use:     "api-vault",
-	Short:   "Secure credential vault for AI agents",
+	Use:   "api-vault",
+	Short: "Secure credential vault for AI agents",
+	Long: `Secure credential vault for AI agents.
+
+Exit codes:
+  0   success
+  1   other error
+  3   credential not found
+  4   credential already exists
+  5   wrong master password
+  6   decryption failed
+  7   integrity check failed
+  8   credential is disabled
+  9   extra or export passphrase missing or wrong
+  10  no rotation plugin for the credential's type
+  11  secret too large
+  12  cipher settings differ from the vault's
+  13  another rotation of the credential is in progress
+  14  attestation signature invalid or from another vault
+  15  vault is open read-only (--read-only)`,
 	Version: version,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if o, _ := cmd.Flags().GetString("output"); o != "text" && o != "json" {
+			return fmt.Errorf("--output must be text or json, got %q", o)
+		}
+		if err := prepareOutput(cmd); err != nil {
+			return err
+		}
+		s, err := loadSettings(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		cfg = s
+		vaultPath = cfg.VaultPath
+		vaultDir = filepath.Dir(cfg.VaultPath)
+		return nil
+	},
 }
 
 func init() {
 	rootCmd.SilenceUsage = true
 	rootCmd.SilenceErrors = true
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
+
+	rootCmd.PersistentFlags().String("config", "", "Config file (default ~/.api-vault/config.json)")
+	rootCmd.PersistentFlags().String("profile", "", "Named settings profile from the config file")
+	rootCmd.PersistentFlags().String("vault", "", "Use the named vault, <name>.db in the vault directory")
+	rootCmd.PersistentFlags().String("vault-path", "", "Use the vault file at this path")
+	rootCmd.PersistentFlags().Bool("insecure-permissions", false, "Open the vault even if its file or directory is group/world accessible")
+	rootCmd.PersistentFlags().Bool("no-backup", false, "Don't back up the vault before a schema migration that rewrites data")
+	rootCmd.PersistentFlags().Bool("read-only", false, "Open the vault locked against changes (also API_VAULT_READ_ONLY=1)")
+	rootCmd.PersistentFlags().Int("max-secret-size", core.DefaultMaxSecretSize, "Maximum secret size in bytes")
+	rootCmd.PersistentFlags().Int("password-attempts", 3, "Master password prompts before giving up (interactive only)")
+	rootCmd.PersistentFlags().Bool("json-errors", false, `On failure, print {"error": ..., "code": ...} to stderr`)
+	rootCmd.PersistentFlags().StringP("output", "o", "text", `Result format: text or json ({"ok": ..., "error": ..., "code": ...} on stdout)`)
 }
 
 func Execute() error {
+	withOutput(rootCmd)
 	return rootCmd.Execute()
 }
@@ -2,8 +2,11 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/user"
+	"slices"
 	"strings"
 	"time"
 
@@ -13,87 +16,530 @@ import (
 )
 
 var rotateCmd = &cobra.Command{
-	Use:   "rotate <name>",
+	Use:   "rotate [name]",
 	Short: "Rotate credentials for a stored service",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		name := args[0]
+	Long: `Rotate credentials for a stored service using the plugin registered for
+its api_type. --fields asks the provider to rotate only those fields, e.g.
+--fields public_key refreshes only the public/anon key; plugins that can
+only rotate all their fields together refuse it.
 
-		db, err := openVault()
+The rotation is recorded in history as done by --as, which defaults to the
+OS user name.
+
+--verify checks the saved key against the provider (for plugins that can).
+If it fails while the old key is still valid, you are offered a rollback.
+
+With --type, every credential of that api_type is rotated; failures are
+reported per credential and do not stop the rest. --due does the same for
+every credential whose rotation schedule (add --rotate-every 90d) has run
+out, counting from its last rotation or, if never rotated, its creation.
+
+Plugin settings come from --config key=value (repeatable) and
+--config-file, a JSON object; pairs override the file. A setting given
+neither way is taken from the credential's own config if it isn't secret
+(store it with 'update <name> --config key=value'), else from
+API_VAULT_ROTATE_<FIELD> (e.g. API_VAULT_ROTATE_ORGANIZATION_ID), so --type
+and --due can rotate unattended. Keys are checked against the plugin's
+schema and every missing required one is listed before anything is
+rotated. Fields the plugin marks secret are refused on the command line,
+where ps would show them: set API_VAULT_ROTATE_<FIELD> (e.g.
+API_VAULT_ROTATE_ADMIN_KEY), put them in the file, or leave them out to be
+prompted.
+
+--test --plugin <name> is a harness for plugin development: it runs the
+plugin's Validate and Rotate against a throwaway credential built from
+--secret, --public-key, --url, --env and --plugin-config, and prints the
+result. The vault is not opened. The plugin really runs, so use a
+disposable key.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		apiType, _ := cmd.Flags().GetString("type")
+		fieldNames, _ := cmd.Flags().GetStringSlice("fields")
+		verify, _ := cmd.Flags().GetBool("verify")
+		testMode, _ := cmd.Flags().GetBool("test")
+		due, _ := cmd.Flags().GetBool("due")
+		pluginName, _ := cmd.Flags().GetString("plugin")
+		configFile, _ := cmd.Flags().GetString("config-file")
+		configPairs, _ := cmd.Flags().GetStringArray("config")
+		pluginCfg, argKeys, err := loadRotateConfig(configFile, configPairs)
 		if err != nil {
 			return err
 		}
-		defer db.Close()
 
-		cred, err := db.GetCredentialV2(name)
-		if err != nil {
-			return fmt.Errorf("credential %q: %w", name, err)
+		if testMode {
+			if pluginName == "" {
+				return fmt.Errorf("--test needs --plugin")
+			}
+			if outputJSON() {
+				return fmt.Errorf("--test has no JSON output")
+			}
+			if len(args) > 0 || apiType != "" || verify || due {
+				return fmt.Errorf("--test runs against a synthetic credential; drop the name, --type, --due and --verify")
+			}
+			reg := rotation.GetGlobalRegistry()
+			if err := preparePluginConfig(reg, pluginName, pluginCfg, argKeys, nil); err != nil {
+				return err
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), rotateTimeout)
+			defer cancel()
+			return testPlugin(ctx, os.Stdout, reg, pluginName, pluginTestInfo(cmd, pluginName), pluginCfg)
+		}
+		if pluginName != "" {
+			return fmt.Errorf("--plugin is only used with --test")
 		}
 
-		plugin, ok := rotation.GetGlobalRegistry().Get(cred.APIType)
-		if !ok {
-			return fmt.Errorf("no rotation plugin for api_type %q (available: %s)",
-				cred.APIType, strings.Join(rotation.GetGlobalRegistry().List(), ", "))
+		switch {
+		case due && (apiType != "" || len(args) > 0):
+			return fmt.Errorf("--due picks the credentials itself; drop the name and --type")
+		case due && verify:
+			return fmt.Errorf("--verify works on a single credential, not --due")
+		case due && (configFile != "" || len(configPairs) > 0):
+			return fmt.Errorf("--due can involve several plugins; store their settings with 'update --config' or set API_VAULT_ROTATE_<FIELD> instead of --config")
+		case apiType != "" && len(args) > 0:
+			return fmt.Errorf("pass either a credential name or --type, not both")
+		case apiType == "" && len(args) == 0 && !due:
+			return fmt.Errorf("a credential name, --type or --due is required")
+		case apiType != "" && verify:
+			return fmt.Errorf("--verify works on a single credential, not --type")
 		}
 
-		info := rotation.CredentialInfo{
-			Name:      cred.Name,
-			APIType:   cred.APIType,
-			SecretKey: cred.SecretKey,
-			PublicKey: cred.PublicKey,
-			URL:       cred.URL,
-			Config:    cred.Config,
+		as, _ := cmd.Flags().GetString("as")
+		if as == "" {
+			as = rotatedByDefault()
+		}
+		reason, _ := cmd.Flags().GetString("reason")
+		opts := rotateOpts{rotatedBy: as, reason: reason, config: pluginCfg}
+		for _, f := range fieldNames {
+			opts.fields = append(opts.fields, rotation.RotatableField(f))
 		}
 
-		if err := plugin.Validate(info); err != nil {
-			return fmt.Errorf("validation: %w", err)
+		db, err := openVault()
+		if err != nil {
+			return err
 		}
+		defer db.Close()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
+		if due {
+			return rotateDue(db, opts)
+		}
+		if apiType != "" {
+			if err := preparePluginConfig(rotation.GetGlobalRegistry(), apiType, pluginCfg, argKeys, nil); err != nil {
+				return err
+			}
+			return rotateByType(db, apiType, opts)
+		}
 
-		result, err := plugin.Rotate(ctx, info, nil)
+		name := args[0]
+		cred, err := db.GetMetadata(name)
 		if err != nil {
-			return fmt.Errorf("rotate: %w", err)
+			return fmt.Errorf("credential %q: %w", name, err)
+		}
+		if err := preparePluginConfig(rotation.GetGlobalRegistry(), cred.APIType, pluginCfg, argKeys, cred); err != nil {
+			return err
 		}
+		ctx, cancel := context.WithTimeout(context.Background(), rotateTimeout)
+		defer cancel()
 
-		coreResult := &core.RotationResult{
-			NewSecretKey: result.NewSecretKey,
-			NewPublicKey: result.NewPublicKey,
-			NewURL:       result.NewURL,
-			KeyID:        result.KeyID,
-			OldKeyGrace:  result.OldKeyGrace,
-			Metadata:     result.Metadata,
+		var before *core.Credential
+		if verify {
+			if before, err = db.GetCredentialV2(name); err != nil {
+				return fmt.Errorf("credential %q: %w", name, err)
+			}
 		}
 
-		if err := db.RotateCredential(name, coreResult, plugin.Name(), "cli"); err != nil {
-			return fmt.Errorf("save rotation: %w", err)
+		plugin, result, err := rotateOne(ctx, db, rotation.GetGlobalRegistry(), name, opts)
+		if err != nil {
+			return err
 		}
 
 		fmt.Fprintf(os.Stderr, "Rotated %q via %s plugin\n", name, plugin.Name())
 		if result.KeyID != "" {
 			fmt.Fprintf(os.Stderr, "  Key ID: %s\n", result.KeyID)
 		}
-		if result.OldKeyGrace > 0 {
+		switch {
+		case result.OldKeyRevoked:
+			fmt.Fprintln(os.Stderr, "  Old key: revoked by plugin")
+		case result.RevokeOldKey:
+			if _, ok := plugin.(rotation.Finalizer); ok {
+				fmt.Fprintln(os.Stderr, "  Old key: revoked")
+			} else {
+				fmt.Fprintf(os.Stderr, "  Old key: still active — revoke it manually (%s plugin cannot)\n", plugin.Name())
+			}
+		case result.OldKeyGrace > 0:
 			fmt.Fprintf(os.Stderr, "  Old key grace period: %s\n", result.OldKeyGrace)
 		}
+		fmt.Fprintf(os.Stderr, "  Rotated fields: %s\n", strings.Join(rotatedFields(result), ", "))
+
+		if verify {
+			return verifyOrRollback(ctx, db, plugin, before, result)
+		}
+		return nil
+	},
+}
+
+// verifyOrRollback checks the freshly rotated credential with the plugin's
+// Verifier. On failure, and only while the old key is still valid, it offers
+// to put the old values back.
+func verifyOrRollback(ctx context.Context, db *core.Database, plugin rotation.Plugin, before *core.Credential, result *rotation.Result) error {
+	err := verifyRotated(ctx, db, plugin, before.Name)
+	if errors.Is(err, errCannotVerify) {
+		fmt.Fprintf(os.Stderr, "  Verify: skipped (%s plugin cannot verify keys)\n", plugin.Name())
+		return nil
+	}
+	if err == nil {
+		fmt.Fprintln(os.Stderr, "  Verify: ✓ new key works")
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "  Verify: ✗ %v\n", err)
+	if !oldKeyUsable(plugin, result) {
+		return fmt.Errorf("new key failed verification and the old key has been revoked: %w", err)
+	}
+	if rbErr := canRollBack(before, result); rbErr != nil {
+		return fmt.Errorf("new key failed verification (kept, %v): %w", rbErr, err)
+	}
+	if !confirm("Roll back to the old key?") {
+		return fmt.Errorf("new key failed verification (kept): %w", err)
+	}
+	if rbErr := rollbackRotation(db, plugin, before, result); rbErr != nil {
+		return fmt.Errorf("roll back: %w", rbErr)
+	}
+	fmt.Fprintf(os.Stderr, "Rolled back %q to the old key\n", before.Name)
+	return fmt.Errorf("new key failed verification (rolled back): %w", err)
+}
+
+var errCannotVerify = errors.New("plugin cannot verify keys")
+
+// verifyRotated runs the plugin's Verifier against the credential as stored.
+func verifyRotated(ctx context.Context, db *core.Database, plugin rotation.Plugin, name string) error {
+	v, ok := plugin.(rotation.Verifier)
+	if !ok {
+		return errCannotVerify
+	}
+	cred, err := db.GetCredentialV2(name)
+	if err != nil {
+		return err
+	}
+	return v.Verify(ctx, credentialInfo(cred))
+}
+
+// oldKeyUsable reports whether the pre-rotation key should still work, i.e.
+// neither the plugin nor its Finalizer has revoked it.
+func oldKeyUsable(plugin rotation.Plugin, result *rotation.Result) bool {
+	if result.OldKeyRevoked {
+		return false
+	}
+	if _, ok := plugin.(rotation.Finalizer); ok && result.RevokeOldKey {
+		return false
+	}
+	return true
+}
+
+// canRollBack reports why rollbackRotation can't undo result: a rotated
+// field that was unset in before. A rotation only sets fields, so it can't
+// put that back.
+func canRollBack(before *core.Credential, result *rotation.Result) error {
+	var unset []string
+	if result.NewSecretKey != nil && before.SecretKey == nil {
+		unset = append(unset, "secret_key")
+	}
+	if result.NewPublicKey != nil && before.PublicKey == nil {
+		unset = append(unset, "public_key")
+	}
+	if (result.NewURL != nil || len(result.NewURLs) > 0) && before.URL == nil && len(before.URLs) == 0 {
+		unset = append(unset, "url")
+	}
+	if len(unset) > 0 {
+		return fmt.Errorf("can't roll back %q: it had no %s before this rotation", before.Name, strings.Join(unset, " or "))
+	}
+	return nil
+}
+
+// rollbackRotation restores the fields result rotated to their values in
+// before. It is recorded as a rotation of its own, so history shows both.
+// Nothing is written if canRollBack refuses.
+func rollbackRotation(db *core.Database, plugin rotation.Plugin, before *core.Credential, result *rotation.Result) error {
+	if err := canRollBack(before, result); err != nil {
+		return err
+	}
+	undo := &core.RotationResult{Metadata: map[string]string{"rollback": "verification failed"}}
+	if result.NewSecretKey != nil {
+		undo.NewSecretKey = before.SecretKey
+	}
+	if result.NewPublicKey != nil {
+		undo.NewPublicKey = before.PublicKey
+	}
+	if result.NewURL != nil || len(result.NewURLs) > 0 {
+		undo.NewURL = before.URL
+		undo.NewURLs = before.URLs
+	}
+	if before.KeyID != nil {
+		undo.KeyID = *before.KeyID
+	}
+	return db.RotateCredential(before.Name, undo, plugin.Name(), "cli-rollback")
+}
+
+const rotateTimeout = 30 * time.Second
+
+// rotatedByDefault is who a CLI rotation is attributed to without --as:
+// the OS user name, or "cli" if it can't be determined.
+func rotatedByDefault() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "cli"
+}
+
+// rotateByType rotates every credential of apiType, continuing past failures.
+func rotateByType(db *core.Database, apiType string, opts rotateOpts) error {
+	creds, err := db.CredentialsByType(apiType)
+	if err != nil {
+		return fmt.Errorf("list credentials: %w", err)
+	}
+	if len(creds) == 0 {
+		return fmt.Errorf("no credentials with api_type %q", apiType)
+	}
+	return rotateEach(db, creds, apiType+" credentials", func(core.Credential) (rotateOpts, error) { return opts, nil })
+}
+
+// rotateDue rotates every credential whose rotation schedule has run out.
+// Secret plugin settings are read from the environment, or prompted for,
+// once per api_type before anything is rotated; a type whose config can't
+// be completed fails each of its credentials.
+func rotateDue(db *core.Database, opts rotateOpts) error {
+	creds, err := db.ListDueForRotation(db.Now())
+	if err != nil {
+		return fmt.Errorf("list credentials: %w", err)
+	}
+	if len(creds) == 0 {
+		fmt.Fprintln(os.Stderr, "No credentials are due for rotation")
+		return nil
+	}
+
+	reg := rotation.GetGlobalRegistry()
+	configs := map[string]rotation.Config{}
+	errs := map[string]error{}
+	for _, c := range creds {
+		if _, done := configs[c.APIType]; done {
+			continue
+		}
+		cfg := rotation.Config{}
+		errs[c.APIType] = preparePluginConfig(reg, c.APIType, cfg, nil, nil)
+		configs[c.APIType] = cfg
+	}
+	return rotateEach(db, creds, "due credentials", func(c core.Credential) (rotateOpts, error) {
+		o := opts
+		o.config = configs[c.APIType]
+		return o, errs[c.APIType]
+	})
+}
+
+// rotateEach rotates creds one by one with the options optsFor gives each,
+// continuing past failures, and sums up as "Rotated n/m <what>".
+func rotateEach(db *core.Database, creds []core.Credential, what string, optsFor func(core.Credential) (rotateOpts, error)) error {
+	failed := 0
+	for _, c := range creds {
+		opts, err := optsFor(c)
+		var result *rotation.Result
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), rotateTimeout)
+			_, result, err = rotateOne(ctx, db, rotation.GetGlobalRegistry(), c.Name, opts)
+			cancel()
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", c.Name, err)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "  ✓ %s (%s)\n", c.Name, strings.Join(rotatedFields(result), ", "))
+	}
+
+	fmt.Fprintf(os.Stderr, "Rotated %d/%d %s\n", len(creds)-failed, len(creds), what)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d rotations failed", failed, len(creds))
+	}
+	return nil
+}
+
+// rotateOpts tunes a single rotation.
+type rotateOpts struct {
+	rotatedBy string
+	reason    string                    // logged with the rotation; see core.RotationReasonKey
+	fields    []rotation.RotatableField // persist only these; nil means all
+	config    rotation.Config           // completed by pluginConfig and passed to the plugin
+}
+
+// rotateOne looks up the plugin for a credential's api_type, runs it, and
+// persists the result via RotateCredential.
+func rotateOne(ctx context.Context, db *core.Database, reg *rotation.Registry, name string, opts rotateOpts) (rotation.Plugin, *rotation.Result, error) {
+	cred, err := db.GetCredentialV2(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("credential %q: %w", name, err)
+	}
+
+	plugin, ok := reg.Get(cred.APIType)
+	if !ok {
+		return nil, nil, userErr(core.ErrNoPlugin, "no rotation plugin for api_type %q (available: %s)",
+			cred.APIType, strings.Join(reg.List(), ", "))
+	}
+
+	info := credentialInfo(cred)
+
+	if err := plugin.Validate(info); err != nil {
+		return nil, nil, fmt.Errorf("validation: %w", err)
+	}
+
+	for _, f := range opts.fields {
+		if !slices.Contains(plugin.RotatableFields(), f) {
+			return nil, nil, fmt.Errorf("%s plugin cannot rotate %s", plugin.Name(), f)
+		}
+	}
+	// A subset has to be asked of the provider: rotating everything and
+	// saving part of it would throw away freshly issued keys.
+	subset, ok := plugin.(rotation.FieldRotator)
+	if opts.fields != nil && !ok && !coversFields(opts.fields, plugin.RotatableFields()) {
+		return nil, nil, fmt.Errorf("%s plugin can only rotate %s together; drop --fields",
+			plugin.Name(), joinFields(plugin.RotatableFields()))
+	}
+	config := pluginConfig(plugin, cred, opts.config)
+	if err := checkPluginConfig(plugin, config); err != nil {
+		return nil, nil, err
+	}
 
-		var fields []string
-		if result.NewSecretKey != nil {
-			fields = append(fields, "secret_key")
+	// The webhook is sent once the rotation is finished and its lock
+	// released (defers run last first), so a slow receiver holds up
+	// neither; it has its own timeout, not ctx.
+	var ev *rotation.WebhookEvent
+	defer func() {
+		if ev != nil {
+			notifyRotation(db, *ev)
 		}
-		if result.NewPublicKey != nil {
-			fields = append(fields, "public_key")
+	}()
+	unlock, err := db.LockRotation(name, 2*rotateTimeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("credential %q: %w", name, err)
+	}
+	defer unlock()
+
+	var result *rotation.Result
+	if opts.fields != nil && ok {
+		result, err = subset.RotateFields(ctx, info, config, opts.fields)
+	} else {
+		result, err = plugin.Rotate(ctx, info, config)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("rotate: %w", err)
+	}
+
+	if opts.fields != nil {
+		for _, f := range result.Fields() {
+			if !slices.Contains(opts.fields, f) {
+				return nil, nil, fmt.Errorf("%s plugin returned a new %s, which wasn't requested; nothing was saved, so check the provider for a key issued by this rotation",
+					plugin.Name(), f)
+			}
 		}
-		if result.NewURL != nil {
-			fields = append(fields, "url")
+		if len(result.Fields()) == 0 {
+			return nil, nil, fmt.Errorf("%s plugin returned none of the requested fields", plugin.Name())
 		}
-		fmt.Fprintf(os.Stderr, "  Rotated fields: %s\n", strings.Join(fields, ", "))
+	}
 
-		return nil
-	},
+	coreResult := &core.RotationResult{
+		NewSecretKey:  result.NewSecretKey,
+		NewPublicKey:  result.NewPublicKey,
+		NewURL:        result.NewURL,
+		NewURLs:       result.NewURLs,
+		KeyID:         result.KeyID,
+		OldKeyGrace:   result.OldKeyGrace,
+		OldKeyRevoked: result.OldKeyRevoked,
+		RevokeOldKey:  result.RevokeOldKey,
+		Metadata:      result.Metadata,
+		Reason:        opts.reason,
+	}
+
+	if err := db.RotateCredential(name, coreResult, plugin.Name(), opts.rotatedBy); err != nil {
+		return nil, nil, fmt.Errorf("save rotation: %w", err)
+	}
+	ev = &rotation.WebhookEvent{
+		Credential:    name,
+		APIType:       cred.APIType,
+		Plugin:        plugin.Name(),
+		RotatedFields: rotatedFields(result),
+		RotatedBy:     opts.rotatedBy,
+		Timestamp:     db.Now().UTC(),
+		OldKey:        rotation.OldKeyActive,
+	}
+
+	switch f, ok := plugin.(rotation.Finalizer); {
+	case result.OldKeyRevoked:
+		ev.OldKey = rotation.OldKeyRevoked
+	case result.RevokeOldKey && ok:
+		if err := f.FinalizeRotation(ctx, info, result); err != nil {
+			ev.OldKey = rotation.OldKeyRevokeFailed
+			return plugin, result, fmt.Errorf("new key saved, but revoking the old key failed: %w", err)
+		}
+		ev.OldKey = rotation.OldKeyRevoked
+	case result.OldKeyGrace > 0:
+		ev.OldKey = rotation.OldKeyGrace
+	}
+	return plugin, result, nil
+}
+
+// coversFields reports whether want includes every field in all.
+func coversFields(want, all []rotation.RotatableField) bool {
+	for _, f := range all {
+		if !slices.Contains(want, f) {
+			return false
+		}
+	}
+	return true
+}
+
+func credentialInfo(cred *core.Credential) rotation.CredentialInfo {
+	info := rotation.CredentialInfo{
+		Name:      cred.Name,
+		APIType:   cred.APIType,
+		SecretKey: cred.SecretKey,
+		PublicKey: cred.PublicKey,
+		URL:       cred.URL,
+		URLs:      cred.URLs,
+		Config:    cred.Config,
+		Metadata:  cred.Metadata,
+	}
+	if cred.Environment != nil {
+		info.Environment = *cred.Environment
+	}
+	return info
+}
+
+func rotatedFields(result *rotation.Result) []string {
+	var fields []string
+	if result.NewSecretKey != nil {
+		fields = append(fields, "secret_key")
+	}
+	if result.NewPublicKey != nil {
+		fields = append(fields, "public_key")
+	}
+	if result.NewURL != nil || len(result.NewURLs) > 0 {
+		fields = append(fields, "url")
+	}
+	return fields
 }
 
 func init() {
+	rotateCmd.Flags().StringP("type", "t", "", "Rotate every credential of this api_type")
+	rotateCmd.Flags().Bool("due", false, "Rotate every credential whose rotation schedule has run out")
+	rotateCmd.Flags().StringSlice("fields", nil, "Only rotate these fields (secret_key, public_key, url)")
+	rotateCmd.Flags().String("as", "", "Identity recorded as rotated_by (default: OS user name)")
+	rotateCmd.Flags().String("reason", "", `Why the key is being rotated, kept in its history (e.g. "vendor breach advisory")`)
+	rotateCmd.Flags().Bool("verify", false, "Check the new key with the provider afterwards; offer a rollback if it fails")
+	rotateCmd.Flags().Bool("test", false, "Run a plugin against a throwaway credential without touching the vault")
+	rotateCmd.Flags().String("plugin", "", "Plugin to exercise with --test")
+	rotateCmd.Flags().String("secret", "", "Secret key of the --test credential")
+	rotateCmd.Flags().String("public-key", "", "Public key of the --test credential")
+	rotateCmd.Flags().String("url", "", "URL of the --test credential")
+	rotateCmd.Flags().String("env", "", "Environment of the --test credential")
+	rotateCmd.Flags().StringArray("config", nil, "Plugin setting key=value (repeatable); secret fields come from env or a prompt")
+	rotateCmd.Flags().String("config-file", "", "JSON object of plugin settings")
+	rotateCmd.Flags().StringToString("plugin-config", nil, "Config of the --test credential (key=value,...)")
 	rootCmd.AddCommand(rotateCmd)
 }
@@ -0,0 +1,562 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/busyrockin/api-vault/rotation"
+)
+
+func tempVault(t *testing.T) *core.Database {
+	t.Helper()
+	db, err := core.NewDatabase(filepath.Join(t.TempDir(), "test.db"), "test-password")
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestRotateOnePersistsResult(t *testing.T) {
+	db := tempVault(t)
+	old, env := "old-secret", "staging"
+	if err := db.AddCredentialV2(&core.Credential{Name: "svc", APIType: "fake", SecretKey: &old, Environment: &env, Metadata: "region=eu"}); err != nil {
+		t.Fatalf("AddCredentialV2: %v", err)
+	}
+
+	secret := "new-secret"
+	reg := rotation.NewRegistry()
+	plugin := rotation.NewTestPlugin("fake", &rotation.Result{
+		NewSecretKey: &secret,
+		KeyID:        "key-1",
+		Metadata:     map[string]string{"source": "test"},
+	})
+	reg.Register(plugin)
+
+	if _, _, err := rotateOne(context.Background(), db, reg, "svc", rotateOpts{rotatedBy: "tester", reason: "vendor advisory"}); err != nil {
+		t.Fatalf("rotateOne: %v", err)
+	}
+	if calls := plugin.Calls(); len(calls) != 1 || calls[0].Environment != "staging" || calls[0].Metadata != "region=eu" {
+		t.Fatalf("plugin saw %+v", calls)
+	}
+
+	cred, err := db.GetCredentialV2("svc")
+	if err != nil {
+		t.Fatalf("GetCredentialV2: %v", err)
+	}
+	if cred.SecretKey == nil || *cred.SecretKey != secret {
+		t.Fatalf("secret not rotated: %v", cred.SecretKey)
+	}
+	if cred.KeyID == nil || *cred.KeyID != "key-1" || cred.LastRotated == nil {
+		t.Fatalf("rotation metadata not persisted: %+v", cred)
+	}
+
+	history, err := db.GetRotationHistory("svc", 10)
+	if err != nil {
+		t.Fatalf("GetRotationHistory: %v", err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("expected 1 history record, got %d", len(history))
+	}
+	h := history[0]
+	if h.PluginName != "fake" || h.RotatedBy != "tester" || h.NewKeyID != "key-1" ||
+		len(h.RotatedFields) != 1 || h.RotatedFields[0] != "secret_key" || h.Metadata["source"] != "test" ||
+		h.Metadata[core.RotationReasonKey] != "vendor advisory" {
+		t.Fatalf("unexpected history record: %+v", h)
+	}
+}
+
+func TestRotateOnePluginFailureLeavesCredential(t *testing.T) {
+	db := tempVault(t)
+	old := "old-secret"
+	db.AddCredentialV2(&core.Credential{Name: "svc", APIType: "fake", SecretKey: &old})
+
+	boom := errors.New("provider down")
+	reg := rotation.NewRegistry()
+	reg.Register(&rotation.TestPlugin{PluginName: "fake", RotateErr: boom})
+
+	if _, _, err := rotateOne(context.Background(), db, reg, "svc", rotateOpts{rotatedBy: "tester"}); !errors.Is(err, boom) {
+		t.Fatalf("expected plugin error, got %v", err)
+	}
+
+	cred, _ := db.GetCredentialV2("svc")
+	if *cred.SecretKey != old {
+		t.Fatalf("secret changed after failed rotation: %q", *cred.SecretKey)
+	}
+	if history, _ := db.GetRotationHistory("svc", 10); len(history) != 0 {
+		t.Fatalf("expected no history, got %d records", len(history))
+	}
+}
+
+func TestRotateOnePublicKeyOnly(t *testing.T) {
+	db := tempVault(t)
+	secret, public := "secret-1", "public-1"
+	db.AddCredentialV2(&core.Credential{Name: "svc", APIType: "fake", SecretKey: &secret, PublicKey: &public})
+
+	newSecret, newPublic := "secret-2", "public-2"
+	reg := rotation.NewRegistry()
+	p := rotation.NewTestPlugin("fake", &rotation.Result{NewSecretKey: &newSecret, NewPublicKey: &newPublic})
+	reg.Register(p)
+
+	opts := rotateOpts{rotatedBy: "tester", fields: []rotation.RotatableField{rotation.FieldPublicKey}}
+	if _, _, err := rotateOne(context.Background(), db, reg, "svc", opts); err != nil {
+		t.Fatalf("rotateOne: %v", err)
+	}
+	// The provider is asked for the public key alone.
+	if got := p.RequestedFields(); len(got) != 1 || !slices.Equal(got[0], opts.fields) {
+		t.Fatalf("RotateFields requests = %v, want [%v]", got, opts.fields)
+	}
+
+	cred, _ := db.GetCredentialV2("svc")
+	if *cred.SecretKey != secret {
+		t.Fatalf("secret should be untouched, got %q", *cred.SecretKey)
+	}
+	if *cred.PublicKey != newPublic {
+		t.Fatalf("public key not rotated, got %q", *cred.PublicKey)
+	}
+
+	opts.fields = []rotation.RotatableField{rotation.FieldURL}
+	if _, _, err := rotateOne(context.Background(), db, reg, "svc", opts); err == nil {
+		t.Fatal("expected error rotating a field the plugin does not support")
+	}
+
+	// A plugin handing back a field that wasn't asked for fails the
+	// rotation rather than having that key dropped.
+	p.UnrequestedFields = true
+	opts.fields = []rotation.RotatableField{rotation.FieldPublicKey}
+	if _, _, err := rotateOne(context.Background(), db, reg, "svc", opts); err == nil || !strings.Contains(err.Error(), "secret_key") {
+		t.Fatalf("unrequested field: err = %v, want a refusal naming secret_key", err)
+	}
+	if cred, _ := db.GetCredentialV2("svc"); *cred.SecretKey != secret || *cred.PublicKey != newPublic {
+		t.Fatalf("credential changed by a refused rotation: %q / %q", *cred.SecretKey, *cred.PublicKey)
+	}
+
+	// A plugin that can't rotate a subset refuses --fields before calling
+	// the provider.
+	whole := rotation.NewTestPlugin("whole", &rotation.Result{NewSecretKey: &newSecret, NewPublicKey: &newPublic})
+	reg.Register(struct{ rotation.Plugin }{whole})
+	db.AddCredentialV2(&core.Credential{Name: "w", APIType: "whole", SecretKey: &secret, PublicKey: &public})
+	if _, _, err := rotateOne(context.Background(), db, reg, "w", opts); err == nil {
+		t.Fatal("expected a plugin without RotateFields to refuse a subset")
+	}
+	if len(whole.Calls()) != 0 {
+		t.Fatal("plugin was called for a refused subset")
+	}
+}
+
+func TestRotateOneRevokesOldKeyImmediately(t *testing.T) {
+	db := tempVault(t)
+	old := "old-secret"
+	db.AddCredentialV2(&core.Credential{Name: "svc", APIType: "fake", SecretKey: &old})
+
+	secret := "new-secret"
+	p := rotation.NewTestPlugin("fake", &rotation.Result{NewSecretKey: &secret, RevokeOldKey: true})
+	reg := rotation.NewRegistry()
+	reg.Register(p)
+
+	if _, _, err := rotateOne(context.Background(), db, reg, "svc", rotateOpts{rotatedBy: "tester"}); err != nil {
+		t.Fatalf("rotateOne: %v", err)
+	}
+	if p.Finalized() != 1 {
+		t.Fatalf("FinalizeRotation called %d times, want 1", p.Finalized())
+	}
+	history, _ := db.GetRotationHistory("svc", 1)
+	if len(history) != 1 || history[0].Metadata["old_key"] != "revoke_now" {
+		t.Fatalf("history = %+v", history)
+	}
+
+	// A failed revoke is reported but the new key is already saved.
+	p.FinalizeErr = errors.New("revoke failed")
+	newer := "newer-secret"
+	p.Result.NewSecretKey = &newer
+	if _, _, err := rotateOne(context.Background(), db, reg, "svc", rotateOpts{rotatedBy: "tester"}); !errors.Is(err, p.FinalizeErr) {
+		t.Fatalf("rotateOne = %v, want finalize error", err)
+	}
+	if cred, _ := db.GetCredentialV2("svc"); *cred.SecretKey != newer {
+		t.Fatalf("secret = %q, want %q", *cred.SecretKey, newer)
+	}
+}
+
+func TestRotateVerifyFailureRollsBack(t *testing.T) {
+	db := tempVault(t)
+	old := "old-secret"
+	db.AddCredentialV2(&core.Credential{Name: "svc", APIType: "fake", SecretKey: &old})
+	before, _ := db.GetCredentialV2("svc")
+
+	secret := "broken-secret"
+	bad := errors.New("401 unauthorized")
+	p := rotation.NewTestPlugin("fake", &rotation.Result{NewSecretKey: &secret, OldKeyGrace: time.Hour})
+	p.VerifyErr = bad
+	reg := rotation.NewRegistry()
+	reg.Register(p)
+
+	ctx := context.Background()
+	_, result, err := rotateOne(ctx, db, reg, "svc", rotateOpts{rotatedBy: "tester"})
+	if err != nil {
+		t.Fatalf("rotateOne: %v", err)
+	}
+	if err := verifyRotated(ctx, db, p, "svc"); !errors.Is(err, bad) {
+		t.Fatalf("verifyRotated = %v, want %v", err, bad)
+	}
+	if !oldKeyUsable(p, result) {
+		t.Fatal("old key within its grace period should be usable")
+	}
+	if err := rollbackRotation(db, p, before, result); err != nil {
+		t.Fatalf("rollbackRotation: %v", err)
+	}
+
+	cred, _ := db.GetCredentialV2("svc")
+	if cred.SecretKey == nil || *cred.SecretKey != old {
+		t.Fatalf("secret after rollback = %v, want %q", cred.SecretKey, old)
+	}
+	hist, _ := db.GetRotationHistory("svc", 10)
+	if len(hist) != 2 || !slices.ContainsFunc(hist, func(r core.RotationRecord) bool { return r.RotatedBy == "cli-rollback" }) {
+		t.Fatalf("history = %+v, want rotation and rollback", hist)
+	}
+
+	result.RevokeOldKey = true
+	if oldKeyUsable(p, result) {
+		t.Fatal("old key revoked by a Finalizer should not be usable")
+	}
+}
+
+func TestRollbackRotationToUnsetField(t *testing.T) {
+	db := tempVault(t)
+	old := "old-secret"
+	db.AddCredentialV2(&core.Credential{Name: "svc", APIType: "fake", SecretKey: &old})
+	before, _ := db.GetCredentialV2("svc")
+
+	secret, public := "new-secret", "pk-new"
+	p := rotation.NewTestPlugin("fake", &rotation.Result{NewSecretKey: &secret, NewPublicKey: &public, OldKeyGrace: time.Hour})
+	reg := rotation.NewRegistry()
+	reg.Register(p)
+	_, result, err := rotateOne(context.Background(), db, reg, "svc", rotateOpts{rotatedBy: "tester"})
+	if err != nil {
+		t.Fatalf("rotateOne: %v", err)
+	}
+
+	// svc had no public key, and a rotation can't remove the new one.
+	err = rollbackRotation(db, p, before, result)
+	if err == nil || !strings.Contains(err.Error(), "no public_key") {
+		t.Fatalf("rollbackRotation = %v, want a refusal naming public_key", err)
+	}
+	cred, _ := db.GetCredentialV2("svc")
+	if *cred.SecretKey != secret || cred.PublicKey == nil || *cred.PublicKey != public {
+		t.Fatalf("refused rollback changed the credential: secret %q, public %v", *cred.SecretKey, cred.PublicKey)
+	}
+	if hist, _ := db.GetRotationHistory("svc", 10); len(hist) != 1 {
+		t.Fatalf("history = %+v, want only the rotation", hist)
+	}
+}
+
+func TestRevokeAtProvider(t *testing.T) {
+	db := tempVault(t)
+	k := "secret"
+	db.AddCredentialV2(&core.Credential{Name: "svc", APIType: "fake", SecretKey: &k})
+	db.AddCredentialV2(&core.Credential{Name: "other", APIType: "unknown", SecretKey: &k})
+
+	boom := errors.New("provider down")
+	p := &rotation.TestPlugin{PluginName: "fake", RevokeErr: boom}
+	reg := rotation.NewRegistry()
+	reg.Register(p)
+	ctx := context.Background()
+
+	if _, err := revokeAtProvider(ctx, db, reg, "svc"); !errors.Is(err, boom) {
+		t.Fatalf("revoke error = %v, want %v", err, boom)
+	}
+	if _, err := revokeAtProvider(ctx, db, reg, "other"); !errors.Is(err, core.ErrNoPlugin) {
+		t.Fatalf("revoke without plugin = %v, want ErrNoPlugin", err)
+	}
+
+	p.RevokeErr = nil
+	if _, err := revokeAtProvider(ctx, db, reg, "svc"); err != nil {
+		t.Fatalf("revokeAtProvider: %v", err)
+	}
+}
+
+func TestPluginTestHarness(t *testing.T) {
+	secret := "sk-brand-new-secret"
+	plugin := rotation.NewTestPlugin("fake", &rotation.Result{NewSecretKey: &secret, KeyID: "k-9"})
+	reg := rotation.NewRegistry()
+	reg.Register(plugin)
+
+	old := "sk-throwaway"
+	var out bytes.Buffer
+	if err := testPlugin(context.Background(), &out, reg, "fake", rotation.CredentialInfo{Name: "plugin-test", SecretKey: &old}, nil); err != nil {
+		t.Fatalf("testPlugin: %v", err)
+	}
+	if calls := plugin.Calls(); len(calls) != 1 || *calls[0].SecretKey != old {
+		t.Fatalf("Rotate calls = %+v", calls)
+	}
+	if strings.Contains(out.String(), secret) || !strings.Contains(out.String(), "k-9") {
+		t.Errorf("output should mask the secret and show the key ID:\n%s", out.String())
+	}
+
+	plugin.ValidateErr = errors.New("missing admin key")
+	if err := testPlugin(context.Background(), io.Discard, reg, "fake", rotation.CredentialInfo{}, nil); err == nil {
+		t.Error("Validate failure should be returned")
+	}
+	if err := testPlugin(context.Background(), io.Discard, reg, "nope", rotation.CredentialInfo{}, nil); !errors.Is(err, core.ErrNoPlugin) {
+		t.Errorf("unknown plugin: %v", err)
+	}
+}
+
+func TestRotateOnePluginConfig(t *testing.T) {
+	db := tempVault(t)
+	old := "old-secret"
+	db.AddCredentialV2(&core.Credential{Name: "svc", APIType: "fake", SecretKey: &old})
+
+	secret := "new-secret"
+	plugin := rotation.NewTestPlugin("fake", &rotation.Result{NewSecretKey: &secret})
+	plugin.Schema = rotation.ConfigSchema{Fields: []rotation.ConfigField{
+		{Name: "org", Required: true},
+		{Name: "admin_key", Required: true, Secret: true},
+		{Name: "region"},
+	}}
+	reg := rotation.NewRegistry()
+	reg.Register(plugin)
+
+	_, _, err := rotateOne(context.Background(), db, reg, "svc", rotateOpts{rotatedBy: "tester"})
+	if err == nil || !strings.Contains(err.Error(), "org, admin_key (or API_VAULT_ROTATE_ADMIN_KEY)") {
+		t.Fatalf("missing config: %v", err)
+	}
+	bad := rotation.Config{"org": "o-1", "admin_key": "ak", "colour": "red"}
+	if _, _, err := rotateOne(context.Background(), db, reg, "svc", rotateOpts{rotatedBy: "tester", config: bad}); err == nil || !strings.Contains(err.Error(), "colour") {
+		t.Fatalf("unknown key: %v", err)
+	}
+	if len(plugin.Calls()) != 0 {
+		t.Fatal("Rotate ran with invalid config")
+	}
+
+	cfg, argKeys, err := loadRotateConfig("", []string{"org=o-1", "region=eu=west"})
+	if err != nil {
+		t.Fatalf("loadRotateConfig: %v", err)
+	}
+	if err := fillSecretConfig(plugin, cfg, []string{"admin_key"}, nil); err == nil {
+		t.Error("a secret field on the command line should be refused")
+	}
+	t.Setenv("API_VAULT_ROTATE_ADMIN_KEY", "ak-from-env")
+	if err := fillSecretConfig(plugin, cfg, argKeys, nil); err != nil {
+		t.Fatalf("fillSecretConfig: %v", err)
+	}
+	if _, _, err := rotateOne(context.Background(), db, reg, "svc", rotateOpts{rotatedBy: "tester", config: cfg}); err != nil {
+		t.Fatalf("rotateOne: %v", err)
+	}
+	got := plugin.Configs()
+	if len(got) != 1 || got[0]["org"] != "o-1" || got[0]["region"] != "eu=west" || got[0]["admin_key"] != "ak-from-env" {
+		t.Fatalf("plugin config = %v", got)
+	}
+}
+
+func TestRotateOneStoredConfig(t *testing.T) {
+	db := tempVault(t)
+	old := "old-secret"
+	db.AddCredentialV2(&core.Credential{Name: "svc", APIType: "fake", SecretKey: &old})
+
+	secret := "new-secret"
+	plugin := rotation.NewTestPlugin("fake", &rotation.Result{NewSecretKey: &secret})
+	plugin.Schema = rotation.ConfigSchema{Fields: []rotation.ConfigField{
+		{Name: "org", Required: true},
+		{Name: "admin_key", Required: true, Secret: true},
+	}}
+	reg := rotation.NewRegistry()
+	reg.Register(plugin)
+	rotation.GetGlobalRegistry().Register(plugin) // patchConfig looks the type up there
+	t.Cleanup(func() { rotation.GetGlobalRegistry().Unregister("fake") })
+
+	if _, err := patchConfig(db, "svc", nil, []string{"admin_key=ak"}); err == nil {
+		t.Error("a secret field should not be stored")
+	}
+	stored, err := patchConfig(db, "svc", nil, []string{"org=o-stored"})
+	if err != nil {
+		t.Fatalf("patchConfig: %v", err)
+	}
+	if err := db.UpdateCredentialV2("svc", &core.CredentialPatch{Config: stored}); err != nil {
+		t.Fatalf("UpdateCredentialV2: %v", err)
+	}
+
+	// No --config at all, as with --type and --due: org comes from the
+	// credential, the secret from the environment.
+	t.Setenv("API_VAULT_ROTATE_ADMIN_KEY", "ak-from-env")
+	if err := preparePluginConfig(reg, "fake", rotation.Config{}, nil, nil); err != nil {
+		t.Fatalf("preparePluginConfig: %v", err)
+	}
+	if _, _, err := rotateOne(context.Background(), db, reg, "svc", rotateOpts{rotatedBy: "tester"}); err != nil {
+		t.Fatalf("rotateOne: %v", err)
+	}
+	if _, _, err := rotateOne(context.Background(), db, reg, "svc", rotateOpts{rotatedBy: "tester", config: rotation.Config{"org": "o-flag"}}); err != nil {
+		t.Fatalf("rotateOne: %v", err)
+	}
+	got := plugin.Configs()
+	if len(got) != 2 || got[0]["org"] != "o-stored" || got[0]["admin_key"] != "ak-from-env" || got[1]["org"] != "o-flag" {
+		t.Fatalf("plugin config = %v", got)
+	}
+}
+
+func TestRotateDueConfig(t *testing.T) {
+	db := tempVault(t)
+	every := time.Minute
+	for _, org := range []string{"o-a", "o-b"} {
+		old := "old-" + org
+		if err := db.AddCredentialV2(&core.Credential{Name: org, APIType: "fakedue", SecretKey: &old, RotationInterval: &every, Config: map[string]string{"org": org}}); err != nil {
+			t.Fatalf("AddCredentialV2: %v", err)
+		}
+	}
+	// Only the vault's clock says they are due.
+	db.SetClock(func() time.Time { return time.Now().Add(time.Hour) })
+
+	secret := "new-secret"
+	plugin := rotation.NewTestPlugin("fakedue", &rotation.Result{NewSecretKey: &secret})
+	plugin.Schema = rotation.ConfigSchema{Fields: []rotation.ConfigField{
+		{Name: "org", Required: true},
+		{Name: "admin_key", Required: true, Secret: true},
+	}}
+	rotation.GetGlobalRegistry().Register(plugin)
+	t.Cleanup(func() { rotation.GetGlobalRegistry().Unregister("fakedue") })
+	t.Setenv("API_VAULT_ROTATE_ADMIN_KEY", "ak-from-env")
+
+	if err := rotateDue(db, rotateOpts{rotatedBy: "tester"}); err != nil {
+		t.Fatalf("rotateDue: %v", err)
+	}
+	got := plugin.Configs()
+	if len(got) != 2 {
+		t.Fatalf("Rotate ran %d times, want 2", len(got))
+	}
+	orgs := []string{got[0]["org"].(string), got[1]["org"].(string)}
+	slices.Sort(orgs)
+	if !slices.Equal(orgs, []string{"o-a", "o-b"}) || got[0]["admin_key"] != "ak-from-env" || got[1]["admin_key"] != "ak-from-env" {
+		t.Fatalf("plugin configs = %v", got)
+	}
+}
+
+func TestRotationReadiness(t *testing.T) {
+	reg := rotation.NewRegistry()
+	p := rotation.NewTestPlugin("fake", nil)
+	p.Schema = rotation.ConfigSchema{Fields: []rotation.ConfigField{
+		{Name: "org", Required: true},
+		{Name: "admin_key", Required: true},
+		{Name: "region"},
+	}}
+	reg.Register(p)
+
+	half := core.Credential{Name: "half", APIType: "fake", Config: map[string]string{"org": "o"}}
+	ready, missing, err := rotationReadiness(reg, half)
+	if err != nil || ready || len(missing) != 1 || missing[0] != "admin_key" {
+		t.Fatalf("half: ready=%v missing=%v err=%v", ready, missing, err)
+	}
+	full := core.Credential{Name: "full", APIType: "fake", Config: map[string]string{"org": "o", "admin_key": "k"}}
+	if ready, missing, err := rotationReadiness(reg, full); err != nil || !ready || len(missing) != 0 {
+		t.Fatalf("full: ready=%v missing=%v err=%v", ready, missing, err)
+	}
+	if _, _, err := rotationReadiness(reg, core.Credential{Name: "orphan", APIType: "unknown"}); !errors.Is(err, core.ErrNoPlugin) {
+		t.Fatalf("orphan: err=%v, want ErrNoPlugin", err)
+	}
+}
+
+func TestRotateOneNotifiesWebhook(t *testing.T) {
+	db := tempVault(t)
+	old := "old-secret"
+	db.AddCredentialV2(&core.Credential{Name: "svc", APIType: "fake", SecretKey: &old})
+
+	events := make(chan rotation.WebhookEvent, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(rotation.WebhookSignatureHeader) != rotation.SignWebhook("s3cret", body) {
+			t.Errorf("bad signature %q", r.Header.Get(rotation.WebhookSignatureHeader))
+		}
+		// The rotation lock is released before the webhook is sent.
+		if unlock, err := db.LockRotation("svc", time.Minute); err != nil {
+			t.Errorf("rotation lock held during the webhook: %v", err)
+		} else {
+			unlock()
+		}
+		var ev rotation.WebhookEvent
+		json.Unmarshal(body, &ev)
+		events <- ev
+	}))
+	defer srv.Close()
+	if err := db.SetRotationWebhook("http://unused.invalid", "s3cret"); err != nil {
+		t.Fatalf("SetRotationWebhook: %v", err)
+	}
+	t.Setenv("API_VAULT_WEBHOOK_URL", srv.URL)
+
+	at := time.Date(2031, 5, 1, 12, 0, 0, 0, time.UTC)
+	db.SetClock(func() time.Time { return at })
+
+	secret := "new-secret"
+	reg := rotation.NewRegistry()
+	plugin := rotation.NewTestPlugin("fake", &rotation.Result{NewSecretKey: &secret, RevokeOldKey: true})
+	plugin.FinalizeErr = errors.New("provider down")
+	reg.Register(plugin)
+	if _, _, err := rotateOne(context.Background(), db, reg, "svc", rotateOpts{rotatedBy: "tester"}); err == nil || !strings.Contains(err.Error(), "provider down") {
+		t.Fatalf("rotateOne: %v, want the revocation failure", err)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.Event != "rotation" || ev.Credential != "svc" || ev.Plugin != "fake" || ev.RotatedBy != "tester" ||
+			!slices.Equal(ev.RotatedFields, []string{"secret_key"}) || !ev.Timestamp.Equal(at) || ev.OldKey != rotation.OldKeyRevokeFailed {
+			t.Fatalf("event = %+v", ev)
+		}
+	default:
+		t.Fatal("webhook not called")
+	}
+}
+
+func TestRotateAs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.db")
+	db, err := core.NewDatabase(path, "test-password")
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	old := "old-secret"
+	db.AddCredentialV2(&core.Credential{Name: "svc", APIType: "fake-as", SecretKey: &old})
+
+	secret := "new-secret"
+	rotation.GetGlobalRegistry().Register(rotation.NewTestPlugin("fake-as", &rotation.Result{NewSecretKey: &secret}))
+	t.Cleanup(func() { rotation.GetGlobalRegistry().Unregister("fake-as") })
+	t.Setenv("API_VAULT_PASSWORD", "test-password")
+	vault := []string{"--vault-path", path, "--insecure-permissions"}
+
+	if _, err := runCLI(t, append([]string{"rotate", "svc", "--as", "ci-bot"}, vault...)...); err != nil {
+		t.Fatalf("rotate --as: %v", err)
+	}
+	if _, err := runCLI(t, append([]string{"rotate", "svc"}, vault...)...); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+
+	want := "cli"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		want = u.Username
+	}
+	if got := rotatedByDefault(); got != want {
+		t.Errorf("rotatedByDefault = %q, want %q", got, want)
+	}
+	hist, err := db.GetRotationHistory("svc", 10)
+	if err != nil {
+		t.Fatalf("GetRotationHistory: %v", err)
+	}
+	var by []string
+	for _, r := range hist {
+		by = append(by, r.RotatedBy)
+	}
+	exp := []string{"ci-bot", want}
+	slices.Sort(by)
+	slices.Sort(exp)
+	if !slices.Equal(by, exp) {
+		t.Fatalf("rotated_by = %v, want %v", by, exp)
+	}
+}
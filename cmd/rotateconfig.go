@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/busyrockin/api-vault/rotation"
+)
+
+// rotateConfigEnvPrefix starts the environment variable a Secret config
+// field is read from, e.g. API_VAULT_ROTATE_ADMIN_KEY for admin_key.
+const rotateConfigEnvPrefix = "API_VAULT_ROTATE_"
+
+// loadRotateConfig merges --config-file (a JSON object) and --config
+// key=value pairs; a pair overrides the same key from the file. It also
+// returns the keys given as pairs, which are visible in the process list.
+func loadRotateConfig(file string, pairs []string) (rotation.Config, []string, error) {
+	cfg := rotation.Config{}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read config file: %w", err)
+		}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, nil, fmt.Errorf("config file %s: %w", file, err)
+		}
+	}
+	var argKeys []string
+	for _, p := range pairs {
+		k, v, ok := strings.Cut(p, "=")
+		if k = strings.TrimSpace(k); !ok || k == "" {
+			return nil, nil, fmt.Errorf("--config %q: expected key=value", p)
+		}
+		cfg[k] = v
+		argKeys = append(argKeys, k)
+	}
+	return cfg, argKeys, nil
+}
+
+// preparePluginConfig runs fillSecretConfig for the plugin handling
+// apiType, if there is one; rotateOne reports a missing plugin. It runs
+// before the rotation timeout starts, so a prompt doesn't eat into it.
+// cred is the credential to be rotated, or nil when there are several.
+func preparePluginConfig(reg *rotation.Registry, apiType string, cfg rotation.Config, argKeys []string, cred *core.Credential) error {
+	plugin, ok := reg.Get(apiType)
+	if !ok {
+		return nil
+	}
+	return fillSecretConfig(plugin, cfg, argKeys, cred)
+}
+
+// fillSecretConfig completes cfg for plugin's Secret fields, which are never
+// taken from the command line: each is read from API_VAULT_ROTATE_<FIELD>,
+// or prompted for when required and the rest of the config pluginConfig
+// will build for cred is valid. cfg is updated in place.
+func fillSecretConfig(plugin rotation.Plugin, cfg rotation.Config, argKeys []string, cred *core.Credential) error {
+	fields := plugin.ConfigSchema().Fields
+	for _, f := range fields {
+		if f.Secret && slices.Contains(argKeys, f.Name) {
+			return fmt.Errorf("%s is secret and would show in the process list; set %s, put it in --config-file, or leave it out to be prompted",
+				f.Name, configEnvVar(f.Name))
+		}
+	}
+
+	var prompt []string
+	for _, f := range fields {
+		if hasConfig(cfg, f.Name) {
+			continue
+		}
+		if f.Secret {
+			if v := os.Getenv(configEnvVar(f.Name)); v != "" {
+				cfg[f.Name] = v
+				continue
+			}
+		}
+		if f.Required && f.Secret {
+			prompt = append(prompt, f.Name)
+		}
+	}
+
+	// Don't prompt for secrets when the config is wrong in other ways.
+	// Without a single credential, non-secret fields may still come from
+	// each one's stored Config, which rotateOne checks.
+	trial := pluginConfig(plugin, cred, cfg)
+	for _, f := range fields {
+		if slices.Contains(prompt, f.Name) || cred == nil && !f.Secret && !hasConfig(trial, f.Name) {
+			trial[f.Name] = "-"
+		}
+	}
+	if err := checkPluginConfig(plugin, trial); err != nil {
+		return err
+	}
+	for _, name := range prompt {
+		v, err := promptHidden(fmt.Sprintf("%s %s: ", plugin.Name(), name))
+		if err != nil {
+			return fmt.Errorf("read %s: %w", name, err)
+		}
+		cfg[name] = v
+	}
+	return nil
+}
+
+// pluginConfig is the config rotateOne passes to plugin for cred: explicit
+// (from --config, --config-file or a prompt), completed field by field so
+// callers that can't prompt — --type, --due, interactive mode — still find
+// required settings. A field explicit lacks is taken, if it isn't secret,
+// from cred's stored Config (update --config), and otherwise from
+// API_VAULT_ROTATE_<FIELD>. cred may be nil; explicit is not modified.
+func pluginConfig(plugin rotation.Plugin, cred *core.Credential, explicit rotation.Config) rotation.Config {
+	cfg := maps.Clone(explicit)
+	if cfg == nil {
+		cfg = rotation.Config{}
+	}
+	for _, f := range plugin.ConfigSchema().Fields {
+		if hasConfig(cfg, f.Name) {
+			continue
+		}
+		if cred != nil && !f.Secret && cred.Config[f.Name] != "" {
+			cfg[f.Name] = cred.Config[f.Name]
+		} else if v := os.Getenv(configEnvVar(f.Name)); v != "" {
+			cfg[f.Name] = v
+		}
+	}
+	return cfg
+}
+
+// checkPluginConfig rejects keys plugin's ConfigSchema doesn't declare and
+// lists every Required field that has no value.
+func checkPluginConfig(plugin rotation.Plugin, cfg rotation.Config) error {
+	fields := plugin.ConfigSchema().Fields
+	var unknown []string
+	for k := range cfg {
+		if !slices.ContainsFunc(fields, func(f rotation.ConfigField) bool { return f.Name == k }) {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		accepted := "none"
+		if len(fields) > 0 {
+			names := make([]string, len(fields))
+			for i, f := range fields {
+				names[i] = f.Name
+			}
+			accepted = strings.Join(names, ", ")
+		}
+		return fmt.Errorf("%s plugin does not accept config %s (accepted: %s)",
+			plugin.Name(), strings.Join(unknown, ", "), accepted)
+	}
+
+	var missing []string
+	for _, f := range fields {
+		if f.Required && !hasConfig(cfg, f.Name) {
+			m := f.Name
+			if f.Secret {
+				m += " (or " + configEnvVar(f.Name) + ")"
+			}
+			missing = append(missing, m)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s plugin needs config: %s; pass --config key=value or --config-file, store it with 'update --config', or set %s<FIELD>",
+			plugin.Name(), strings.Join(missing, ", "), rotateConfigEnvPrefix)
+	}
+	return nil
+}
+
+func hasConfig(cfg rotation.Config, key string) bool {
+	v, ok := cfg[key]
+	if s, isString := v.(string); isString {
+		return s != ""
+	}
+	return ok && v != nil
+}
+
+func configEnvVar(field string) string {
+	return rotateConfigEnvPrefix + envVarName(field)
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/busyrockin/api-vault/rotation"
+	"github.com/spf13/cobra"
+)
+
+// pluginTestInfo builds the synthetic credential for rotate --test from the
+// --secret, --public-key, --url, --env and --plugin-config flags.
+func pluginTestInfo(cmd *cobra.Command, pluginName string) rotation.CredentialInfo {
+	info := rotation.CredentialInfo{Name: "plugin-test", APIType: pluginName}
+	if s, _ := cmd.Flags().GetString("secret"); s != "" {
+		info.SecretKey = &s
+	}
+	if s, _ := cmd.Flags().GetString("public-key"); s != "" {
+		info.PublicKey = &s
+	}
+	if s, _ := cmd.Flags().GetString("url"); s != "" {
+		info.URL = &s
+		info.URLs = []string{s}
+	}
+	info.Environment, _ = cmd.Flags().GetString("env")
+	info.Config, _ = cmd.Flags().GetStringToString("plugin-config")
+	return info
+}
+
+// testPlugin runs a plugin's Validate and Rotate against info, with cfg
+// checked against its schema, and prints what came back. It never opens
+// the vault, and FinalizeRotation is not called, so an old key the plugin
+// wants revoked stays live.
+func testPlugin(ctx context.Context, w io.Writer, reg *rotation.Registry, pluginName string, info rotation.CredentialInfo, cfg rotation.Config) error {
+	plugin, ok := reg.Get(pluginName)
+	if !ok {
+		return userErr(core.ErrNoPlugin, "no rotation plugin %q (available: %s)",
+			pluginName, strings.Join(reg.List(), ", "))
+	}
+
+	fmt.Fprintf(w, "Plugin:          %s\n", plugin.Name())
+	fmt.Fprintf(w, "Rotatable:       %s\n", joinFields(plugin.RotatableFields()))
+	for _, f := range plugin.ConfigSchema().Fields {
+		req := "optional"
+		if f.Required {
+			req = "required"
+		}
+		fmt.Fprintf(w, "  config: %s — %s (%s)\n", f.Name, f.Description, req)
+	}
+
+	if err := plugin.Validate(info); err != nil {
+		fmt.Fprintln(w, "✗ Validate")
+		return fmt.Errorf("validation: %w", err)
+	}
+	fmt.Fprintln(w, "✓ Validate")
+
+	if err := checkPluginConfig(plugin, cfg); err != nil {
+		fmt.Fprintln(w, "✗ Config")
+		return err
+	}
+
+	result, err := plugin.Rotate(ctx, info, cfg)
+	if err != nil {
+		fmt.Fprintln(w, "✗ Rotate")
+		return fmt.Errorf("rotate: %w", err)
+	}
+	fmt.Fprintln(w, "✓ Rotate")
+
+	masked := func(s *string) string {
+		if s == nil {
+			return "(unchanged)"
+		}
+		return fmt.Sprintf("%s (%d chars)", maskKey(*s), len(*s))
+	}
+	plain := func(s *string) string {
+		if s == nil {
+			return "(unchanged)"
+		}
+		return *s
+	}
+	fmt.Fprintf(w, "  New secret:    %s\n", masked(result.NewSecretKey))
+	fmt.Fprintf(w, "  New public:    %s\n", masked(result.NewPublicKey))
+	fmt.Fprintf(w, "  New URL:       %s\n", plain(result.NewURL))
+	if len(result.NewURLs) > 0 {
+		fmt.Fprintf(w, "  New URLs:      %s\n", strings.Join(result.NewURLs, ", "))
+	}
+	fmt.Fprintf(w, "  Key ID:        %s\n", orNone(result.KeyID))
+	fmt.Fprintf(w, "  Old key:       revoked=%t revoke-requested=%t grace=%s\n",
+		result.OldKeyRevoked, result.RevokeOldKey, result.OldKeyGrace)
+	for _, k := range slices.Sorted(maps.Keys(result.Metadata)) {
+		fmt.Fprintf(w, "  meta %s = %s\n", k, result.Metadata[k])
+	}
+	if result.RevokeOldKey {
+		fmt.Fprintln(w, "  Note: FinalizeRotation was not run; revoke the old test key yourself.")
+	}
+	return nil
+}
+
+func joinFields(fields []rotation.RotatableField) string {
+	if len(fields) == 0 {
+		return "(none)"
+	}
+	s := make([]string, len(fields))
+	for i, f := range fields {
+		s[i] = string(f)
+	}
+	return strings.Join(s, ", ")
+}
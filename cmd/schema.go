@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Show the vault's table layout",
+	Long: `Show the columns of the credentials and rotations tables as stored in the
+opened vault. --sql prints the CREATE statements instead, with PRAGMA
+table_info as comments. Only schema metadata is read; no secrets.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asSQL, _ := cmd.Flags().GetBool("sql")
+
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		tables, err := db.Schema()
+		if err != nil {
+			return fmt.Errorf("read schema: %w", err)
+		}
+
+		for i, t := range tables {
+			if i > 0 {
+				fmt.Println()
+			}
+			if asSQL {
+				printSchemaSQL(t)
+				continue
+			}
+			fmt.Println(t.Name)
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "  COLUMN\tTYPE\tNOT NULL\tDEFAULT\tPK")
+			for _, c := range t.Columns {
+				fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", c.Name, c.Type, yesNo(c.NotNull), columnDefault(c), yesNo(c.PK))
+			}
+			w.Flush()
+		}
+		return nil
+	},
+}
+
+func printSchemaSQL(t core.TableSchema) {
+	fmt.Printf("%s;\n", t.SQL)
+	for _, idx := range t.Indexes {
+		fmt.Printf("%s;\n", idx)
+	}
+	fmt.Printf("-- PRAGMA table_info(%s)\n", t.Name)
+	for _, c := range t.Columns {
+		fmt.Printf("--   %s %s", c.Name, c.Type)
+		var attrs []string
+		if c.NotNull {
+			attrs = append(attrs, "NOT NULL")
+		}
+		if c.Default != nil {
+			attrs = append(attrs, "DEFAULT "+*c.Default)
+		}
+		if c.PK {
+			attrs = append(attrs, "PRIMARY KEY")
+		}
+		if len(attrs) > 0 {
+			fmt.Printf(" %s", strings.Join(attrs, " "))
+		}
+		fmt.Println()
+	}
+}
+
+func columnDefault(c core.ColumnInfo) string {
+	if c.Default == nil {
+		return "-"
+	}
+	return *c.Default
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return ""
+}
+
+func init() {
+	schemaCmd.Flags().Bool("sql", false, "Print CREATE statements and table_info as SQL")
+	rootCmd.AddCommand(schemaCmd)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Find credentials by name, type, environment or notes",
+	Long: `Find credentials where query appears in the name, api type, environment
+or notes (metadata). Matching is a literal substring, case-insensitive for
+ASCII unless --case-sensitive; --name, --type, --env and --notes limit it
+to those fields.
+
+With --words, notes are matched by whole words instead, ignoring case; all
+the words given must appear.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		words, _ := cmd.Flags().GetBool("words")
+		var opts core.SearchOptions
+		opts.Name, _ = cmd.Flags().GetBool("name")
+		opts.APIType, _ = cmd.Flags().GetBool("type")
+		opts.Environment, _ = cmd.Flags().GetBool("env")
+		opts.Metadata, _ = cmd.Flags().GetBool("notes")
+		opts.CaseSensitive, _ = cmd.Flags().GetBool("case-sensitive")
+
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		var creds []core.Credential
+		if words {
+			creds, err = db.SearchNotes(args[0])
+		} else {
+			creds, err = db.Search(args[0], opts)
+		}
+		if err != nil {
+			return fmt.Errorf("search: %w", err)
+		}
+		if len(creds) == 0 {
+			fmt.Fprintln(os.Stderr, "No matches.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTYPE\tENV\tNOTES")
+		for _, c := range creds {
+			env := ""
+			if c.Environment != nil {
+				env = *c.Environment
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Name, c.APIType, env, c.Metadata)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	searchCmd.Flags().Bool("name", false, "Search names")
+	searchCmd.Flags().Bool("type", false, "Search api types")
+	searchCmd.Flags().Bool("env", false, "Search environments")
+	searchCmd.Flags().Bool("notes", false, "Search notes")
+	searchCmd.Flags().Bool("case-sensitive", false, "Match case exactly")
+	searchCmd.Flags().Bool("words", false, "Match whole words in notes")
+	searchCmd.MarkFlagsMutuallyExclusive("words", "name")
+	searchCmd.MarkFlagsMutuallyExclusive("words", "type")
+	searchCmd.MarkFlagsMutuallyExclusive("words", "env")
+	searchCmd.MarkFlagsMutuallyExclusive("words", "case-sensitive")
+	rootCmd.AddCommand(searchCmd)
+}
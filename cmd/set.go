@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+var setCmd = &cobra.Command{
+	Use:   "set [name]",
+	Short: "Replace the secret of existing credentials",
+	Long: `Replace the secret of an existing credential, keeping its type, metadata,
+config and rotation history. The new secret comes from --secret,
+--secret-file, or a hidden prompt.
+
+--batch <file> updates many credentials at once from name=secret lines
+(blank lines and # comments are ignored); use - to read stdin, with the
+master password in API_VAULT_PASSWORD. All updates run in one transaction.
+Each line is reported; lines that fail (unknown name, passphrase-protected
+credential, oversized secret) are skipped and the rest are saved.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		batch, _ := cmd.Flags().GetString("batch")
+		secret, _ := cmd.Flags().GetString("secret")
+		secretFile, _ := cmd.Flags().GetString("secret-file")
+
+		if batch != "" {
+			if len(args) > 0 || secret != "" || secretFile != "" {
+				return fmt.Errorf("--batch takes names and secrets from the file; drop the name, --secret and --secret-file")
+			}
+			return setBatch(batch)
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("a credential name or --batch is required")
+		}
+		name := args[0]
+
+		switch {
+		case secretFile != "":
+			s, err := readSecretFile(secretFile, cfg.MaxSecretSize)
+			if err != nil {
+				return err
+			}
+			secret = s
+		case secret == "":
+			s, err := promptHidden(fmt.Sprintf("New secret for %q: ", name))
+			if err != nil {
+				return err
+			}
+			secret = s
+		}
+
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		db.SetMaxSecretSize(cfg.MaxSecretSize)
+
+		if err := db.SetSecret(name, secret); err != nil {
+			if errors.Is(err, core.ErrNotFound) {
+				return userErr(err, "credential %q not found", name)
+			}
+			return fmt.Errorf("set secret: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Updated secret of %q\n", name)
+		return nil
+	},
+}
+
+// batchLine is one name=secret entry, or a line that didn't parse.
+type batchLine struct {
+	num    int
+	update core.SecretUpdate
+	err    error
+}
+
+// parseSecretBatch reads name=secret lines. The secret is everything after
+// the first '=', so it may itself contain '='.
+func parseSecretBatch(r io.Reader) ([]batchLine, error) {
+	var lines []batchLine
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		text := strings.TrimSuffix(sc.Text(), "\r")
+		if t := strings.TrimSpace(text); t == "" || strings.HasPrefix(t, "#") {
+			continue
+		}
+		name, secret, ok := strings.Cut(text, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			lines = append(lines, batchLine{num: n, err: errors.New("expected name=secret")})
+			continue
+		}
+		lines = append(lines, batchLine{num: n, update: core.SecretUpdate{Name: name, Secret: secret}})
+	}
+	return lines, sc.Err()
+}
+
+func setBatch(path string) error {
+	data, err := readImportFile(path)
+	if err != nil {
+		return err
+	}
+	lines, err := parseSecretBatch(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("read batch: %w", err)
+	}
+
+	db, err := openVault()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	db.SetMaxSecretSize(cfg.MaxSecretSize)
+
+	var updates []core.SecretUpdate
+	for _, l := range lines {
+		if l.err == nil {
+			updates = append(updates, l.update)
+		}
+	}
+	results, err := db.SetSecrets(updates)
+	if err != nil {
+		return fmt.Errorf("set secrets (nothing saved): %w", err)
+	}
+
+	updated, failed := 0, 0
+	for _, l := range lines {
+		if l.err == nil {
+			l.err, results = results[0], results[1:]
+		}
+		switch {
+		case l.err == nil:
+			updated++
+			fmt.Fprintf(os.Stderr, "  ✓ %s\n", l.update.Name)
+		case l.update.Name == "":
+			failed++
+			fmt.Fprintf(os.Stderr, "  ✗ line %d: %v\n", l.num, l.err)
+		default:
+			failed++
+			fmt.Fprintf(os.Stderr, "  ✗ line %d: %s: %v\n", l.num, l.update.Name, l.err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Updated %d credentials (%d failed)\n", updated, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d lines failed", failed, len(lines))
+	}
+	return nil
+}
+
+func init() {
+	setCmd.Flags().String("secret", "", "New secret value")
+	setCmd.Flags().String("secret-file", "", "Read the new secret from a file")
+	setCmd.Flags().String("batch", "", "Update many credentials from name=secret lines (- for stdin)")
+	rootCmd.AddCommand(setCmd)
+}
@@ -59,6 +59,17 @@ func (m setupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		// On the text steps every typed or pasted character is input, even
+		// the ones that are shortcuts elsewhere (j, k, s, a).
+		if msg.Type == tea.KeyRunes && (m.step == 1 || m.step == 2) {
+			if m.step == 1 {
+				m.accountName += string(msg.Runes)
+			} else {
+				m.apiKey += string(msg.Runes)
+			}
+			return m, nil
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "esc":
 			return m, tea.Quit
@@ -66,6 +77,17 @@ func (m setupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "enter":
 			return m.handleEnter()
 
+		case "s", "a":
+			// On the confirmation step, jump back to fix the service or account.
+			if m.step == 3 {
+				if msg.String() == "s" {
+					m.step = 0
+				} else {
+					m.step = 1
+				}
+				return m, nil
+			}
+
 		case "up", "k":
 			if m.step == 0 && m.selectedService > 0 {
 				m.selectedService--
@@ -117,12 +139,13 @@ func (m setupModel) handleEnter() (tea.Model, tea.Cmd) {
 			m.err = fmt.Errorf("API key cannot be empty")
 			return m, nil
 		}
+		m.step = 3
+		m.err = nil
+		return m, nil
 
-		// Save credential
+	case 3: // Confirm and save
 		service := m.serviceOptions[m.selectedService]
-		name := fmt.Sprintf("%s-%s", strings.ToLower(service), m.accountName)
-
-		if err := m.db.AddCredential(name, m.apiKey, strings.ToLower(service)); err != nil {
+		if err := m.db.AddCredential(m.credentialName(), m.apiKey, strings.ToLower(service)); err != nil {
 			m.err = fmt.Errorf("failed to save: %w", err)
 			return m, nil
 		}
@@ -157,6 +180,8 @@ func (m setupModel) View() string {
 		b.WriteString(m.renderAccountName())
 	case 2:
 		b.WriteString(m.renderAPIKey())
+	case 3:
+		b.WriteString(m.renderConfirm())
 	}
 
 	return ui.BoxStyle.Render(b.String())
@@ -206,9 +231,7 @@ func (m setupModel) renderAPIKey() string {
 	var b strings.Builder
 
 	service := m.serviceOptions[m.selectedService]
-	name := fmt.Sprintf("%s-%s", strings.ToLower(service), m.accountName)
-
-	b.WriteString(ui.SubtitleStyle.Render(fmt.Sprintf("Credential: %s", name)))
+	b.WriteString(ui.SubtitleStyle.Render(fmt.Sprintf("Credential: %s", m.credentialName())))
 	b.WriteString("\n\n")
 
 	// Mask the API key
@@ -220,16 +243,51 @@ func (m setupModel) renderAPIKey() string {
 	hint := serviceHints[service]
 	b.WriteString(ui.Muted.Render(hint.key))
 	b.WriteString("\n\n")
-	b.WriteString(ui.HelpStyle.Render("[Type] Enter key  [Enter] Save  [Esc] Cancel"))
+	b.WriteString(ui.HelpStyle.Render("[Type] Enter key  [Enter] Continue  [Esc] Cancel"))
 
 	return b.String()
 }
 
-func (m setupModel) renderSuccess() string {
+func (m setupModel) renderConfirm() string {
 	var b strings.Builder
 
 	service := m.serviceOptions[m.selectedService]
-	name := fmt.Sprintf("%s-%s", strings.ToLower(service), m.accountName)
+
+	b.WriteString(ui.SubtitleStyle.Render("Save this credential?"))
+	b.WriteString("\n\n")
+	b.WriteString("Name:    ")
+	b.WriteString(ui.Primary.Render(m.credentialName()))
+	b.WriteString("\n")
+	b.WriteString("Type:    ")
+	b.WriteString(ui.NormalStyle.Render(strings.ToLower(service)))
+	b.WriteString("\n")
+	b.WriteString("API Key: ")
+	b.WriteString(ui.NormalStyle.Render(maskKey(m.apiKey)))
+	b.WriteString("\n\n")
+	b.WriteString(ui.HelpStyle.Render("[Enter] Save  [s] Change service  [a] Change account  [Esc] Cancel"))
+
+	return b.String()
+}
+
+// credentialName is the name the wizard saves under: service-account.
+func (m setupModel) credentialName() string {
+	service := m.serviceOptions[m.selectedService]
+	return fmt.Sprintf("%s-%s", strings.ToLower(service), m.accountName)
+}
+
+// maskKey shows just enough of a key to recognize it: the first four
+// characters of longer keys, the rest as asterisks.
+func maskKey(key string) string {
+	if len(key) < 12 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:4] + strings.Repeat("*", len(key)-4)
+}
+
+func (m setupModel) renderSuccess() string {
+	var b strings.Builder
+
+	name := m.credentialName()
 
 	b.WriteString(ui.TitleStyle.Render("✓ Credential Saved"))
 	b.WriteString("\n\n")
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/busyrockin/api-vault/core"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// typeText sends s to m one key at a time, as typing would.
+func typeText(m tea.Model, s string) tea.Model {
+	for _, r := range s {
+		m = pressKeys(m, string(r))
+	}
+	return m
+}
+
+func TestSetupConfirmStep(t *testing.T) {
+	db := tempVault(t)
+	key := "sk-ant-api03-secret"
+
+	// Anthropic, account "prod", then the key; typed s, k and a are text here.
+	m := pressKeys(newSetupModel(db), "j", "enter")
+	m = pressKeys(typeText(m, "prod"), "enter")
+	m = pressKeys(typeText(m, key), "enter")
+	got := m.(setupModel)
+	if got.step != 3 || got.apiKey != key {
+		t.Fatalf("step %d, key %q; want the confirm step with the typed key", got.step, got.apiKey)
+	}
+	view := got.View()
+	if !strings.Contains(view, "anthropic-prod") || strings.Contains(view, key) || !strings.Contains(view, "sk-a***") {
+		t.Fatalf("confirm step should name the credential and mask the key:\n%s", view)
+	}
+	if _, err := db.GetCredential("anthropic-prod"); !errors.Is(err, core.ErrNotFound) {
+		t.Fatalf("saved before confirming: %v", err)
+	}
+
+	// a goes back to the account, keeping the key.
+	m = pressKeys(m, "a", "backspace", "backspace", "backspace", "backspace")
+	m = pressKeys(typeText(m, "dev"), "enter", "enter")
+	if got = m.(setupModel); got.step != 3 || got.credentialName() != "anthropic-dev" || got.apiKey != key {
+		t.Fatalf("after a: step %d, name %q, key %q", got.step, got.credentialName(), got.apiKey)
+	}
+	// s goes back to the service list.
+	m = pressKeys(m, "s", "j", "enter", "enter", "enter")
+	if got = m.(setupModel); got.step != 3 || got.credentialName() != "supabase-dev" {
+		t.Fatalf("after s: step %d, name %q", got.step, got.credentialName())
+	}
+
+	m = pressKeys(m, "enter")
+	if got = m.(setupModel); !got.done || got.err != nil {
+		t.Fatalf("enter on confirm: done %v, err %v", got.done, got.err)
+	}
+	if v, err := db.GetCredential("supabase-dev"); err != nil || v != key {
+		t.Fatalf("saved secret = %q, %v", v, err)
+	}
+	if c, _ := db.GetMetadata("supabase-dev"); c.APIType != "supabase" {
+		t.Fatalf("saved type %q", c.APIType)
+	}
+	for _, name := range []string{"anthropic-prod", "anthropic-dev"} {
+		if _, err := db.GetCredential(name); !errors.Is(err, core.ErrNotFound) {
+			t.Errorf("%s was saved: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+var showCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show a credential's details with its keys masked",
+	Long: `Show everything stored about a credential: type, environment, URLs, key
+ID, tags, config, notes, timestamps and status. Keys are masked, e.g.
+sk-…1234; use 'get' for the secret itself.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		c, err := db.GetMetadata(name)
+		if err != nil {
+			if errors.Is(err, core.ErrNotFound) {
+				return userErr(err, "credential %q not found", name)
+			}
+			return fmt.Errorf("get credential: %w", err)
+		}
+		// Only the masks need the keys.
+		keys, err := db.InspectCredential(name)
+		if err != nil {
+			return fmt.Errorf("get credential: %w", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		row := func(label, value string) {
+			switch {
+			case value == "":
+			case label == "":
+				fmt.Fprintf(w, "\t%s\n", value)
+			default:
+				fmt.Fprintf(w, "%s:\t%s\n", label, value)
+			}
+		}
+		row("Name", c.Name)
+		row("Type", c.APIType)
+		row("Environment", deref(c.Environment))
+		label := "URL"
+		for _, u := range c.URLs {
+			row(label, u)
+			label = ""
+		}
+
+		secret := "(none)"
+		switch {
+		case keys.HasSecret():
+			secret = maskSecret(*keys.SecretKey)
+		case c.Protected:
+			secret = "(passphrase-protected)"
+		}
+		row("Secret", secret)
+		if keys.HasPublic() {
+			row("Public key", maskSecret(*keys.PublicKey))
+		}
+		row("Key ID", deref(c.KeyID))
+		row("Tags", strings.Join(c.Tags, ", "))
+		label = "Config"
+		for _, k := range slices.Sorted(maps.Keys(c.Config)) {
+			row(label, k+"="+c.Config[k])
+			label = ""
+		}
+		row("Notes", c.Metadata)
+
+		row("Created", c.CreatedAt.Format("2006-01-02 15:04:05"))
+		row("Updated", c.UpdatedAt.Format("2006-01-02 15:04:05"))
+		rotated := "never"
+		if c.LastRotated != nil {
+			rotated = c.LastRotated.Format("2006-01-02 15:04:05")
+		}
+		row("Last rotated", rotated)
+		if due, ok := c.NextRotation(); ok {
+			next := due.Format("2006-01-02 15:04:05")
+			if c.RotationDue(time.Now()) {
+				next += " (due)"
+			}
+			row("Rotate every", formatInterval(*c.RotationInterval))
+			row("Next rotation", next)
+		}
+		if c.LastAccessed != nil {
+			row("Last accessed", c.LastAccessed.Format("2006-01-02 15:04:05"))
+		}
+		if c.ExpiresAt != nil {
+			exp := c.ExpiresAt.Format("2006-01-02 15:04:05")
+			if c.Expired(time.Now()) {
+				exp += " (expired)"
+			}
+			row("Expires", exp)
+		}
+		if c.Disabled {
+			row("Status", "disabled")
+		}
+		return w.Flush()
+	},
+}
+
+// maskSecret shows a key's prefix (up to its first - or _, e.g. sk- or
+// ghp_) and last four characters: sk-…1234. Short keys are fully hidden.
+func maskSecret(s string) string {
+	if len(s) < 12 {
+		return strings.Repeat("*", len(s))
+	}
+	prefix := ""
+	if i := strings.IndexAny(s[:8], "-_"); i > 0 {
+		prefix = s[:i+1]
+	}
+	return prefix + "…" + s[len(s)-4:]
+}
+
+// formatInterval prints a rotation interval in the form add --rotate-every
+// takes: whole days as "90d", anything else as a Go duration.
+func formatInterval(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d >= day && d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.String()
+}
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+}
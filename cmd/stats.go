@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize the vault's credentials",
+	Long: `Print credential counts by type and environment, the oldest and newest
+creation dates, and how many have been rotated. Nothing is decrypted.
+--json prints the same as an object, for monitoring.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		s, err := db.Stats()
+		if err != nil {
+			return fmt.Errorf("stats: %w", err)
+		}
+
+		if asJSON {
+			out := struct {
+				Total         int            `json:"total"`
+				Rotated       int            `json:"rotated"`
+				ByType        map[string]int `json:"by_type"`
+				ByEnvironment map[string]int `json:"by_environment"`
+				Oldest        *time.Time     `json:"oldest_created_at"`
+				Newest        *time.Time     `json:"newest_created_at"`
+			}{Total: s.Total, Rotated: s.Rotated, ByType: map[string]int{}, ByEnvironment: map[string]int{}, Oldest: s.Oldest, Newest: s.Newest}
+			for _, t := range s.ByType {
+				out.ByType[t.APIType] = t.Count
+			}
+			for _, e := range s.ByEnvironment {
+				out.ByEnvironment[e.Environment] = e.Count
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(out)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Credentials:\t%d\n", s.Total)
+		if s.Total == 0 {
+			return w.Flush()
+		}
+		fmt.Fprintf(w, "Rotated:\t%d (%d never)\n", s.Rotated, s.Total-s.Rotated)
+		fmt.Fprintf(w, "Oldest:\t%s\n", s.Oldest.Format("2006-01-02"))
+		fmt.Fprintf(w, "Newest:\t%s\n", s.Newest.Format("2006-01-02"))
+		label := "By type:"
+		for _, t := range s.ByType {
+			fmt.Fprintf(w, "%s\t%s\t%d\n", label, orNone(t.APIType), t.Count)
+			label = ""
+		}
+		label = "By environment:"
+		for _, e := range s.ByEnvironment {
+			fmt.Fprintf(w, "%s\t%s\t%d\n", label, orNone(e.Environment), e.Count)
+			label = ""
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	statsCmd.Flags().Bool("json", false, "Print as JSON")
+	rootCmd.AddCommand(statsCmd)
+}
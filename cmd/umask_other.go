@@ -0,0 +1,7 @@
+//go:build !unix
+
+package cmd
+
+func withUmask(_ int, fn func() error) error {
+	return fn()
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package cmd
+
+import "golang.org/x/sys/unix"
+
+// withUmask runs fn with the process umask set to mask, so files and
+// sockets fn creates never get looser permissions, not even briefly. The
+// umask is process-wide: only call this while nothing else creates files.
+func withUmask(mask int, fn func() error) error {
+	old := unix.Umask(mask)
+	defer unix.Umask(old)
+	return fn()
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/busyrockin/api-vault/rotation"
+	"github.com/spf13/cobra"
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update <name>",
+	Short: "Change fields of an existing credential",
+	Long: `Change a credential's URL, environment, type, tags, expiry or rotation
+schedule in place, keeping its secret, creation time and rotation history.
+Only the flags given are changed; an empty value (--env "") clears the
+field. Use 'set' to replace the secret.
+
+--config key=value (repeatable) stores a setting for the credential's
+rotation plugin, which 'rotate' uses when no --config gives it; "key="
+removes one. Fields the plugin marks secret can't be stored this way: pass
+them to rotate through API_VAULT_ROTATE_<FIELD>.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		flags := cmd.Flags()
+		if !slices.ContainsFunc(updateFlags, flags.Changed) {
+			return fmt.Errorf("nothing to update; pass --url, --env, --type, --public, --tag, --expires, --ttl, --no-expiry, --rotate-every, --no-rotate-every or --config")
+		}
+
+		var patch core.CredentialPatch
+		str := func(flag string) *string {
+			if !flags.Changed(flag) {
+				return nil
+			}
+			v, _ := flags.GetString(flag)
+			return &v
+		}
+		patch.APIType = str("type")
+		patch.Environment = str("env")
+		patch.PublicKey = str("public")
+		list := func(flag string) []string {
+			if !flags.Changed(flag) {
+				return nil
+			}
+			v, _ := flags.GetStringArray(flag)
+			return slices.DeleteFunc(v, func(s string) bool { return s == "" })
+		}
+		patch.URLs = list("url")
+		patch.Tags = list("tag")
+		noExpiry, _ := flags.GetBool("no-expiry")
+		expires, _ := flags.GetString("expires")
+		ttl, _ := flags.GetString("ttl")
+		switch {
+		case noExpiry:
+			patch.ExpiresAt = &time.Time{}
+		case expires != "" || ttl != "":
+			t, err := expiryFromFlags(expires, ttl, time.Now())
+			if err != nil {
+				return err
+			}
+			patch.ExpiresAt = &t
+		}
+		noSchedule, _ := flags.GetBool("no-rotate-every")
+		rotateEvery, _ := flags.GetString("rotate-every")
+		switch {
+		case noSchedule:
+			patch.RotationInterval = new(time.Duration)
+		case rotateEvery != "":
+			d, err := core.ParseTTL(rotateEvery)
+			if err != nil {
+				return fmt.Errorf("--rotate-every: %w", err)
+			}
+			patch.RotationInterval = &d
+		}
+
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		db.SetMaxSecretSize(cfg.MaxSecretSize)
+
+		if flags.Changed("config") {
+			pairs, _ := flags.GetStringArray("config")
+			if patch.Config, err = patchConfig(db, name, patch.APIType, pairs); err != nil {
+				return err
+			}
+		}
+
+		if err := db.UpdateCredentialV2(name, &patch); err != nil {
+			if errors.Is(err, core.ErrNotFound) {
+				return userErr(err, "credential %q not found", name)
+			}
+			return fmt.Errorf("update credential: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Updated credential %q\n", name)
+		return nil
+	},
+}
+
+// updateFlags are the fields update can change.
+var updateFlags = []string{"type", "url", "env", "public", "tag", "expires", "ttl", "no-expiry", "rotate-every", "no-rotate-every", "config"}
+
+// patchConfig merges --config pairs into name's stored Config, refusing
+// fields the rotation plugin for its (possibly new) type marks secret:
+// Config is stored unencrypted.
+func patchConfig(db *core.Database, name string, apiType *string, pairs []string) (map[string]string, error) {
+	cred, err := db.GetMetadata(name)
+	if err != nil {
+		if errors.Is(err, core.ErrNotFound) {
+			return nil, userErr(err, "credential %q not found", name)
+		}
+		return nil, err
+	}
+	if apiType == nil {
+		apiType = &cred.APIType
+	}
+	var secret []string
+	if plugin, ok := rotation.GetGlobalRegistry().Get(*apiType); ok {
+		for _, f := range plugin.ConfigSchema().Fields {
+			if f.Secret {
+				secret = append(secret, f.Name)
+			}
+		}
+	}
+
+	out := maps.Clone(cred.Config)
+	if out == nil {
+		out = map[string]string{}
+	}
+	for _, p := range pairs {
+		k, v, ok := strings.Cut(p, "=")
+		if k = strings.TrimSpace(k); !ok || k == "" {
+			return nil, fmt.Errorf("--config %q: expected key=value", p)
+		}
+		switch {
+		case k == core.SecretKindConfigKey:
+			return nil, fmt.Errorf("--config: %s is set by the vault", k)
+		case slices.Contains(secret, k):
+			return nil, fmt.Errorf("--config: %s is secret and would be stored unencrypted; set %s when rotating", k, configEnvVar(k))
+		case v == "":
+			delete(out, k)
+		default:
+			out[k] = v
+		}
+	}
+	return out, nil
+}
+
+func init() {
+	updateCmd.Flags().StringP("type", "t", "", "New API type")
+	updateCmd.Flags().StringArray("url", nil, "Service URL (repeat for multiple endpoints; replaces all)")
+	updateCmd.Flags().StringP("env", "e", "", "Environment")
+	updateCmd.Flags().String("public", "", "Public/anon key")
+	updateCmd.Flags().StringArray("tag", nil, "Tag (repeatable; replaces all tags, --tag \"\" clears them)")
+	updateCmd.Flags().String("expires", "", "Expiry date (2025-12-31) or RFC 3339 time")
+	updateCmd.Flags().String("ttl", "", "Expire this long from now, e.g. 90d, 2w or 36h")
+	updateCmd.Flags().Bool("no-expiry", false, "Remove the expiry")
+	updateCmd.Flags().String("rotate-every", "", "Rotation schedule, e.g. 90d")
+	updateCmd.Flags().Bool("no-rotate-every", false, "Remove the rotation schedule")
+	updateCmd.Flags().StringArray("config", nil, "Rotation plugin setting key=value, kept for 'rotate' (repeatable; key= removes it)")
+	updateCmd.MarkFlagsMutuallyExclusive("expires", "ttl", "no-expiry")
+	updateCmd.MarkFlagsMutuallyExclusive("rotate-every", "no-rotate-every")
+	rootCmd.AddCommand(updateCmd)
+}
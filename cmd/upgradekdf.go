@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+var upgradeKDFCmd = &cobra.Command{
+	Use:     "upgrade-kdf",
+	Aliases: []string{"upgrade"},
+	Short:   "Re-derive the field key with stronger Argon2id parameters",
+	Long: `Re-derive the field encryption key from the master password with new
+Argon2id parameters and a fresh salt, and re-encrypt every stored key. The
+password itself does not change. Unset flags keep the vault's current values;
+with no --time or --memory, a vault below today's defaults is raised to them.
+A vault created with --kdf scrypt moves to Argon2id, starting from the
+default parameters.
+
+Before writing, the vault file is copied to <vault>.bak-<timestamp>.
+--dry-run checks that every stored key decrypts and times one derivation
+with the new parameters, without changing anything.`,
+	Example: "  api-vault upgrade-kdf --time 3 --memory 128MB\n  api-vault upgrade",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		db, pw, err := openVaultPassword()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		cur, isArgon := db.KDF().(core.Argon2Params)
+		p := cur
+		def := core.DefaultArgon2Params()
+		switch {
+		case !isArgon:
+			p = def
+		case !cmd.Flags().Changed("time") && !cmd.Flags().Changed("memory") && cur.WeakerThan(def):
+			// No flags on a vault below the defaults: raise it to them.
+			p.Time, p.Memory = max(cur.Time, def.Time), max(cur.Memory, def.Memory)
+		}
+		if cmd.Flags().Changed("time") {
+			p.Time, _ = cmd.Flags().GetUint32("time")
+		}
+		if cmd.Flags().Changed("threads") {
+			p.Threads, _ = cmd.Flags().GetUint8("threads")
+		}
+		if cmd.Flags().Changed("memory") {
+			s, _ := cmd.Flags().GetString("memory")
+			if p.Memory, err = parseMemoryKiB(s); err != nil {
+				return err
+			}
+		}
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		switch {
+		case !isArgon:
+			fmt.Fprintf(os.Stderr, "%s → Argon2id: time %d, memory %d KiB, threads %d\n",
+				db.KDF().ID(), p.Time, p.Memory, p.Threads)
+		case p == cur:
+			return fmt.Errorf("parameters unchanged (time %d, memory %d KiB, threads %d)", p.Time, p.Memory, p.Threads)
+		default:
+			fmt.Fprintf(os.Stderr, "Argon2id: time %d → %d, memory %d → %d KiB, threads %d → %d\n",
+				cur.Time, p.Time, cur.Memory, p.Memory, cur.Threads, p.Threads)
+		}
+
+		if dryRun {
+			rep, err := db.CheckRekey(pw)
+			if err != nil {
+				return fmt.Errorf("dry run: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "Credentials: %d (%d encrypted fields)\n", rep.Credentials, rep.Blobs)
+			fmt.Fprintf(os.Stderr, "Key derivation with new parameters: %s per unlock\n", p.Cost().Round(time.Millisecond))
+			if !rep.OK() {
+				for _, name := range rep.Failed {
+					fmt.Fprintf(os.Stderr, "  ✗ %s: cannot decrypt\n", name)
+				}
+				return fmt.Errorf("%d credential(s) would block the upgrade", len(rep.Failed))
+			}
+			fmt.Fprintln(os.Stderr, "✓ All fields decrypt; the upgrade would succeed.")
+			return nil
+		}
+
+		backup := core.BackupPath(vaultPath, time.Now())
+		if err := db.Backup(backup); err != nil {
+			return fmt.Errorf("backup: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Backed up vault to %s\n", backup)
+
+		if err := db.UpgradeKDF(pw, p); err != nil {
+			return fmt.Errorf("upgrade kdf: %w", err)
+		}
+		fmt.Fprintln(os.Stderr, "✓ Field key re-derived; all credentials re-encrypted.")
+		return nil
+	},
+}
+
+// parseMemoryKiB parses sizes like "128MB", "64MiB", "1GB" or "65536KiB"
+// into KiB. Units are binary; a bare number is MiB.
+func parseMemoryKiB(s string) (uint32, error) {
+	u := strings.ToUpper(strings.TrimSpace(s))
+	mult := uint64(1024)
+	for _, unit := range []struct {
+		suffix string
+		kib    uint64
+	}{
+		{"KIB", 1}, {"KB", 1}, {"K", 1},
+		{"MIB", 1024}, {"MB", 1024}, {"M", 1024},
+		{"GIB", 1 << 20}, {"GB", 1 << 20}, {"G", 1 << 20},
+	} {
+		if strings.HasSuffix(u, unit.suffix) {
+			u, mult = strings.TrimSpace(strings.TrimSuffix(u, unit.suffix)), unit.kib
+			break
+		}
+	}
+	n, err := strconv.ParseUint(u, 10, 32)
+	if err != nil || n == 0 || n*mult > 1<<32-1 {
+		return 0, fmt.Errorf("invalid memory size %q (e.g. 128MB)", s)
+	}
+	return uint32(n * mult), nil
+}
+
+func init() {
+	upgradeKDFCmd.Flags().Uint32("time", 0, "Argon2id passes")
+	upgradeKDFCmd.Flags().String("memory", "", "Argon2id memory, e.g. 128MB")
+	upgradeKDFCmd.Flags().Uint8("threads", 0, "Argon2id parallelism")
+	upgradeKDFCmd.Flags().Bool("dry-run", false, "Check the upgrade without changing anything")
+	rootCmd.AddCommand(upgradeKDFCmd)
+}
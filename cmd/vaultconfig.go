@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+// vaultToggleCmd builds a "config <name> [on|off]" command for a per-vault
+// setting: with no argument it prints the state, otherwise it sets it.
+func vaultToggleCmd(use, short, long, label string, get func(*core.Database) bool, set func(*core.Database, bool) error) *cobra.Command {
+	return &cobra.Command{
+		Use:       use + " [on|off]",
+		Short:     short,
+		Long:      long,
+		Args:      cobra.MaximumNArgs(1),
+		ValidArgs: []string{"on", "off"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := openVault()
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			if len(args) == 0 {
+				state := "off"
+				if get(db) {
+					state = "on"
+				}
+				fmt.Println(state)
+				return nil
+			}
+
+			var on bool
+			switch args[0] {
+			case "on":
+				on = true
+			case "off":
+			default:
+				return fmt.Errorf("expected on or off, got %q", args[0])
+			}
+			if err := set(db, on); err != nil {
+				return fmt.Errorf("%s: %w", use, err)
+			}
+			fmt.Fprintf(os.Stderr, "%s %s\n", label, args[0])
+			return nil
+		},
+	}
+}
+
+var configHistoryEncryptionCmd = vaultToggleCmd("history-encryption",
+	"Show or set encryption of rotation history metadata",
+	`Show or set whether rotation metadata is encrypted under the field key,
+the same second layer stored secrets get. This is a per-vault setting and
+is off by default. Switching it converts the existing history in place.`,
+	"Rotation metadata encryption",
+	(*core.Database).RotationMetadataEncrypted,
+	(*core.Database).SetRotationMetadataEncryption,
+)
+
+func init() {
+	configCmd.AddCommand(configHistoryEncryptionCmd)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var vaultsCmd = &cobra.Command{
+	Use:   "vaults",
+	Short: "List the vaults in the vault directory",
+	Long: `List the .db files in the vault directory (~/.api-vault unless the config
+or --vault-path points elsewhere) with their size and modification time.
+The one the current settings select is marked with *. Any other is opened
+with --vault <name>. The vaults are not opened, so no password is needed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := os.ReadDir(vaultDir)
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "No vault directory at %s. Run 'api-vault init' first.\n", vaultDir)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read vault directory: %w", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\tNAME\tSIZE\tMODIFIED")
+		found := 0
+		for _, e := range entries {
+			if e.IsDir() || filepath.Ext(e.Name()) != ".db" {
+				continue
+			}
+			fi, err := e.Info()
+			if err != nil {
+				return fmt.Errorf("stat %s: %w", e.Name(), err)
+			}
+			mark := ""
+			if filepath.Join(vaultDir, e.Name()) == filepath.Clean(vaultPath) {
+				mark = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", mark, strings.TrimSuffix(e.Name(), ".db"),
+				fi.Size(), fi.ModTime().Format("2006-01-02 15:04:05"))
+			found++
+		}
+		if found == 0 {
+			fmt.Fprintf(os.Stderr, "No vaults in %s. Run 'api-vault init' first.\n", vaultDir)
+			return nil
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(vaultsCmd)
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/busyrockin/api-vault/core"
+)
+
+func TestSelectVault(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".api-vault")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for name, secret := range map[string]string{"vault": "sk-default", "work": "sk-work"} {
+		path := filepath.Join(dir, name+".db")
+		db, err := core.NewDatabase(path, "test-password")
+		if err != nil {
+			t.Fatalf("NewDatabase: %v", err)
+		}
+		db.AddCredential("svc", secret, "t")
+		db.Close()
+		os.Chmod(path, 0600)
+	}
+	other := filepath.Join(t.TempDir(), "elsewhere.db")
+	db, err := core.NewDatabase(other, "test-password")
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	db.AddCredential("svc", "sk-elsewhere", "t")
+	db.Close()
+	t.Setenv("API_VAULT_PASSWORD", "test-password")
+	t.Setenv("API_VAULT_NO_AGENT", "1")
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{nil, "sk-default"},
+		{[]string{"--vault", "work"}, "sk-work"},
+		{[]string{"--vault", "work.db"}, "sk-work"},
+		{[]string{"--vault-path", other, "--insecure-permissions"}, "sk-elsewhere"},
+	} {
+		out, err := runCLI(t, append([]string{"get", "svc"}, tc.args...)...)
+		if err != nil || out != tc.want {
+			t.Errorf("get %v = %q, %v; want %q", tc.args, out, err, tc.want)
+		}
+	}
+	for _, args := range [][]string{
+		{"--vault", "../work"},
+		{"--vault", ".hidden"},
+		{"--vault", "work", "--vault-path", other},
+	} {
+		if _, err := runCLI(t, append([]string{"get", "svc"}, args...)...); err == nil {
+			t.Errorf("get %v should be refused", args)
+		}
+	}
+
+	out, err := runCLI(t, "vaults", "--vault", "work")
+	if err != nil {
+		t.Fatalf("vaults: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], " ") || !strings.Contains(lines[1], "vault") ||
+		!strings.HasPrefix(lines[2], "*") || !strings.Contains(lines[2], "work") {
+		t.Fatalf("vaults output:\n%s", out)
+	}
+	if strings.Contains(out, "elsewhere") {
+		t.Errorf("vaults listed a file outside the vault directory:\n%s", out)
+	}
+}
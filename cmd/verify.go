@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the vault for corruption",
+	Long: `Check the whole vault, e.g. after copying it between machines: SQLCipher's
+page integrity check, the integrity digest, a trial decryption of every
+stored key, and that every rotation record belongs to a credential. Only
+counts and credential names are printed, never keys.
+
+Exits non-zero if any check fails: 6 if a key didn't decrypt, 7 for any
+other problem.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		r, err := db.Verify()
+		if err != nil {
+			return fmt.Errorf("verify: %w", err)
+		}
+
+		check := func(ok bool, format string, args ...any) {
+			mark := "✓"
+			if !ok {
+				mark = "✗"
+			}
+			fmt.Fprintf(os.Stderr, "  %s "+format+"\n", append([]any{mark}, args...)...)
+		}
+		check(len(r.SQLiteProblems) == 0, "database pages")
+		for _, p := range r.SQLiteProblems {
+			fmt.Fprintf(os.Stderr, "      %s\n", p)
+		}
+		check(r.DigestOK, "integrity digest")
+		check(len(r.Failed) == 0, "keys: %d of %d credentials decrypt", r.Decrypted, r.Credentials)
+		if len(r.Failed) > 0 {
+			fmt.Fprintf(os.Stderr, "      failed: %s\n", strings.Join(r.Failed, ", "))
+		}
+		check(r.OrphanedRotations == 0, "rotation history: %d orphaned records", r.OrphanedRotations)
+
+		switch {
+		case len(r.Failed) > 0:
+			return userErr(core.ErrDecryptFail, "%d credential(s) failed to decrypt", len(r.Failed))
+		case !r.OK():
+			return userErr(core.ErrIntegrity, "vault failed verification")
+		}
+		fmt.Fprintln(os.Stderr, "Vault verified.")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/busyrockin/api-vault/core"
+	"github.com/busyrockin/api-vault/rotation"
+	"github.com/spf13/cobra"
+)
+
+var configWebhookCmd = &cobra.Command{
+	Use:   "webhook [url|off]",
+	Short: "Show or set the URL notified after each rotation",
+	Long: `Show or set the webhook every successful rotation is reported to: a POST
+of {"event":"rotation","credential":...,"api_type":...,"plugin":...,
+"rotated_fields":[...],"rotated_by":...,"timestamp":...,"old_key":...},
+old_key being revoked, revoke_failed, grace or active. No key is ever
+sent. The body is signed with HMAC-SHA256 in the X-API-Vault-Signature
+header ("sha256=<hex>"); the signing secret is generated the first time a
+URL is set and printed once. --show-secret prints it again.
+
+Failed deliveries are retried with backoff, then reported as a warning;
+the rotation itself stands. API_VAULT_WEBHOOK_URL and
+API_VAULT_WEBHOOK_SECRET override the vault's setting, e.g. for a
+scheduled job.`,
+	Example: "  api-vault config webhook https://hooks.example.com/api-vault\n  api-vault config webhook off",
+	Args:    cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		showSecret, _ := cmd.Flags().GetBool("show-secret")
+
+		db, err := openVault()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		url, secret, err := db.RotationWebhook()
+		if err != nil {
+			return fmt.Errorf("webhook: %w", err)
+		}
+		switch {
+		case len(args) == 0 && showSecret:
+			if secret == "" {
+				return fmt.Errorf("no webhook secret is set")
+			}
+			fmt.Println(secret)
+			return nil
+		case len(args) == 0:
+			if url == "" {
+				url = "off"
+			}
+			fmt.Println(url)
+			return nil
+		case args[0] == "off":
+			if err := db.SetRotationWebhook("", ""); err != nil {
+				return fmt.Errorf("webhook: %w", err)
+			}
+			fmt.Fprintln(os.Stderr, "Rotation webhook off")
+			return nil
+		}
+
+		fresh := secret == ""
+		if fresh {
+			b := make([]byte, 32)
+			if _, err := rand.Read(b); err != nil {
+				return err
+			}
+			secret = hex.EncodeToString(b)
+		}
+		if err := db.SetRotationWebhook(args[0], secret); err != nil {
+			return fmt.Errorf("webhook: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Rotation webhook set to %s\n", args[0])
+		if fresh || showSecret {
+			fmt.Fprintln(os.Stderr, "Signing secret (configure your receiver with it):")
+			fmt.Println(secret)
+		}
+		return nil
+	},
+}
+
+// webhookTimeout bounds a notification including its retries.
+const webhookTimeout = 45 * time.Second
+
+// notifyRotation reports a saved rotation to the webhook, if one is
+// configured. Failures are only warned about: the new key is already in
+// the vault.
+func notifyRotation(db *core.Database, ev rotation.WebhookEvent) {
+	url, secret, err := db.RotationWebhook()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: rotation webhook: %v\n", err)
+		return
+	}
+	if v := os.Getenv("API_VAULT_WEBHOOK_URL"); v != "" {
+		url = v
+	}
+	if v := os.Getenv("API_VAULT_WEBHOOK_SECRET"); v != "" {
+		secret = v
+	}
+	if url == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	w := &rotation.Webhook{URL: url, Secret: secret, Backoff: time.Second}
+	if err := w.Notify(ctx, ev); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s rotated, but the notification failed: %v\n", ev.Credential, err)
+	}
+}
+
+func init() {
+	configWebhookCmd.Flags().Bool("show-secret", false, "Print the signing secret")
+	configCmd.AddCommand(configWebhookCmd)
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// With WithAccessLog, successful secret reads are recorded in access_log:
+// which credential, when, and through which call. It is off by default
+// because it turns every read into a write, serialized with all other
+// writes. Logging is best effort; a failed insert never fails the read. A
+// vault opened read-only records nothing. The log is not covered by the
+// integrity HMAC and is removed along with its credential.
+
+// Operations recorded in access_log.
+const (
+	AccessGet    = "get"    // GetCredential, GetMany
+	AccessGetV2  = "get_v2" // GetCredentialV2
+	AccessUnlock = "unlock" // UnlockCredential
+)
+
+// AccessRecord is one entry of a credential's access history.
+type AccessRecord struct {
+	Name      string
+	At        time.Time
+	Operation string
+}
+
+// lastAccessedColumn selects a credential's most recent access time.
+const lastAccessedColumn = `(SELECT MAX(accessed_at) FROM access_log WHERE credential_name = credentials.name)`
+
+// WithAccessLog records every secret read in access_log, for
+// AccessHistory and Credential.LastAccessed.
+func WithAccessLog() Option {
+	return func(o *options) { o.recordReads = true }
+}
+
+// WithoutAccessLog turns access logging off, undoing an earlier
+// WithAccessLog. Off is the default.
+func WithoutAccessLog() Option {
+	return func(o *options) { o.recordReads = false }
+}
+
+// logAccess records a read of name. Callers must hold d.mu if access
+// logging is on.
+func (d *Database) logAccess(ctx context.Context, name, op string) {
+	d.log.Debug("credential read", "name", name, "operation", op)
+	if !d.recordReads || d.readOnly {
+		return
+	}
+	d.db.ExecContext(ctx, `INSERT INTO access_log (credential_name, accessed_at, operation) VALUES (?, ?, ?)`,
+		name, d.now().Unix(), op)
+}
+
+// recordAccess is logAccess for the reads that run without d.mu (see
+// readonly.go). It takes d.mu only when there is a row to write.
+func (d *Database) recordAccess(ctx context.Context, name, op string) {
+	if d.recordReads && !d.readOnly {
+		d.mu.RLock()
+		defer d.mu.RUnlock()
+		if d.locked {
+			return
+		}
+	}
+	d.logAccess(ctx, name, op)
+}
+
+// AccessHistory returns the most recent limit reads of name, newest first.
+// A name that was never read, or doesn't exist, has no records.
+func (d *Database) AccessHistory(name string, limit int) ([]AccessRecord, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	rows, err := d.reader().Query(
+		`SELECT accessed_at, operation FROM access_log
+		 WHERE credential_name = ? ORDER BY accessed_at DESC, rowid DESC LIMIT ?`,
+		name, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []AccessRecord
+	for rows.Next() {
+		var at int64
+		r := AccessRecord{Name: name}
+		if err := rows.Scan(&at, &r.Operation); err != nil {
+			return nil, err
+		}
+		r.At = time.Unix(at, 0)
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// deleteAccessLog drops name's access history.
+func deleteAccessLog(ctx context.Context, tx *sql.Tx, name string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM access_log WHERE credential_name = ?`, name)
+	return err
+}
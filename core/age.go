@@ -0,0 +1,38 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// HumanAge describes how long ago t was, e.g. "3 months ago", for listings
+// where staleness matters more than the exact date.
+func HumanAge(t time.Time) string {
+	return humanAge(time.Since(t))
+}
+
+func humanAge(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute")
+	case d < day:
+		return plural(int(d/time.Hour), "hour")
+	case d < 14*day:
+		return plural(int(d/day), "day")
+	case d < 60*day:
+		return plural(int(d/(7*day)), "week")
+	case d < 365*day:
+		return plural(int(d/(30*day)), "month")
+	}
+	return plural(int(d/(365*day)), "year")
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s ago", unit)
+	}
+	return fmt.Sprintf("%d %ss ago", n, unit)
+}
@@ -0,0 +1,285 @@
+package core
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Attestations are signed with an Ed25519 key that belongs to the vault,
+// not to the master password: its seed is generated on first use and kept
+// in the config table under the field key, so changing the password
+// re-seals it but keeps the same public key. Auditors pin that key once
+// (attest --public-key) and can then check any later attestation.
+const (
+	attestFormat       = "api-vault-attestation"
+	attestVersion      = 1
+	attestKeyConfigKey = "attest_key"
+)
+
+// ErrBadAttestation is returned when an attestation's signature doesn't
+// verify, or it was signed by a different key than expected.
+var ErrBadAttestation = errors.New("attestation signature invalid or from another vault")
+
+// AttestedCredential is one credential's entry in an attestation.
+// SecretHash is an HMAC-SHA256 of the name and secret under a key derived
+// from the attestation seed: equal across runs while the secret is
+// unchanged, but useless for guessing the secret without the vault. It is
+// empty for credentials without a secret and for passphrase-protected ones,
+// whose secret can't be read.
+type AttestedCredential struct {
+	Name        string     `json:"name"`
+	APIType     string     `json:"api_type,omitempty"`
+	LastRotated *time.Time `json:"last_rotated,omitempty"`
+	Disabled    bool       `json:"disabled,omitempty"`
+	Protected   bool       `json:"protected,omitempty"`
+	SecretHash  string     `json:"secret_hash,omitempty"`
+}
+
+// Attestation is the signed statement of a vault's contents.
+type Attestation struct {
+	Format      string               `json:"format"`
+	Version     int                  `json:"version"`
+	CreatedAt   time.Time            `json:"created_at"`
+	PublicKey   []byte               `json:"public_key"`
+	Credentials []AttestedCredential `json:"credentials"`
+}
+
+// signedAttestation is the file format: the attestation and an Ed25519
+// signature over its compact JSON encoding, so reformatting the file
+// doesn't break verification.
+type signedAttestation struct {
+	Attestation json.RawMessage `json:"attestation"`
+	Signature   []byte          `json:"signature"`
+}
+
+// Attest returns a signed attestation of every credential's name, type,
+// last rotation and secret hash, as indented JSON. No secrets are included.
+// The first call creates the vault's attestation key.
+func (d *Database) Attest() ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	seed, err := d.attestSeed(true)
+	if err != nil {
+		return nil, err
+	}
+	priv := ed25519.NewKeyFromSeed(seed)
+	hashKey := attestHashKey(seed)
+
+	rows, err := d.db.Query(
+		`SELECT name, api_type, last_rotated, disabled, extra_salt IS NOT NULL, api_key FROM credentials ORDER BY name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	att := Attestation{
+		Format:      attestFormat,
+		Version:     attestVersion,
+		CreatedAt:   d.now().UTC(),
+		PublicKey:   priv.Public().(ed25519.PublicKey),
+		Credentials: []AttestedCredential{},
+	}
+	for rows.Next() {
+		var c AttestedCredential
+		var apiType sql.NullString
+		var lastRotated sql.NullInt64
+		var blob []byte
+		if err := rows.Scan(&c.Name, &apiType, &lastRotated, &c.Disabled, &c.Protected, &blob); err != nil {
+			return nil, err
+		}
+		c.APIType = apiType.String
+		if lastRotated.Valid {
+			t := time.Unix(lastRotated.Int64, 0).UTC()
+			c.LastRotated = &t
+		}
+		if !c.Protected && len(blob) > 0 {
+			secret, err := d.decrypt(blob)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", c.Name, err)
+			}
+			c.SecretHash = secretHash(hashKey, c.Name, secret)
+			clear(secret)
+		}
+		att.Credentials = append(att.Credentials, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(att)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(signedAttestation{payload, ed25519.Sign(priv, payload)}, "", "  ")
+}
+
+// AttestationPublicKey returns the vault's attestation public key, creating
+// the key if needed.
+func (d *Database) AttestationPublicKey() (ed25519.PublicKey, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	seed, err := d.attestSeed(true)
+	if err != nil {
+		return nil, err
+	}
+	return ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey), nil
+}
+
+// VerifyAttestation checks data's signature and returns the attestation.
+// With a non-nil pub, the attestation must also have been signed by pub;
+// without one, only self-consistency is checked, which proves nothing
+// about who signed it.
+func VerifyAttestation(data []byte, pub ed25519.PublicKey) (*Attestation, error) {
+	var s signedAttestation
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse attestation: %w", err)
+	}
+	var payload bytes.Buffer
+	if err := json.Compact(&payload, s.Attestation); err != nil {
+		return nil, fmt.Errorf("parse attestation: %w", err)
+	}
+	var att Attestation
+	if err := json.Unmarshal(payload.Bytes(), &att); err != nil {
+		return nil, fmt.Errorf("parse attestation: %w", err)
+	}
+	if att.Format != attestFormat {
+		return nil, fmt.Errorf("not an api-vault attestation (format %q)", att.Format)
+	}
+	if att.Version != attestVersion {
+		return nil, fmt.Errorf("unsupported attestation version %d", att.Version)
+	}
+	if len(att.PublicKey) != ed25519.PublicKeySize {
+		return nil, ErrBadAttestation
+	}
+	if pub != nil && !bytes.Equal(pub, att.PublicKey) {
+		return nil, ErrBadAttestation
+	}
+	if !ed25519.Verify(att.PublicKey, payload.Bytes(), s.Signature) {
+		return nil, ErrBadAttestation
+	}
+	return &att, nil
+}
+
+// CompareAttestations lists what changed from old to cur, one line per
+// credential, sorted by name. Nothing is returned when they match.
+func CompareAttestations(old, cur *Attestation) []string {
+	before := make(map[string]AttestedCredential, len(old.Credentials))
+	for _, c := range old.Credentials {
+		before[c.Name] = c
+	}
+	var changes []string
+	for _, c := range cur.Credentials {
+		o, ok := before[c.Name]
+		delete(before, c.Name)
+		if !ok {
+			changes = append(changes, c.Name+": added")
+			continue
+		}
+		var what []string
+		if o.SecretHash != c.SecretHash || o.Protected != c.Protected {
+			what = append(what, "secret changed")
+		}
+		if !timePtrEqual(o.LastRotated, c.LastRotated) {
+			what = append(what, "rotated")
+		}
+		if o.APIType != c.APIType {
+			what = append(what, fmt.Sprintf("type %q → %q", o.APIType, c.APIType))
+		}
+		switch {
+		case !o.Disabled && c.Disabled:
+			what = append(what, "disabled")
+		case o.Disabled && !c.Disabled:
+			what = append(what, "enabled")
+		}
+		if len(what) > 0 {
+			changes = append(changes, c.Name+": "+strings.Join(what, ", "))
+		}
+	}
+	for _, o := range old.Credentials {
+		if _, ok := before[o.Name]; ok {
+			changes = append(changes, o.Name+": removed")
+		}
+	}
+	slices.Sort(changes)
+	return changes
+}
+
+// attestSeed returns the vault's attestation key seed, creating and storing
+// one when create is set and there is none (nil otherwise). Callers must
+// hold d.mu.
+func (d *Database) attestSeed(create bool) ([]byte, error) {
+	var blob []byte
+	err := d.db.QueryRow(`SELECT value FROM config WHERE key = ?`, attestKeyConfigKey).Scan(&blob)
+	switch {
+	case err == nil:
+		return d.decrypt(blob)
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	case !create:
+		return nil, nil
+	}
+
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	err = d.writeTx(func(tx *sql.Tx) error {
+		return d.storeAttestSeed(tx, seed)
+	})
+	return seed, err
+}
+
+// storeAttestSeed seals seed under the current field key. Callers must hold
+// d.mu.
+func (d *Database) storeAttestSeed(q dbtx, seed []byte) error {
+	blob, err := d.encrypt(seed)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(`INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)`, attestKeyConfigKey, blob)
+	return err
+}
+
+// attestHashKey separates the secret-hash key from the signing seed.
+func attestHashKey(seed []byte) []byte {
+	m := hmac.New(sha256.New, seed)
+	m.Write([]byte("api-vault attestation hash v1"))
+	return m.Sum(nil)
+}
+
+func secretHash(key []byte, name string, secret []byte) string {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(name))
+	m.Write([]byte{0})
+	m.Write(secret)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+func timePtrEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// A long-running process holding a Database keeps the field key, and
+// SQLCipher keeps its own, in memory for as long as the vault is open.
+// Relock wipes the field key and closes both connection pools; every
+// access then fails with ErrLocked until Unlock re-derives the key from
+// the master password. SetAutoLock does the same after a period without
+// any access, so an idle daemon holds no key material.
+//
+// Each exported method that touches the vault counts as an access (see
+// use). Accessors of in-memory settings such as Argon2Params or ReadOnly
+// neither count nor fail while locked.
+
+// SetAutoLock relocks d once idle passes with no vault access. It replaces
+// any earlier setting; idle <= 0 turns auto-locking off. The check runs on
+// a background goroutine that Close stops.
+func (d *Database) SetAutoLock(idle time.Duration) {
+	d.stopAutoLock()
+	if idle <= 0 {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.lastUse.Store(time.Now().UnixNano())
+	done, exited := make(chan struct{}), make(chan struct{})
+	d.stopIdle = func() {
+		close(done)
+		<-exited
+	}
+
+	tick := idle / 4
+	if tick > time.Minute {
+		tick = time.Minute
+	}
+	go func() {
+		defer close(exited)
+		t := time.NewTicker(tick)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+			if time.Since(time.Unix(0, d.lastUse.Load())) < idle {
+				continue
+			}
+			d.mu.Lock()
+			select {
+			case <-done:
+				d.mu.Unlock()
+				return
+			default:
+			}
+			// An access may have slipped in while we waited for the lock.
+			if time.Since(time.Unix(0, d.lastUse.Load())) >= idle {
+				d.relock()
+			}
+			d.mu.Unlock()
+		}
+	}()
+}
+
+// stopAutoLock ends the SetAutoLock goroutine, if any, and waits for it.
+// It must be called without d.mu held, since the goroutine may be waiting
+// for it.
+func (d *Database) stopAutoLock() {
+	d.mu.Lock()
+	stop := d.stopIdle
+	d.stopIdle = nil
+	d.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+// Relock wipes the field key and closes the connection pools now. It is a
+// no-op on a locked vault. Rotation locks taken with LockRotation stay in
+// the vault until they expire.
+func (d *Database) Relock() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.relock()
+	return nil
+}
+
+// Locked reports whether d is locked by Relock or SetAutoLock.
+func (d *Database) Locked() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.locked
+}
+
+// Unlock reopens a locked vault with the master password and re-derives
+// the field key. A wrong password returns ErrWrongPassword and leaves d
+// locked. On a vault that isn't locked it only checks the password.
+func (d *Database) Unlock(password string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.locked {
+		return d.checkPassword(password)
+	}
+	db, sqlKey, err := openKeyed(d.path, password, d.cipher)
+	if err != nil {
+		if errors.Is(err, ErrWrongPassword) {
+			d.log.Warn("wrong master password", "path", d.path)
+		}
+		return err
+	}
+	salt, err := loadOrCreateSalt(db)
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("salt: %w", err)
+	}
+	if err := d.setKey(d.kdf.Derive(password, salt)); err != nil {
+		db.Close()
+		return err
+	}
+	if err := d.openReader(sqlKey); err != nil {
+		d.wipeKey()
+		db.Close()
+		return err
+	}
+	d.db, d.locked = db, false
+	d.lastUse.Store(time.Now().UnixNano())
+	d.log.Info("vault unlocked", "path", d.path)
+	return nil
+}
+
+// relock is Relock for callers holding d.mu.
+func (d *Database) relock() {
+	if d.locked {
+		return
+	}
+	d.wipeKey()
+	d.closeReader()
+	d.db.Close()
+	d.locked = true
+	d.log.Info("vault locked", "path", d.path)
+}
+
+// use records a vault access, or returns ErrLocked. Callers must hold d.mu
+// (read or write).
+func (d *Database) use() error {
+	if d.locked {
+		return ErrLocked
+	}
+	d.lastUse.Store(time.Now().UnixNano())
+	return nil
+}
+
+// wipeKey zeroes (and munlocks) the field and MAC keys. Callers must hold
+// d.mu.
+func (d *Database) wipeKey() {
+	releaseKeys(d.key, d.macKey, d.memLocked)
+	d.key, d.macKey, d.aead, d.memLocked = nil, nil, nil, false
+}
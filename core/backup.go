@@ -0,0 +1,141 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// BackupKeep is how many migration backups (<vault>.bak-migrate-*) are
+// left behind; older ones are pruned. Backups taken under BackupPath, e.g.
+// by upgrade-kdf, are never pruned.
+const BackupKeep = 5
+
+// BackupPath returns the conventional backup name for the vault at path:
+// <path>.bak-YYYYMMDD-HHMMSS.
+func BackupPath(path string, t time.Time) string {
+	return fmt.Sprintf("%s.bak-%s", path, t.Format("20060102-150405"))
+}
+
+// migrationBackupPath is the name backupBeforeRewrite copies to:
+// <path>.bak-migrate-YYYYMMDD-HHMMSS. Its own prefix keeps pruneBackups
+// away from backups the user asked for.
+func migrationBackupPath(path string, t time.Time) string {
+	return fmt.Sprintf("%s.bak-migrate-%s", path, t.Format("20060102-150405"))
+}
+
+// Backup copies the vault file, and its cipher sidecar if any, to dst. It
+// never overwrites: dst must not exist. The copy stays encrypted under the
+// same password and opens like the original.
+func (d *Database) Backup(dst string) error {
+	// Writers hold d.mu, so the file is quiescent while we read it.
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return err
+	}
+
+	if err := d.checkpoint(); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return backupFiles(d.path, dst)
+}
+
+// backupFiles copies the vault at src, its cipher sidecar and any WAL to
+// dst. A non-empty WAL is replayed when the backup is first opened; the
+// -shm index is rebuilt, so it isn't copied.
+func backupFiles(src, dst string) error {
+	if err := copyNew(src, dst); err != nil {
+		return err
+	}
+	for _, suffix := range []string{cipherSidecar(""), "-wal"} {
+		if fi, err := os.Stat(src + suffix); err != nil || fi.Size() == 0 {
+			continue
+		}
+		if err := copyNew(src+suffix, dst+suffix); err != nil {
+			removeBackup(dst)
+			return err
+		}
+	}
+	return nil
+}
+
+// removeBackup deletes a backup, its sidecar and any WAL.
+func removeBackup(path string) {
+	os.Remove(path)
+	os.Remove(cipherSidecar(path))
+	os.Remove(path + "-wal")
+}
+
+// pruneBackups removes all but the newest keep migration backups of path.
+// The timestamp suffix sorts chronologically, so name order is age order.
+func pruneBackups(path string, keep int) error {
+	matches, err := filepath.Glob(path + ".bak-migrate-*")
+	if err != nil {
+		return err
+	}
+	var backups []string
+	for _, m := range matches {
+		if !strings.HasSuffix(m, cipherSidecar("")) && !strings.HasSuffix(m, "-wal") {
+			backups = append(backups, m)
+		}
+	}
+	slices.Sort(backups)
+	for len(backups) > keep {
+		removeBackup(backups[0])
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// backupBeforeRewrite copies the vault to migrationBackupPath when a migration that
+// rebuilds tables is pending, returning the backup's path ("" if none was
+// needed or skip is set). Older backups beyond BackupKeep are pruned.
+func backupBeforeRewrite(db *sql.DB, path string, skip bool) (string, error) {
+	if skip {
+		return "", nil
+	}
+	pending, err := publicKeyNeedsBlob(db)
+	if err != nil || !pending {
+		return "", err
+	}
+	dst := migrationBackupPath(path, time.Now())
+	if err := backupFiles(path, dst); err != nil {
+		return "", fmt.Errorf("back up before migrating: %w", err)
+	}
+	// Best effort: a failed prune leaves extra files, not a broken vault.
+	_ = pruneBackups(path, BackupKeep)
+	return dst, nil
+}
+
+// copyNew copies src to a new file at dst. The copy is owner-only (0600),
+// which is never looser than a vault file is allowed to be.
+func copyNew(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	sqlite3 "github.com/mutecomm/go-sqlcipher/v4"
+)
+
+// CipherSettings are SQLCipher's page-level parameters. Zero values keep
+// SQLCipher's defaults (kdf_iter 256000, cipher_page_size 4096).
+//
+// SQLCipher needs the same values on every open before it can read a single
+// page, so they can't live inside the encrypted file. Non-default settings
+// are recorded in a plaintext sidecar next to the vault (<path>.cipher);
+// nothing in it is secret.
+type CipherSettings struct {
+	KDFIter  int `json:"kdf_iter,omitempty"`
+	PageSize int `json:"cipher_page_size,omitempty"`
+}
+
+func (c CipherSettings) validate() error {
+	if c.KDFIter < 0 {
+		return fmt.Errorf("kdf_iter must be positive, got %d", c.KDFIter)
+	}
+	if c.PageSize != 0 && (c.PageSize < 512 || c.PageSize > 65536 || c.PageSize&(c.PageSize-1) != 0) {
+		return fmt.Errorf("cipher_page_size must be a power of two between 512 and 65536, got %d", c.PageSize)
+	}
+	return nil
+}
+
+// ReadCipherSettings returns the settings recorded for the vault at path,
+// or zero values if it uses SQLCipher's defaults.
+func ReadCipherSettings(path string) (CipherSettings, error) {
+	var cs CipherSettings
+	b, err := os.ReadFile(cipherSidecar(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return cs, nil
+	}
+	if err != nil {
+		return cs, err
+	}
+	if err := json.Unmarshal(b, &cs); err != nil {
+		return cs, fmt.Errorf("%s: %w", cipherSidecar(path), err)
+	}
+	return cs, nil
+}
+
+func writeCipherSettings(path string, cs CipherSettings) error {
+	b, _ := json.Marshal(cs)
+	return os.WriteFile(cipherSidecar(path), b, 0600)
+}
+
+func cipherSidecar(path string) string { return path + ".cipher" }
+
+// dsnKey encodes password for the driver's _pragma_key DSN parameter so
+// that SQLCipher is keyed with it verbatim, as PRAGMA rekey does. The
+// driver query-decodes the value and runs PRAGMA key = "<value>", so it is
+// query-escaped with double quotes doubled.
+func dsnKey(password string) string {
+	return url.QueryEscape(strings.ReplaceAll(password, `"`, `""`))
+}
+
+// legacyDSNKey returns the key the driver derived from password when it
+// was put in the DSN unescaped, as older versions did: "+" became a space,
+// %XX sequences were decoded and "&" ended the key. ok is false when that
+// is the password itself or no vault could have been keyed that way.
+func legacyDSNKey(password string) (key string, ok bool) {
+	q, err := url.ParseQuery("_pragma_key=" + password)
+	if err != nil {
+		return "", false
+	}
+	key = q.Get("_pragma_key")
+	return key, key != "" && key != password && !strings.Contains(key, `"`)
+}
+
+// connector opens SQLCipher connections with CipherSettings applied.
+//
+// The driver reads page 1 before any ConnectHook runs, so per-connection
+// pragmas are too late. cipher_page_size goes through the DSN (applied
+// right after the key); kdf_iter only has a process-wide default, which is
+// swapped in under kdfDefaultMu for the duration of the open. Opens that
+// want SQLCipher's own default take the mutex too, or they could read the
+// default while another vault has it swapped and key their file with the
+// wrong kdf_iter.
+type connector struct {
+	dsn     string
+	kdfIter int
+	driver  *sqlite3.SQLiteDriver
+}
+
+var kdfDefaultMu sync.Mutex
+
+func newConnector(dsn string, cs CipherSettings) *connector {
+	if cs.PageSize > 0 {
+		dsn += fmt.Sprintf("&_pragma_cipher_page_size=%d", cs.PageSize)
+	}
+	return &connector{dsn: dsn, kdfIter: cs.KDFIter, driver: &sqlite3.SQLiteDriver{}}
+}
+
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	kdfDefaultMu.Lock()
+	defer kdfDefaultMu.Unlock()
+
+	if c.kdfIter == 0 {
+		return c.driver.Open(c.dsn)
+	}
+	prev, err := c.swapDefaultKDFIter(c.kdfIter)
+	if err != nil {
+		return nil, fmt.Errorf("kdf_iter: %w", err)
+	}
+	defer c.swapDefaultKDFIter(prev)
+	return c.driver.Open(c.dsn)
+}
+
+func (c *connector) Driver() driver.Driver { return c.driver }
+
+// swapDefaultKDFIter sets SQLCipher's default kdf_iter and returns the old one.
+func (c *connector) swapDefaultKDFIter(n int) (int, error) {
+	dc, err := c.driver.Open(":memory:")
+	if err != nil {
+		return 0, err
+	}
+	conn := dc.(*sqlite3.SQLiteConn)
+	defer conn.Close()
+
+	rows, err := conn.Query("PRAGMA cipher_default_kdf_iter", nil)
+	if err != nil {
+		return 0, err
+	}
+	vals := make([]driver.Value, 1)
+	err = rows.Next(vals)
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+	prev, err := strconv.Atoi(fmt.Sprint(vals[0]))
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = conn.Exec(fmt.Sprintf("PRAGMA cipher_default_kdf_iter = %d", n), nil)
+	return prev, err
+}
@@ -10,11 +10,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	_ "github.com/mutecomm/go-sqlcipher/v4"
 	"golang.org/x/crypto/argon2"
 )
 
@@ -28,18 +30,87 @@ const (
 	nonceLen     = 12
 )
 
+// Argon2Params are the Argon2id cost parameters for the field key.
+type Argon2Params struct {
+	Time    uint32
+	Memory  uint32 // KiB
+	Threads uint8
+	KeyLen  uint32
+}
+
+// DefaultArgon2Params returns the parameters used to derive field keys.
+func DefaultArgon2Params() Argon2Params {
+	return Argon2Params{Time: argonTime, Memory: argonMemory, Threads: argonThreads, KeyLen: argonKeyLen}
+}
+
+// DefaultMaxSecretSize caps a single key field. Anything larger is almost
+// certainly a mistake, e.g. --secret-file pointed at the wrong file.
+const DefaultMaxSecretSize = 1 << 20 // 1 MB
+
 // Sentinel errors.
 var (
-	ErrNotFound    = errors.New("credential not found")
-	ErrDuplicate   = errors.New("credential already exists")
-	ErrDecryptFail = errors.New("decryption failed")
+	ErrNotFound       = errors.New("credential not found")
+	ErrDuplicate      = errors.New("credential already exists")
+	ErrDecryptFail    = errors.New("decryption failed")
+	ErrTooLarge       = errors.New("secret exceeds maximum size")
+	ErrCipherMismatch = errors.New("cipher settings differ from the vault's; changing them requires a rekey")
+	ErrIntegrity      = errors.New("integrity check failed: vault contents changed outside api-vault")
+	ErrNoPlugin       = errors.New("no rotation plugin")
+	ErrDisabled       = errors.New("credential is disabled")
+
+	// ErrRotationInProgress means another rotation of the same credential
+	// holds its lock; see LockRotation.
+	ErrRotationInProgress = errors.New("rotation already in progress")
+
+	ErrPassphraseRequired = errors.New("credential is protected by an extra passphrase")
+	ErrWrongPassphrase    = errors.New("wrong credential passphrase")
+
+	// ErrWrongPassword means SQLCipher couldn't read the file with the given
+	// key. A corrupted file or mismatched cipher settings look the same.
+	ErrWrongPassword = errors.New("wrong password")
+
+	// ErrReadOnly is returned by every write on a vault opened with
+	// OpenReadOnly (or WithReadOnly).
+	ErrReadOnly = errors.New("vault is open read-only")
+
+	// ErrLocked is returned by every vault access after the key has been
+	// wiped by Relock or SetAutoLock, until Unlock.
+	ErrLocked = errors.New("vault is locked")
+
+	// ErrStop may be returned from an EachCredential callback to end the
+	// iteration early. EachCredential then returns nil.
+	ErrStop = errors.New("stop iteration")
 )
 
 // Database is an encrypted credential store backed by SQLCipher.
 type Database struct {
-	db  *sql.DB
-	key []byte // 32-byte AES-256-GCM key, in-memory only
-	mu  sync.RWMutex
+	db          *sql.DB
+	key         []byte      // 32-byte AES-256-GCM key, in-memory only
+	macKey      []byte      // integrity HMAC key, derived from key
+	aead        cipher.AEAD // AES-GCM over key, built once; safe for concurrent use
+	path        string
+	cipher      CipherSettings
+	kdf         KeyDeriver        // how the field key was derived; see kdf.go
+	locks       map[string]string // rotation locks held by this handle: name -> owner
+	sealMeta    bool              // encrypt rotations.metadata; see rotationmeta.go
+	wal         bool              // journal_mode=WAL; see wal.go
+	readOnly    bool              // reject every write with ErrReadOnly; see readonly.go
+	recordReads bool              // record reads in access_log; see accesslog.go
+	memLock     bool              // mlock key and macKey; see memlock.go
+	memLocked   bool              // whether they currently are
+	clock       func() time.Time  // time source for stored timestamps; see SetClock
+	log         *slog.Logger      // never nil; see logger.go
+	maxSecret   int
+	mu          sync.RWMutex
+
+	// rd is the read-only pool and field cipher the lock-free reads use,
+	// nil while locked; see readonly.go.
+	rd atomic.Pointer[readState]
+
+	// Idle locking; see autolock.go.
+	locked   bool         // key wiped and pools closed until Unlock
+	lastUse  atomic.Int64 // UnixNano of the last vault access
+	stopIdle func()       // ends the SetAutoLock goroutine
 }
 
 // Credential holds metadata about a stored credential. V1 methods still work
@@ -50,10 +121,22 @@ type Credential struct {
 	PublicKey                   *string
 	SecretKey                   *string
 	URL                         *string
+	URLs                        []string // every endpoint; URL mirrors URLs[0]
 	Config                      map[string]string
 	KeyID                       *string
 	LastRotated                 *time.Time
+	LastAccessed                *time.Time     // latest access_log entry; nil if never read
+	ExpiresAt                   *time.Time     // nil: never expires; see expiry.go
+	RotationInterval            *time.Duration // nil: no schedule; see schedule.go
 	CreatedAt, UpdatedAt        time.Time
+	Disabled                    bool     // out of service; Get returns ErrDisabled
+	Tags                        []string // case-insensitive labels; see tags.go
+
+	// Passphrase, when set on add, wraps SecretKey under an extra key so
+	// reading it needs UnlockCredential. Never stored. Protected reports
+	// whether a stored credential has one.
+	Passphrase string
+	Protected  bool
 }
 
 func (c *Credential) Validate() error {
@@ -63,6 +146,14 @@ func (c *Credential) Validate() error {
 	if c.SecretKey == nil && c.PublicKey == nil {
 		return errors.New("at least one of secret or public key is required")
 	}
+	for _, t := range c.Tags {
+		if err := ValidateTag(t); err != nil {
+			return err
+		}
+	}
+	if r := c.RotationInterval; r != nil && *r != 0 && *r < time.Second {
+		return errors.New("rotation interval must be at least a second")
+	}
 	return nil
 }
 
@@ -83,50 +174,126 @@ type RotationRecord struct {
 // RotationResult carries the output of a rotation plugin. Defined here to
 // avoid an import cycle between core and rotation packages.
 type RotationResult struct {
-	NewSecretKey *string
-	NewPublicKey *string
-	NewURL       *string
-	KeyID        string
-	OldKeyGrace  time.Duration
-	Metadata     map[string]string
+	NewSecretKey  *string
+	NewPublicKey  *string
+	NewURL        *string
+	NewURLs       []string // replaces the whole list; takes precedence over NewURL
+	KeyID         string
+	OldKeyGrace   time.Duration
+	OldKeyRevoked bool
+	RevokeOldKey  bool
+	Metadata      map[string]string
+
+	// Reason is the operator's note on why the rotation happened. It comes
+	// from the caller, not the plugin, and is logged in the record's
+	// Metadata under RotationReasonKey.
+	Reason string
+}
+
+// RotationReasonKey is the rotation record metadata key holding
+// RotationResult.Reason.
+const RotationReasonKey = "reason"
+
+// oldKeyState summarizes what happened to the previous key, for the
+// rotation log: "revoked", "revoke_now", "grace <duration>" or "unspecified".
+func (r *RotationResult) oldKeyState() string {
+	switch {
+	case r.OldKeyRevoked:
+		return "revoked"
+	case r.RevokeOldKey:
+		return "revoke_now"
+	case r.OldKeyGrace > 0:
+		return "grace " + r.OldKeyGrace.String()
+	default:
+		return "unspecified"
+	}
+}
+
+// Option configures NewDatabase.
+type Option func(*options)
+
+type options struct {
+	cipher      *CipherSettings
+	kdf         KeyDeriver
+	noBackup    bool
+	wal         bool
+	readOnly    bool
+	recordReads bool
+	memLock     bool
+	logger      *slog.Logger
+}
+
+// WithCipherSettings sets SQLCipher page-level parameters. They take effect
+// when the vault is created; opening an existing vault with different
+// values fails with ErrCipherMismatch rather than an unreadable file.
+func WithCipherSettings(cs CipherSettings) Option {
+	return func(o *options) { o.cipher = &cs }
+}
+
+// WithoutMigrationBackup skips the copy NewDatabase otherwise makes before
+// running a migration that rebuilds tables.
+func WithoutMigrationBackup() Option {
+	return func(o *options) { o.noBackup = true }
 }
 
 // NewDatabase opens (or creates) an encrypted database at path, protected
 // by password. SQLCipher encrypts the file on disk; an Argon2id-derived
 // AES key adds a second layer for individual API key fields.
-func NewDatabase(path, password string) (*Database, error) {
-	dsn := fmt.Sprintf("%s?_pragma_key=%s", path, password)
-	db, err := sql.Open("sqlite3", dsn)
+func NewDatabase(path, password string, opts ...Option) (*Database, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	log := o.log()
+
+	_, statErr := os.Stat(path)
+	exists := statErr == nil
+	if o.readOnly && !exists {
+		return nil, fmt.Errorf("open read-only: %w", statErr)
+	}
+
+	cs, err := ReadCipherSettings(path)
 	if err != nil {
-		return nil, fmt.Errorf("open db: %w", err)
+		return nil, fmt.Errorf("cipher settings: %w", err)
 	}
-	if err := db.Ping(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("ping db: %w", err)
+	if o.cipher != nil {
+		if exists && *o.cipher != cs {
+			return nil, ErrCipherMismatch
+		}
+		if err := o.cipher.validate(); err != nil {
+			return nil, err
+		}
+		cs = *o.cipher
+	}
+	if o.kdf != nil {
+		if err := validateKDF(o.kdf); err != nil {
+			return nil, err
+		}
 	}
 
-	if _, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS config (
-			key   TEXT PRIMARY KEY,
-			value BLOB NOT NULL
-		);
-		CREATE TABLE IF NOT EXISTS credentials (
-			id         TEXT PRIMARY KEY,
-			name       TEXT UNIQUE NOT NULL,
-			api_key    BLOB NOT NULL,
-			api_type   TEXT,
-			metadata   TEXT,
-			created_at INTEGER NOT NULL,
-			updated_at INTEGER NOT NULL
-		);
-	`); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("schema: %w", err)
+	db, sqlKey, err := openKeyed(path, password, cs)
+	if err != nil {
+		log.Warn("vault open failed", "path", path, "err", err)
+		return nil, err
 	}
 
-	if err := migrateV2(db); err != nil {
+	// A read-only open must leave the file exactly as it found it, so an
+	// out-of-date schema is refused rather than migrated.
+	if o.readOnly {
+		if err := checkSchema(db); err != nil {
+			db.Close()
+			return nil, err
+		}
+	} else if err := migrateSchema(db, path, o.noBackup, log); err != nil {
 		db.Close()
-		return nil, fmt.Errorf("migrate v2: %w", err)
+		return nil, err
+	}
+
+	if !exists && cs != (CipherSettings{}) {
+		if err := writeCipherSettings(path, cs); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("cipher settings: %w", err)
+		}
 	}
 
 	salt, err := loadOrCreateSalt(db)
@@ -134,29 +301,126 @@ func NewDatabase(path, password string) (*Database, error) {
 		db.Close()
 		return nil, fmt.Errorf("salt: %w", err)
 	}
+	if !exists {
+		var k KeyDeriver = DefaultArgon2Params()
+		if o.kdf != nil {
+			k = o.kdf
+		}
+		if err := storeKDF(db, k); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("kdf params: %w", err)
+		}
+	}
+	kdf, err := loadKDF(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("kdf params: %w", err)
+	}
+	sealMeta, err := loadSealMeta(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	wal, err := applyWAL(db, o.wal && !o.readOnly)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("wal: %w", err)
+	}
 
-	return &Database{
-		db:  db,
-		key: deriveKey(password, salt),
-	}, nil
+	d := &Database{
+		db:          db,
+		path:        path,
+		cipher:      cs,
+		kdf:         kdf,
+		locks:       make(map[string]string),
+		sealMeta:    sealMeta,
+		wal:         wal,
+		readOnly:    o.readOnly,
+		recordReads: o.recordReads,
+		memLock:     o.memLock,
+		clock:       time.Now,
+		log:         log,
+		maxSecret:   DefaultMaxSecretSize,
+	}
+	if err := d.setKey(kdf.Derive(password, salt)); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := d.initIntegrity(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("integrity: %w", err)
+	}
+	if err := d.openReader(sqlKey); err != nil {
+		db.Close()
+		return nil, err
+	}
+	log.Info("vault opened", "path", path, "created", !exists, "kdf", kdf.ID(), "read_only", o.readOnly)
+	return d, nil
+}
+
+// SetClock replaces the time source used for created/updated/rotated
+// timestamps, lock expiry and export dates, so tests can freeze or advance
+// time. nil restores time.Now. Call it before d is shared between
+// goroutines.
+func (d *Database) SetClock(now func() time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if now == nil {
+		now = time.Now
+	}
+	d.clock = now
+}
+
+// Now returns the current time according to d's clock.
+func (d *Database) Now() time.Time { return d.now() }
+
+func (d *Database) now() time.Time { return d.clock() }
+
+// SetMaxSecretSize changes the per-field size limit enforced on writes.
+// n <= 0 restores DefaultMaxSecretSize.
+func (d *Database) SetMaxSecretSize(n int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if n <= 0 {
+		n = DefaultMaxSecretSize
+	}
+	d.maxSecret = n
 }
 
 // AddCredential stores a new credential with an encrypted API key.
 func (d *Database) AddCredential(name, apiKey, apiType string) error {
+	return d.AddCredentialContext(context.Background(), name, apiKey, apiType)
+}
+
+// AddCredentialContext is AddCredential bound to ctx.
+func (d *Database) AddCredentialContext(ctx context.Context, name, apiKey, apiType string) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	if err := d.use(); err != nil {
+		return err
+	}
+
+	if err := d.checkSize(apiKey); err != nil {
+		return err
+	}
+
 	blob, err := d.encrypt([]byte(apiKey))
 	if err != nil {
 		return err
 	}
 
-	now := time.Now().Unix()
-	_, err = d.db.Exec(
-		`INSERT INTO credentials (id, name, api_key, api_type, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?)`,
-		newID(), name, blob, apiType, now, now,
-	)
+	now := d.now().Unix()
+	err = d.writeTxContext(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO credentials (id, name, api_key, api_type, created_at, updated_at)
+			 VALUES (?, ?, ?, ?, ?, ?)`,
+			newID(), name, blob, apiType, now, now,
+		)
+		return err
+	})
 	if err != nil && isUniqueViolation(err) {
 		return ErrDuplicate
 	}
@@ -165,91 +429,353 @@ func (d *Database) AddCredential(name, apiKey, apiType string) error {
 
 // GetCredential returns the decrypted API key for the given name.
 func (d *Database) GetCredential(name string) (string, error) {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
+	return d.GetCredentialContext(context.Background(), name)
+}
 
-	var blob []byte
-	err := d.db.QueryRow(
-		`SELECT api_key FROM credentials WHERE name = ?`, name,
-	).Scan(&blob)
-	if errors.Is(err, sql.ErrNoRows) {
-		return "", ErrNotFound
-	}
-	if err != nil {
-		return "", err
-	}
+// GetCredentialContext is GetCredential bound to ctx.
+func (d *Database) GetCredentialContext(ctx context.Context, name string) (string, error) {
+	var secret string
+	err := d.read(ctx, func(rs *readState) error {
+		var blob, extraSalt []byte
+		var disabled bool
+		err := rs.db.QueryRowContext(ctx,
+			`SELECT api_key, disabled, extra_salt FROM credentials WHERE name = ?`, name,
+		).Scan(&blob, &disabled, &extraSalt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if disabled {
+			return ErrDisabled
+		}
+		if extraSalt != nil {
+			return ErrPassphraseRequired
+		}
 
-	plain, err := d.decrypt(blob)
+		plain, err := decryptWith(rs.aead, blob)
+		if err != nil {
+			return err
+		}
+		secret = string(plain)
+		zero(plain)
+		return nil
+	})
 	if err != nil {
 		return "", err
 	}
-	return string(plain), nil
+	d.recordAccess(ctx, name, AccessGet)
+	return secret, nil
 }
 
 // ListCredentials returns metadata for every stored credential.
 // No secrets are included.
 func (d *Database) ListCredentials() ([]Credential, error) {
+	return d.ListCredentialsContext(context.Background())
+}
+
+// ListCredentialsContext is ListCredentials bound to ctx.
+func (d *Database) ListCredentialsContext(ctx context.Context) ([]Credential, error) {
+	var creds []Credential
+	err := d.read(ctx, func(rs *readState) error {
+		creds = nil
+		return eachWhere(ctx, rs.db, "", func(c Credential) error {
+			creds = append(creds, c)
+			return nil
+		})
+	})
+	return creds, err
+}
+
+// CredentialsByType returns metadata for every credential of the given
+// api_type. No secrets are included.
+func (d *Database) CredentialsByType(apiType string) ([]Credential, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	return d.listWhere(context.Background(), "WHERE api_type = ?", apiType)
+}
+
+// NeverRotated returns metadata for the credentials that have never been
+// rotated (NULL last_rotated), optionally limited to one api_type ("" for
+// all). No secrets are decrypted.
+func (d *Database) NeverRotated(apiType string) ([]Credential, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	if apiType == "" {
+		return d.listWhere(context.Background(), "WHERE last_rotated IS NULL")
+	}
+	return d.listWhere(context.Background(), "WHERE last_rotated IS NULL AND api_type = ?", apiType)
+}
+
+// EnvironmentCount is one distinct environment and how many credentials use it.
+type EnvironmentCount struct {
+	Environment string
+	Count       int
+}
+
+// Environments returns the distinct non-null environments in the vault with
+// their credential counts, sorted by name. Nothing is decrypted.
+func (d *Database) Environments() ([]EnvironmentCount, error) {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
 	rows, err := d.db.Query(
-		`SELECT id, name, api_type, metadata, created_at, updated_at
-		 FROM credentials ORDER BY name`,
+		`SELECT environment, COUNT(*) FROM credentials
+		 WHERE environment IS NOT NULL GROUP BY environment ORDER BY environment`,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	var envs []EnvironmentCount
+	for rows.Next() {
+		var e EnvironmentCount
+		if err := rows.Scan(&e.Environment, &e.Count); err != nil {
+			return nil, err
+		}
+		envs = append(envs, e)
+	}
+	return envs, rows.Err()
+}
+
+// EachCredential calls fn with the metadata of every credential in name
+// order, one row at a time, so large vaults can be processed without loading
+// them into memory. No secrets are included. The read lock is held until
+// iteration ends, so fn must not call methods that write to d. Returning
+// ErrStop from fn ends iteration without error; any other error is returned
+// as-is.
+func (d *Database) EachCredential(fn func(Credential) error) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return err
+	}
+
+	err := eachWhere(context.Background(), d.reader(), "", fn)
+	if errors.Is(err, ErrStop) {
+		return nil
+	}
+	return err
+}
+
+// listWhere runs the metadata-only list query with an optional filter.
+// Callers must hold d.mu.
+func (d *Database) listWhere(ctx context.Context, where string, args ...any) ([]Credential, error) {
 	var creds []Credential
+	err := eachWhere(ctx, d.reader(), where, func(c Credential) error {
+		creds = append(creds, c)
+		return nil
+	}, args...)
+	return creds, err
+}
+
+// eachWhere streams the metadata-only list query on db into fn, stopping
+// at the first error fn returns.
+func eachWhere(ctx context.Context, db *sql.DB, where string, fn func(Credential) error, args ...any) error {
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, name, api_type, metadata, environment, config, last_rotated, expires_at, rotation_interval, created_at, updated_at, disabled, extra_salt IS NOT NULL, `+tagsColumn+`, `+lastAccessedColumn+`
+		 FROM credentials `+where+` ORDER BY name`, args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
 	for rows.Next() {
 		var c Credential
-		var apiType, meta sql.NullString
+		var apiType, meta, env, cfgJSON, tags sql.NullString
+		var lastRotated, expires, interval, accessed sql.NullInt64
 		var created, updated int64
-		if err := rows.Scan(&c.ID, &c.Name, &apiType, &meta, &created, &updated); err != nil {
-			return nil, err
+		if err := rows.Scan(&c.ID, &c.Name, &apiType, &meta, &env, &cfgJSON, &lastRotated, &expires, &interval, &created, &updated, &c.Disabled, &c.Protected, &tags, &accessed); err != nil {
+			return err
+		}
+		c.Tags = splitTags(tags.String)
+		if env.Valid {
+			c.Environment = &env.String
+		}
+		if lastRotated.Valid {
+			t := time.Unix(lastRotated.Int64, 0)
+			c.LastRotated = &t
 		}
+		c.ExpiresAt = unixPtr(expires)
+		c.RotationInterval = intervalPtr(interval)
+		c.LastAccessed = unixPtr(accessed)
 		c.APIType = apiType.String
 		c.Metadata = meta.String
+		if cfgJSON.Valid {
+			c.Config = make(map[string]string)
+			json.Unmarshal([]byte(cfgJSON.String), &c.Config)
+		}
 		c.CreatedAt = time.Unix(created, 0)
 		c.UpdatedAt = time.Unix(updated, 0)
-		creds = append(creds, c)
+		if err := fn(c); err != nil {
+			return err
+		}
 	}
-	return creds, rows.Err()
+	return rows.Err()
 }
 
-// DeleteCredential removes a credential by name.
-func (d *Database) DeleteCredential(name string) error {
+// SetDisabled takes a credential out of service (or puts it back) without
+// deleting it.
+func (d *Database) SetDisabled(name string, disabled bool) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	res, err := d.db.Exec(`DELETE FROM credentials WHERE name = ?`, name)
+	if err := d.use(); err != nil {
+		return err
+	}
+
+	return d.writeTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec(`UPDATE credentials SET disabled = ?, updated_at = ? WHERE name = ?`,
+			disabled, d.now().Unix(), name)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// RemapAPIType changes the api_type of every credential typed from to to,
+// in one transaction, and returns how many were updated. Matching is exact.
+func (d *Database) RemapAPIType(from, to string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err := d.writeTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec(`UPDATE credentials SET api_type = ?, updated_at = ? WHERE api_type = ?`,
+			to, d.now().Unix(), from)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
 	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// DeleteCredential removes a credential by name, along with its rotation
+// history.
+func (d *Database) DeleteCredential(name string) error {
+	return d.DeleteCredentialContext(context.Background(), name)
+}
+
+// DeleteCredentialContext is DeleteCredential bound to ctx.
+func (d *Database) DeleteCredentialContext(ctx context.Context, name string) error {
+	_, err := d.DeleteCredentialWithStatsContext(ctx, name)
+	return err
+}
+
+// DeleteStats counts the records removed along with a credential.
+type DeleteStats struct {
+	Rotations int
+}
+
+// DeleteCredentialWithStats is DeleteCredential that also reports how many
+// associated records went with it. Foreign keys aren't enforced on the
+// connection, so the rotations "cascade" is done explicitly here.
+func (d *Database) DeleteCredentialWithStats(name string) (DeleteStats, error) {
+	return d.DeleteCredentialWithStatsContext(context.Background(), name)
+}
+
+// DeleteCredentialWithStatsContext is DeleteCredentialWithStats bound to ctx.
+func (d *Database) DeleteCredentialWithStatsContext(ctx context.Context, name string) (DeleteStats, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return DeleteStats{}, err
+	}
+
+	var stats DeleteStats
+	err := d.writeTxContext(ctx, func(tx *sql.Tx) error {
+		var err error
+		stats, err = deleteCredential(ctx, tx, name)
 		return err
+	})
+	if err != nil {
+		return DeleteStats{}, err
+	}
+	return stats, nil
+}
+
+// deleteCredential removes name and everything keyed by it in tx,
+// returning ErrNotFound if there is no such credential.
+func deleteCredential(ctx context.Context, tx *sql.Tx, name string) (DeleteStats, error) {
+	res, err := tx.ExecContext(ctx, `DELETE FROM credentials WHERE name = ?`, name)
+	if err != nil {
+		return DeleteStats{}, err
 	}
 	n, err := res.RowsAffected()
 	if err != nil {
-		return err
+		return DeleteStats{}, err
 	}
 	if n == 0 {
-		return ErrNotFound
+		return DeleteStats{}, ErrNotFound
 	}
-	return nil
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM credential_tags WHERE credential_name = ?`, name); err != nil {
+		return DeleteStats{}, err
+	}
+	if err := deleteAccessLog(ctx, tx, name); err != nil {
+		return DeleteStats{}, err
+	}
+	res, err = tx.ExecContext(ctx, `DELETE FROM rotations WHERE credential_name = ?`, name)
+	if err != nil {
+		return DeleteStats{}, err
+	}
+	n, err = res.RowsAffected()
+	return DeleteStats{Rotations: int(n)}, err
 }
 
 // Close zeros the in-memory key and closes the database.
 func (d *Database) Close() error {
+	d.stopAutoLock()
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	for i := range d.key {
-		d.key[i] = 0
-	}
+	d.wipeKey()
+	d.closeReader()
 	return d.db.Close()
 }
 
 // AddCredentialV2 stores a credential using the full V2 model.
 func (d *Database) AddCredentialV2(cred *Credential) error {
+	return d.AddCredentialV2Context(context.Background(), cred)
+}
+
+// AddCredentialV2Context is AddCredentialV2 bound to ctx.
+func (d *Database) AddCredentialV2Context(ctx context.Context, cred *Credential) error {
 	if err := cred.Validate(); err != nil {
 		return err
 	}
@@ -257,67 +783,416 @@ func (d *Database) AddCredentialV2(cred *Credential) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	if err := d.use(); err != nil {
+		return err
+	}
+
+	now := d.now().Unix()
+	return d.writeTxContext(ctx, func(tx *sql.Tx) error {
+		return d.insertCredential(tx, cred, now, now)
+	})
+}
+
+// AddCredentialV2WithTimestamps is AddCredentialV2 that keeps the caller's
+// cred.CreatedAt and cred.UpdatedAt, for imports that carry history from
+// another system. Zero timestamps default to now, and updated_at is never
+// earlier than created_at. Timestamps in the future are rejected.
+func (d *Database) AddCredentialV2WithTimestamps(cred *Credential) error {
+	if err := cred.Validate(); err != nil {
+		return err
+	}
+
+	now := d.now()
+	created, updated := now, now
+	if !cred.CreatedAt.IsZero() {
+		created = cred.CreatedAt
+	}
+	if !cred.UpdatedAt.IsZero() {
+		updated = cred.UpdatedAt
+	}
+	if created.After(now) || updated.After(now) {
+		return fmt.Errorf("credential %q: timestamps in the future", cred.Name)
+	}
+	if updated.Before(created) {
+		updated = created
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return err
+	}
+
+	return d.writeTx(func(tx *sql.Tx) error {
+		return d.insertCredential(tx, cred, created.Unix(), updated.Unix())
+	})
+}
+
+// AddOrUpdateCredentialV2 is an idempotent AddCredentialV2: it inserts cred
+// if the name is free, otherwise replaces the stored fields in place,
+// keeping created_at, last_rotated, the disabled flag and rotation history.
+// A nil ExpiresAt or RotationInterval keeps the stored expiry or schedule;
+// point them at the zero time or 0 to remove it. created reports which
+// happened.
+func (d *Database) AddOrUpdateCredentialV2(cred *Credential) (created bool, err error) {
+	if err := cred.Validate(); err != nil {
+		return false, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return false, err
+	}
+
+	now := d.now().Unix()
+	err = d.writeTx(func(tx *sql.Tx) error {
+		err := d.updateCredential(tx, cred, now)
+		if errors.Is(err, ErrNotFound) {
+			created = true
+			return d.insertCredential(tx, cred, now, now)
+		}
+		return err
+	})
+	return created, err
+}
+
+// credentialRow is a Credential encoded for the credentials table.
+type credentialRow struct {
+	secret, public, extraSalt []byte
+	url, urls, config, meta   *string
+	expires, interval         *int64
+}
+
+// insertCredential encrypts and inserts cred with the given timestamps.
+// Callers must hold d.mu and have validated cred.
+func (d *Database) insertCredential(tx *sql.Tx, cred *Credential, created, updated int64) error {
+	r, err := d.encodeCredential(cred)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		`INSERT INTO credentials (id, name, api_key, api_type, metadata, environment, public_key, url, config, key_id, created_at, updated_at, extra_salt, urls, expires_at, rotation_interval)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		newID(), cred.Name, r.secret, cred.APIType, r.meta, cred.Environment, r.public, r.url, r.config, cred.KeyID, created, updated, r.extraSalt, r.urls, r.expires, r.interval,
+	)
+	if err != nil && isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	return storeTags(tx, cred.Name, cred.Tags)
+}
+
+// updateCredential overwrites the stored fields of cred.Name, returning
+// ErrNotFound if there is no such credential. Tags are replaced only when
+// cred.Tags is non-nil, the expiry only when cred.ExpiresAt is (the zero
+// time removes it) and the schedule only when cred.RotationInterval is (0
+// removes it). Callers must hold d.mu and have validated cred.
+func (d *Database) updateCredential(tx *sql.Tx, cred *Credential, updated int64) error {
+	r, err := d.encodeCredential(cred)
+	if err != nil {
+		return err
+	}
+	set := `api_key = ?, api_type = ?, metadata = ?, environment = ?, public_key = ?, url = ?, config = ?, key_id = ?, updated_at = ?, extra_salt = ?, urls = ?`
+	args := []any{r.secret, cred.APIType, r.meta, cred.Environment, r.public, r.url, r.config, cred.KeyID, updated, r.extraSalt, r.urls}
+	if cred.ExpiresAt != nil {
+		set += `, expires_at = ?`
+		args = append(args, r.expires)
+	}
+	if cred.RotationInterval != nil {
+		set += `, rotation_interval = ?`
+		args = append(args, r.interval)
+	}
+	res, err := tx.Exec(`UPDATE credentials SET `+set+` WHERE name = ?`, append(args, cred.Name)...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	if cred.Tags != nil {
+		return storeTags(tx, cred.Name, cred.Tags)
+	}
+	return nil
+}
+
+// encodeCredential checks sizes and encrypts cred's fields for storage.
+func (d *Database) encodeCredential(cred *Credential) (credentialRow, error) {
 	secretBlob := []byte{} // empty blob satisfies NOT NULL when no secret
+	var extraSalt []byte
 	if cred.HasSecret() {
+		if err := d.checkSize(*cred.SecretKey); err != nil {
+			return credentialRow{}, err
+		}
+		plain := []byte(*cred.SecretKey)
+		if cred.Passphrase != "" {
+			extraSalt = make([]byte, saltLen)
+			if _, err := rand.Read(extraSalt); err != nil {
+				return credentialRow{}, err
+			}
+			var err error
+			if plain, err = sealGCM(passphraseKey(cred.Passphrase, extraSalt), plain); err != nil {
+				return credentialRow{}, err
+			}
+		}
 		var err error
-		secretBlob, err = d.encrypt([]byte(*cred.SecretKey))
+		secretBlob, err = d.encrypt(plain)
 		if err != nil {
-			return err
+			return credentialRow{}, err
 		}
 	}
 
 	var publicBlob []byte
 	if cred.HasPublic() {
+		if err := d.checkSize(*cred.PublicKey); err != nil {
+			return credentialRow{}, err
+		}
 		var err error
 		publicBlob, err = d.encrypt([]byte(*cred.PublicKey))
 		if err != nil {
+			return credentialRow{}, err
+		}
+	}
+
+	cfg := cred.Config
+	if _, set := cfg[SecretKindConfigKey]; !set && cred.HasSecret() {
+		cfg = make(map[string]string, len(cred.Config)+1)
+		for k, v := range cred.Config {
+			cfg[k] = v
+		}
+		cfg[SecretKindConfigKey] = string(DetectSecretType(*cred.SecretKey))
+	}
+
+	var cfgJSON *string
+	if len(cfg) > 0 {
+		b, _ := json.Marshal(cfg)
+		s := string(b)
+		cfgJSON = &s
+	}
+
+	var meta *string
+	if cred.Metadata != "" {
+		meta = &cred.Metadata
+	}
+
+	url, urlsJSON := cred.URL, (*string)(nil)
+	if len(cred.URLs) > 0 {
+		url = &cred.URLs[0]
+		urlsJSON = marshalURLs(cred.URLs)
+	}
+
+	var expires *int64
+	if cred.ExpiresAt != nil && !cred.ExpiresAt.IsZero() {
+		t := cred.ExpiresAt.Unix()
+		expires = &t
+	}
+	var interval *int64
+	if cred.RotationInterval != nil && *cred.RotationInterval != 0 {
+		interval = intervalSeconds(cred.RotationInterval)
+	}
+
+	return credentialRow{
+		secret: secretBlob, public: publicBlob, extraSalt: extraSalt,
+		url: url, urls: urlsJSON, config: cfgJSON, meta: meta, expires: expires,
+		interval: interval,
+	}, nil
+}
+
+// GetCredentialV2 returns the full credential struct with decrypted keys.
+func (d *Database) GetCredentialV2(name string) (*Credential, error) {
+	return d.GetCredentialV2Context(context.Background(), name)
+}
+
+// GetCredentialV2Context is GetCredentialV2 bound to ctx.
+func (d *Database) GetCredentialV2Context(ctx context.Context, name string) (*Credential, error) {
+	var c *Credential
+	err := d.read(ctx, func(rs *readState) error {
+		var err error
+		if c, err = getCredentialFrom(ctx, rs, name, ""); err != nil {
 			return err
 		}
+		if c.Disabled {
+			return ErrDisabled
+		}
+		if c.Protected {
+			return ErrPassphraseRequired
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.recordAccess(ctx, name, AccessGetV2)
+	return c, nil
+}
+
+// GetCredentialByKeyID finds the credential whose current provider key ID is
+// keyID, e.g. to act on a provider alert. Disabled credentials are included;
+// a protected secret is left nil. More than one match is an error.
+func (d *Database) GetCredentialByKeyID(keyID string) (*Credential, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.Query(`SELECT name FROM credentials WHERE key_id = ? LIMIT 2`, keyID)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for rows.Next() {
+		var n string
+		if err := rows.Scan(&n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	switch len(names) {
+	case 0:
+		return nil, ErrNotFound
+	case 1:
+		return d.getCredentialV2(context.Background(), names[0], "")
+	}
+	return nil, fmt.Errorf("key id %q matches more than one credential (%s, ...)", keyID, strings.Join(names, ", "))
+}
+
+// InspectCredential is GetCredentialV2 for tooling that must see every
+// credential: disabled ones are returned as-is and a protected secret is
+// left nil instead of failing.
+func (d *Database) InspectCredential(name string) (*Credential, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	return d.getCredentialV2(context.Background(), name, "")
+}
+
+// GetMetadata returns everything about a credential except its keys:
+// SecretKey and PublicKey are always nil and nothing is decrypted, so it is
+// cheap and safe for detail views. HasSecret and HasPublic report false
+// for the result. Disabled and protected credentials are returned as-is.
+func (d *Database) GetMetadata(name string) (*Credential, error) {
+	return d.GetMetadataContext(context.Background(), name)
+}
+
+// GetMetadataContext is GetMetadata bound to ctx.
+func (d *Database) GetMetadataContext(ctx context.Context, name string) (*Credential, error) {
+	var c *Credential
+	err := d.read(ctx, func(rs *readState) error {
+		var err error
+		c, _, err = loadCredential(ctx, rs.db, name)
+		return err
+	})
+	return c, err
+}
+
+// UnlockCredential is GetCredentialV2 for a credential added with a
+// Passphrase. It also works for unprotected credentials, ignoring
+// passphrase.
+func (d *Database) UnlockCredential(name, passphrase string) (*Credential, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	c, err := d.getCredentialV2(context.Background(), name, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if c.Disabled {
+		return nil, ErrDisabled
+	}
+	d.logAccess(context.Background(), name, AccessUnlock)
+	return c, nil
+}
+
+// getCredentialV2 loads and decrypts a credential regardless of whether it
+// is disabled. A protected secret is unwrapped with passphrase, or left nil
+// when passphrase is empty. Callers must hold d.mu.
+func (d *Database) getCredentialV2(ctx context.Context, name, passphrase string) (*Credential, error) {
+	return getCredentialFrom(ctx, d.state(), name, passphrase)
+}
+
+// getCredentialFrom is getCredentialV2 reading through rs.
+func getCredentialFrom(ctx context.Context, rs *readState, name, passphrase string) (*Credential, error) {
+	c, sealed, err := loadCredential(ctx, rs.db, name)
+	if err != nil {
+		return nil, err
 	}
 
-	var cfgJSON *string
-	if len(cred.Config) > 0 {
-		b, _ := json.Marshal(cred.Config)
-		s := string(b)
-		cfgJSON = &s
+	if len(sealed.secret) > 0 && (!c.Protected || passphrase != "") {
+		plain, err := decryptWith(rs.aead, sealed.secret)
+		if err != nil {
+			return nil, err
+		}
+		if c.Protected {
+			if plain, err = openGCM(passphraseKey(passphrase, sealed.extraSalt), plain); err != nil {
+				return nil, ErrWrongPassphrase
+			}
+		}
+		s := string(plain)
+		zero(plain)
+		c.SecretKey = &s
 	}
-
-	now := time.Now().Unix()
-	_, err := d.db.Exec(
-		`INSERT INTO credentials (id, name, api_key, api_type, environment, public_key, url, config, key_id, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		newID(), cred.Name, secretBlob, cred.APIType, cred.Environment, publicBlob, cred.URL, cfgJSON, cred.KeyID, now, now,
-	)
-	if err != nil && isUniqueViolation(err) {
-		return ErrDuplicate
+	if len(sealed.public) > 0 {
+		plain, err := decryptWith(rs.aead, sealed.public)
+		if err != nil {
+			return nil, err
+		}
+		s := string(plain)
+		zero(plain)
+		c.PublicKey = &s
 	}
-	return err
+	return c, nil
 }
 
-// GetCredentialV2 returns the full credential struct with decrypted keys.
-func (d *Database) GetCredentialV2(name string) (*Credential, error) {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
+// sealedKeys are a credential's encrypted key columns as stored.
+type sealedKeys struct {
+	secret, public, extraSalt []byte
+}
 
+// loadCredential reads a credential's row from db without decrypting
+// anything; the keys come back sealed.
+func loadCredential(ctx context.Context, db *sql.DB, name string) (*Credential, *sealedKeys, error) {
 	var c Credential
-	var apiType, meta, env, url, cfgJSON, keyID sql.NullString
-	var secretBlob, publicBlob []byte
+	var apiType, meta, env, url, urls, cfgJSON, keyID, tags sql.NullString
+	var secretBlob, publicBlob, extraSalt []byte
 	var created, updated int64
-	var lastRotated sql.NullInt64
+	var lastRotated, expires, interval, accessed sql.NullInt64
 
-	err := d.db.QueryRow(
-		`SELECT id, name, api_key, api_type, metadata, environment, public_key, url, config, key_id, last_rotated, created_at, updated_at
+	err := db.QueryRowContext(ctx,
+		`SELECT id, name, api_key, api_type, metadata, environment, public_key, url, urls, config, key_id, last_rotated, expires_at, rotation_interval, created_at, updated_at, disabled, extra_salt, `+tagsColumn+`, `+lastAccessedColumn+`
 		 FROM credentials WHERE name = ?`, name,
-	).Scan(&c.ID, &c.Name, &secretBlob, &apiType, &meta, &env, &publicBlob, &url, &cfgJSON, &keyID, &lastRotated, &created, &updated)
+	).Scan(&c.ID, &c.Name, &secretBlob, &apiType, &meta, &env, &publicBlob, &url, &urls, &cfgJSON, &keyID, &lastRotated, &expires, &interval, &created, &updated, &c.Disabled, &extraSalt, &tags, &accessed)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, ErrNotFound
+		return nil, nil, ErrNotFound
 	}
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	c.APIType = apiType.String
 	c.Metadata = meta.String
+	c.Tags = splitTags(tags.String)
 	c.CreatedAt = time.Unix(created, 0)
 	c.UpdatedAt = time.Unix(updated, 0)
 
@@ -326,6 +1201,10 @@ func (d *Database) GetCredentialV2(name string) (*Credential, error) {
 	}
 	if url.Valid {
 		c.URL = &url.String
+		c.URLs = []string{url.String}
+	}
+	if urls.Valid {
+		json.Unmarshal([]byte(urls.String), &c.URLs)
 	}
 	if keyID.Valid {
 		c.KeyID = &keyID.String
@@ -334,30 +1213,16 @@ func (d *Database) GetCredentialV2(name string) (*Credential, error) {
 		t := time.Unix(lastRotated.Int64, 0)
 		c.LastRotated = &t
 	}
+	c.ExpiresAt = unixPtr(expires)
+	c.RotationInterval = intervalPtr(interval)
+	c.LastAccessed = unixPtr(accessed)
 
-	if len(secretBlob) > 0 {
-		plain, err := d.decrypt(secretBlob)
-		if err != nil {
-			return nil, err
-		}
-		s := string(plain)
-		c.SecretKey = &s
-	}
-	if len(publicBlob) > 0 {
-		plain, err := d.decrypt(publicBlob)
-		if err != nil {
-			return nil, err
-		}
-		s := string(plain)
-		c.PublicKey = &s
-	}
-
+	c.Protected = extraSalt != nil
 	if cfgJSON.Valid {
 		c.Config = make(map[string]string)
 		json.Unmarshal([]byte(cfgJSON.String), &c.Config)
 	}
-
-	return &c, nil
+	return &c, &sealedKeys{secret: secretBlob, public: publicBlob, extraSalt: extraSalt}, nil
 }
 
 // RotateCredential atomically updates keys and logs the rotation.
@@ -365,6 +1230,13 @@ func (d *Database) RotateCredential(name string, result *RotationResult, pluginN
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	if err := d.use(); err != nil {
+		return err
+	}
+
+	if d.readOnly {
+		return ErrReadOnly
+	}
 	ctx := context.Background()
 	tx, err := d.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -372,8 +1244,21 @@ func (d *Database) RotateCredential(name string, result *RotationResult, pluginN
 	}
 	defer tx.Rollback()
 
+	if err := d.checkRotationLock(tx, name); err != nil {
+		return err
+	}
+
+	if result.NewSecretKey != nil {
+		// A plain write would silently strip a per-credential passphrase.
+		var protected bool
+		err := tx.QueryRow(`SELECT extra_salt IS NOT NULL FROM credentials WHERE name = ?`, name).Scan(&protected)
+		if err == nil && protected {
+			return ErrPassphraseRequired
+		}
+	}
+
 	// Update credential fields
-	now := time.Now().Unix()
+	now := d.now().Unix()
 	var fields []string
 
 	if result.NewSecretKey != nil {
@@ -398,10 +1283,28 @@ func (d *Database) RotateCredential(name string, result *RotationResult, pluginN
 		fields = append(fields, "public_key")
 	}
 
-	if result.NewURL != nil {
+	if len(result.NewURLs) > 0 {
+		if _, err := tx.Exec(`UPDATE credentials SET url = ?, urls = ?, updated_at = ? WHERE name = ?`,
+			result.NewURLs[0], marshalURLs(result.NewURLs), now, name); err != nil {
+			return err
+		}
+		fields = append(fields, "url")
+	} else if result.NewURL != nil {
+		// Replace the primary endpoint, keeping any others.
 		if _, err := tx.Exec(`UPDATE credentials SET url = ?, updated_at = ? WHERE name = ?`, *result.NewURL, now, name); err != nil {
 			return err
 		}
+		var urls sql.NullString
+		if err := tx.QueryRow(`SELECT urls FROM credentials WHERE name = ?`, name).Scan(&urls); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		var list []string
+		if urls.Valid && json.Unmarshal([]byte(urls.String), &list) == nil && len(list) > 0 {
+			list[0] = *result.NewURL
+			if _, err := tx.Exec(`UPDATE credentials SET urls = ? WHERE name = ?`, marshalURLs(list), name); err != nil {
+				return err
+			}
+		}
 		fields = append(fields, "url")
 	}
 
@@ -417,11 +1320,16 @@ func (d *Database) RotateCredential(name string, result *RotationResult, pluginN
 
 	// Log rotation
 	fieldsJSON, _ := json.Marshal(fields)
-	var metaJSON *string
-	if len(result.Metadata) > 0 {
-		b, _ := json.Marshal(result.Metadata)
-		s := string(b)
-		metaJSON = &s
+	meta := map[string]string{"old_key": result.oldKeyState()}
+	for k, v := range result.Metadata {
+		meta[k] = v
+	}
+	if result.Reason != "" {
+		meta[RotationReasonKey] = result.Reason
+	}
+	metaJSON, err := d.encodeRotationMeta(meta)
+	if err != nil {
+		return err
 	}
 
 	if _, err := tx.Exec(
@@ -432,7 +1340,15 @@ func (d *Database) RotateCredential(name string, result *RotationResult, pluginN
 		return err
 	}
 
-	return tx.Commit()
+	if err := d.updateIntegrity(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	d.log.Info("credential rotated", "name", name, "plugin", pluginName, "rotated_by", rotatedBy,
+		"fields", fields, "old_key", result.oldKeyState())
+	return nil
 }
 
 // GetRotationHistory returns the most recent rotation records for a credential.
@@ -440,6 +1356,16 @@ func (d *Database) GetRotationHistory(name string, limit int) ([]RotationRecord,
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	return d.getRotationHistory(name, limit)
+}
+
+// getRotationHistory is GetRotationHistory without locking; a negative
+// limit returns every record. Callers must hold d.mu.
+func (d *Database) getRotationHistory(name string, limit int) ([]RotationRecord, error) {
 	rows, err := d.db.Query(
 		`SELECT id, rotated_fields, new_key_id, plugin_name, rotated_at, rotated_by, metadata
 		 FROM rotations WHERE credential_name = ? ORDER BY rotated_at DESC LIMIT ?`,
@@ -465,8 +1391,9 @@ func (d *Database) GetRotationHistory(name string, limit int) ([]RotationRecord,
 		r.NewKeyID = newKeyID.String
 		r.RotatedAt = time.Unix(rotatedAt, 0)
 		if metaJSON.Valid {
-			r.Metadata = make(map[string]string)
-			json.Unmarshal([]byte(metaJSON.String), &r.Metadata)
+			if r.Metadata, err = d.decodeRotationMeta(metaJSON.String); err != nil {
+				return nil, fmt.Errorf("rotation %s metadata: %w", r.ID, err)
+			}
 		}
 		records = append(records, r)
 	}
@@ -479,8 +1406,63 @@ func deriveKey(password string, salt []byte) []byte {
 	return argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
 }
 
+func (d *Database) checkSize(value string) error {
+	if len(value) > d.maxSecret {
+		return fmt.Errorf("%w: %d bytes (limit %d)", ErrTooLarge, len(value), d.maxSecret)
+	}
+	return nil
+}
+
+// setKey installs the field key and everything derived from it. Every key
+// change goes through here so the cached AEAD can't go stale.
+func (d *Database) setKey(key []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	d.key, d.macKey, d.aead, d.memLocked = key, deriveMACKey(key), gcm, false
+	if d.memLock {
+		d.key, d.macKey, d.memLocked = lockKeys(d.key, d.macKey)
+	}
+	return nil
+}
+
 func (d *Database) encrypt(plaintext []byte) ([]byte, error) {
-	block, err := aes.NewCipher(d.key)
+	nonce := make([]byte, nonceLen)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return d.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (d *Database) decrypt(data []byte) ([]byte, error) {
+	return decryptWith(d.aead, data)
+}
+
+// decryptWith opens a nonce || ciphertext field blob with aead.
+func decryptWith(aead cipher.AEAD, data []byte) ([]byte, error) {
+	if len(data) < nonceLen {
+		return nil, ErrDecryptFail
+	}
+	plain, err := aead.Open(nil, data[:nonceLen], data[nonceLen:], nil)
+	if err != nil {
+		return nil, ErrDecryptFail
+	}
+	return plain, nil
+}
+
+// passphraseKey derives the extra key for a passphrase-protected secret.
+func passphraseKey(passphrase string, salt []byte) []byte {
+	return deriveKey(passphrase, salt)
+}
+
+// sealGCM encrypts with AES-256-GCM, returning nonce || ciphertext.
+func sealGCM(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
@@ -495,11 +1477,12 @@ func (d *Database) encrypt(plaintext []byte) ([]byte, error) {
 	return gcm.Seal(nonce, nonce, plaintext, nil), nil
 }
 
-func (d *Database) decrypt(data []byte) ([]byte, error) {
+// openGCM reverses sealGCM. Any failure is ErrDecryptFail.
+func openGCM(key, data []byte) ([]byte, error) {
 	if len(data) < nonceLen {
 		return nil, ErrDecryptFail
 	}
-	block, err := aes.NewCipher(d.key)
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, ErrDecryptFail
 	}
@@ -539,35 +1522,191 @@ func isUniqueViolation(err error) bool {
 	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
 }
 
-func migrateV2(db *sql.DB) error {
-	// Idempotent: check if public_key column already exists
-	var hasColumn bool
-	rows, err := db.Query(`PRAGMA table_info(credentials)`)
+// openKeyed opens and pings the primary pool. sqlKey is what SQLCipher is
+// keyed with: the password, or for vaults keyed before the DSN was escaped,
+// what the driver made of it.
+func openKeyed(path, password string, cs CipherSettings) (db *sql.DB, sqlKey string, err error) {
+	sqlKey = password
+	db = openDB(path, sqlKey, cs)
+	err = db.Ping()
+	if isNotADatabase(err) {
+		if legacy, ok := legacyDSNKey(password); ok {
+			db.Close()
+			db = openDB(path, legacy, cs)
+			sqlKey = legacy
+			err = db.Ping()
+		}
+	}
+	if err != nil {
+		db.Close()
+		if isNotADatabase(err) {
+			return nil, "", ErrWrongPassword
+		}
+		return nil, "", fmt.Errorf("ping db: %w", err)
+	}
+	return db, sqlKey, nil
+}
+
+func openDB(path, password string, cs CipherSettings) *sql.DB {
+	return sql.OpenDB(newConnector(fmt.Sprintf("%s?_pragma_key=%s", path, dsnKey(password)), cs))
+}
+
+func isNotADatabase(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "file is not a database")
+}
+
+// migrateSchema creates any missing tables and brings an older vault's
+// schema up to date. checkSchema must agree with it on what counts as
+// current.
+func migrateSchema(db *sql.DB, path string, noBackup bool, log *slog.Logger) error {
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS config (
+			key   TEXT PRIMARY KEY,
+			value BLOB NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS credentials (
+			id         TEXT PRIMARY KEY,
+			name       TEXT UNIQUE NOT NULL,
+			api_key    BLOB NOT NULL,
+			api_type   TEXT,
+			metadata   TEXT,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS locks (
+			name       TEXT PRIMARY KEY,
+			owner      TEXT NOT NULL,
+			expires_at INTEGER NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS credential_tags (
+			credential_name TEXT NOT NULL,
+			tag             TEXT NOT NULL,
+			tag_key         TEXT NOT NULL,
+			PRIMARY KEY (credential_name, tag_key)
+		);
+		CREATE INDEX IF NOT EXISTS idx_credential_tags_key ON credential_tags(tag_key);
+		CREATE TABLE IF NOT EXISTS access_log (
+			credential_name TEXT NOT NULL,
+			accessed_at     INTEGER NOT NULL,
+			operation       TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_access_log_name ON access_log(credential_name, accessed_at);
+	`); err != nil {
+		return fmt.Errorf("schema: %w", err)
+	}
+
+	if err := migrateV2(db, log); err != nil {
+		return fmt.Errorf("migrate v2: %w", err)
+	}
+
+	// Migrations that rebuild tables run against a backup, which is removed
+	// once they commit and kept for recovery if they fail.
+	backup, err := backupBeforeRewrite(db, path, noBackup)
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
-	for rows.Next() {
-		var cid int
-		var name, typ string
-		var notnull int
-		var dflt sql.NullString
-		var pk int
-		if err := rows.Scan(&cid, &name, &typ, &notnull, &dflt, &pk); err != nil {
-			return err
+	if err := migratePublicKeyBlob(db, log); err != nil {
+		if backup != "" {
+			return fmt.Errorf("migrate public_key (backup kept at %s): %w", backup, err)
+		}
+		return fmt.Errorf("migrate public_key: %w", err)
+	}
+	if backup != "" {
+		removeBackup(backup)
+	}
+
+	if err := migrateColumns(db, log); err != nil {
+		return fmt.Errorf("migrate columns: %w", err)
+	}
+	if err := dropNotesIndex(db, log); err != nil {
+		return fmt.Errorf("drop notes index: %w", err)
+	}
+
+	return nil
+}
+
+// schemaObjects are the tables and indexes migrateSchema leaves behind.
+var schemaObjects = []string{
+	"config", "credentials", "locks", "credential_tags", "access_log", "rotations",
+	"idx_credential_tags_key", "idx_access_log_name", "idx_rotations_credential",
+	"idx_rotations_date", "idx_credentials_key_id",
+}
+
+// checkSchema reports, as ErrReadOnly, a schema migrateSchema would still
+// change, along with the config rows NewDatabase would otherwise create.
+func checkSchema(db *sql.DB) error {
+	pending, err := pendingMigration(db)
+	if err != nil {
+		return fmt.Errorf("schema: %w", err)
+	}
+	if pending != "" {
+		return fmt.Errorf("%w: vault needs migration (%s); open it read-write once", ErrReadOnly, pending)
+	}
+	return nil
+}
+
+// pendingMigration names the first change migrateSchema or NewDatabase
+// would make to db, or returns "" if there is none.
+func pendingMigration(db *sql.DB) (string, error) {
+	for _, name := range schemaObjects {
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = ?`, name).Scan(&n); err != nil {
+			return "", err
+		}
+		if n == 0 {
+			return "missing " + name, nil
 		}
-		if name == "public_key" {
-			hasColumn = true
-			break
+	}
+	cols, err := tableColumns(db, "credentials")
+	if err != nil {
+		return "", err
+	}
+	if _, ok := cols["public_key"]; !ok {
+		return "v2 columns", nil
+	}
+	if strings.EqualFold(cols["public_key"], "TEXT") {
+		return "public_key as BLOB", nil
+	}
+	for _, c := range addedColumns {
+		if _, ok := cols[c.name]; !ok {
+			return "column " + c.name, nil
 		}
 	}
-	if hasColumn {
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'note_index'`).Scan(&n); err != nil {
+		return "", err
+	}
+	if n > 0 {
+		return "drop notes index", nil
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM config WHERE key = 'salt' AND length(value) = ?`, saltLen).Scan(&n); err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "salt", nil
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM config WHERE key = ?`, integrityConfigKey).Scan(&n); err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "integrity digest", nil
+	}
+	return "", nil
+}
+
+func migrateV2(db *sql.DB, log *slog.Logger) error {
+	// Idempotent: check if public_key column already exists
+	cols, err := tableColumns(db, "credentials")
+	if err != nil {
+		return err
+	}
+	if _, ok := cols["public_key"]; ok {
 		return nil
 	}
 
 	for _, stmt := range []string{
 		`ALTER TABLE credentials ADD COLUMN environment TEXT`,
-		`ALTER TABLE credentials ADD COLUMN public_key TEXT`,
+		`ALTER TABLE credentials ADD COLUMN public_key BLOB`,
 		`ALTER TABLE credentials ADD COLUMN url TEXT`,
 		`ALTER TABLE credentials ADD COLUMN config TEXT`,
 		`ALTER TABLE credentials ADD COLUMN key_id TEXT`,
@@ -597,5 +1736,137 @@ func migrateV2(db *sql.DB) error {
 		return fmt.Errorf("migrate rotations: %w", err)
 	}
 
+	log.Info("vault migrated", "migration", "v2")
+	return nil
+}
+
+// migratePublicKeyBlob fixes vaults created before public_key was declared
+// BLOB. It holds AES-GCM ciphertext, so TEXT affinity is wrong. SQLite can't
+// change a column type in place, so the table is rebuilt in one transaction.
+func migratePublicKeyBlob(db *sql.DB, log *slog.Logger) error {
+	if pending, err := publicKeyNeedsBlob(db); err != nil || !pending {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		CREATE TABLE credentials_new (
+			id           TEXT PRIMARY KEY,
+			name         TEXT UNIQUE NOT NULL,
+			api_key      BLOB NOT NULL,
+			api_type     TEXT,
+			metadata     TEXT,
+			created_at   INTEGER NOT NULL,
+			updated_at   INTEGER NOT NULL,
+			environment  TEXT,
+			public_key   BLOB,
+			url          TEXT,
+			config       TEXT,
+			key_id       TEXT,
+			last_rotated INTEGER
+		);
+		INSERT INTO credentials_new
+			(id, name, api_key, api_type, metadata, created_at, updated_at,
+			 environment, public_key, url, config, key_id, last_rotated)
+		SELECT id, name, api_key, api_type, metadata, created_at, updated_at,
+			environment, CAST(public_key AS BLOB), url, config, key_id, last_rotated
+		FROM credentials;
+		DROP TABLE credentials;
+		ALTER TABLE credentials_new RENAME TO credentials;
+	`); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Info("vault migrated", "migration", "public_key_blob")
+	return nil
+}
+
+func publicKeyNeedsBlob(db *sql.DB) (bool, error) {
+	cols, err := tableColumns(db, "credentials")
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(cols["public_key"], "TEXT"), nil
+}
+
+// addedColumns are columns introduced after V2. migrateColumns adds any that
+// are missing, so new columns only need appending here.
+var addedColumns = []struct{ name, decl string }{
+	{"disabled", "INTEGER NOT NULL DEFAULT 0"},
+	{"extra_salt", "BLOB"}, // set when the secret is wrapped under a per-credential passphrase
+	{"urls", "TEXT"},       // JSON array of endpoints; url holds the first
+	{"expires_at", "INTEGER"},
+	{"rotation_interval", "INTEGER"}, // seconds
+}
+
+func marshalURLs(urls []string) *string {
+	b, _ := json.Marshal(urls)
+	s := string(b)
+	return &s
+}
+
+// dropNotesIndex removes the notes blind index earlier versions could keep
+// (the note_index table and its notes_index config row). Notes are stored
+// as plain text next to it, so it hid nothing.
+func dropNotesIndex(db *sql.DB, log *slog.Logger) error {
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'note_index'`).Scan(&n); err != nil || n == 0 {
+		return err
+	}
+	if _, err := db.Exec(`DROP TABLE note_index; DELETE FROM config WHERE key = 'notes_index'`); err != nil {
+		return err
+	}
+	log.Info("vault migrated", "migration", "drop_notes_index")
+	return nil
+}
+
+func migrateColumns(db *sql.DB, log *slog.Logger) error {
+	cols, err := tableColumns(db, "credentials")
+	if err != nil {
+		return err
+	}
+	for _, c := range addedColumns {
+		if _, ok := cols[c.name]; ok {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE credentials ADD COLUMN %s %s`, c.name, c.decl)); err != nil {
+			return fmt.Errorf("add %s: %w", c.name, err)
+		}
+		log.Info("vault migrated", "migration", "add_column", "column", c.name)
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_credentials_key_id ON credentials(key_id)`); err != nil {
+		return fmt.Errorf("index key_id: %w", err)
+	}
 	return nil
 }
+
+// tableColumns returns the declared type of each column in table, keyed by
+// column name.
+func tableColumns(db *sql.DB, table string) (map[string]string, error) {
+	rows, err := db.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols := make(map[string]string)
+	for rows.Next() {
+		var cid int
+		var name, typ string
+		var notnull int
+		var dflt sql.NullString
+		var pk int
+		if err := rows.Scan(&cid, &name, &typ, &notnull, &dflt, &pk); err != nil {
+			return nil, err
+		}
+		cols[name] = typ
+	}
+	return cols, rows.Err()
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expired reports whether c has an expiry and now is past it.
+func (c *Credential) Expired(now time.Time) bool {
+	return c.ExpiresAt != nil && now.After(*c.ExpiresAt)
+}
+
+// ListExpiring returns the credentials whose expiry falls within the given
+// duration from now, including those already expired, in name order. No
+// secrets are included.
+func (d *Database) ListExpiring(within time.Duration) ([]Credential, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	return d.listWhere(context.Background(), "WHERE expires_at IS NOT NULL AND expires_at <= ?", d.now().Add(within).Unix())
+}
+
+// ParseTTL parses a lifetime such as "90d", "2w" or any time.ParseDuration
+// string ("36h"). It must be positive.
+func ParseTTL(s string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	if n, unit, ok := cutUnit(s); ok {
+		d = time.Duration(n) * unit
+	} else if d, err = time.ParseDuration(s); err != nil {
+		return 0, fmt.Errorf("invalid duration %q (use e.g. 90d, 2w or 36h)", s)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration %q must be positive", s)
+	}
+	return d, nil
+}
+
+// cutUnit parses the day and week forms time.ParseDuration lacks.
+func cutUnit(s string) (int64, time.Duration, bool) {
+	const day = 24 * time.Hour
+	for suffix, unit := range map[string]time.Duration{"d": day, "w": 7 * day} {
+		if num, ok := strings.CutSuffix(s, suffix); ok {
+			n, err := strconv.ParseInt(num, 10, 32)
+			return n, unit, err == nil
+		}
+	}
+	return 0, 0, false
+}
+
+func unixPtr(v sql.NullInt64) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := time.Unix(v.Int64, 0)
+	return &t
+}
+
+// PurgeExpired deletes every credential whose expiry is before now, with
+// its rotation history, and returns how many went.
+func (d *Database) PurgeExpired(now time.Time) (int, error) {
+	return d.PurgeExpiredFunc(now, nil)
+}
+
+// PurgeExpiredFunc is PurgeExpired that calls deleted, if non-nil, with
+// each purged credential's name and what went with it, in name order. The
+// purge is one transaction; deleted runs only once it has committed, so it
+// never reports a deletion that was rolled back, and may call methods on d.
+func (d *Database) PurgeExpiredFunc(now time.Time, deleted func(name string, stats DeleteStats)) (int, error) {
+	type purged struct {
+		name  string
+		stats DeleteStats
+	}
+	var gone []purged
+	err := func() error {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		if err := d.use(); err != nil {
+			return err
+		}
+		return d.writeTx(func(tx *sql.Tx) error {
+			rows, err := tx.Query(`SELECT name FROM credentials
+				WHERE expires_at IS NOT NULL AND expires_at < ? ORDER BY name`, now.Unix())
+			if err != nil {
+				return err
+			}
+			var names []string
+			for rows.Next() {
+				var name string
+				if err := rows.Scan(&name); err != nil {
+					rows.Close()
+					return err
+				}
+				names = append(names, name)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+
+			for _, name := range names {
+				stats, err := deleteCredential(context.Background(), tx, name)
+				if err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+				gone = append(gone, purged{name, stats})
+			}
+			return nil
+		})
+	}()
+	if err != nil {
+		return 0, err
+	}
+	if deleted != nil {
+		for _, p := range gone {
+			deleted(p.name, p.stats)
+		}
+	}
+	return len(gone), nil
+}
@@ -0,0 +1,431 @@
+package core
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Exports are sealed with a passphrase chosen at export time, independent
+// of the master password, so a file can be handed to someone else. The
+// envelope header is plaintext JSON and is bound to the ciphertext as
+// AES-GCM associated data.
+const (
+	exportFormat  = "api-vault-export"
+	exportVersion = 1
+)
+
+// Export scopes. The scope is readable without the passphrase so import can
+// tell a single shared credential from a full vault backup.
+const (
+	ScopeCredential = "credential"
+	ScopeVault      = "vault"
+)
+
+// ErrBadPassphrase is returned when an export can't be opened with the given
+// passphrase (or has been tampered with).
+var ErrBadPassphrase = errors.New("wrong passphrase or corrupted export")
+
+// ExportedCredential is one credential in an export, secrets in the clear.
+type ExportedCredential struct {
+	Name             string            `json:"name"`
+	APIType          string            `json:"api_type,omitempty"`
+	Metadata         string            `json:"metadata,omitempty"`
+	Environment      *string           `json:"environment,omitempty"`
+	SecretKey        *string           `json:"secret_key,omitempty"`
+	PublicKey        *string           `json:"public_key,omitempty"`
+	URL              *string           `json:"url,omitempty"`
+	URLs             []string          `json:"urls,omitempty"`
+	Config           map[string]string `json:"config,omitempty"`
+	KeyID            *string           `json:"key_id,omitempty"`
+	LastRotated      *time.Time        `json:"last_rotated,omitempty"`
+	ExpiresAt        *time.Time        `json:"expires_at,omitempty"`
+	RotationInterval *time.Duration    `json:"rotation_interval,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+	Disabled         bool              `json:"disabled,omitempty"`
+	Tags             []string          `json:"tags,omitempty"`
+	History          []RotationRecord  `json:"history,omitempty"`
+
+	// A passphrase-protected secret travels still wrapped under its own
+	// passphrase: SealedSecret is the wrapped secret and SecretSalt the
+	// salt its key was derived with. SecretKey is then nil.
+	SealedSecret []byte `json:"sealed_secret,omitempty"`
+	SecretSalt   []byte `json:"secret_salt,omitempty"`
+}
+
+// Bundle is the decrypted content of an export.
+type Bundle struct {
+	Scope       string               `json:"scope"`
+	ExportedAt  time.Time            `json:"exported_at"`
+	Credentials []ExportedCredential `json:"credentials"`
+}
+
+type envelope struct {
+	Format     string      `json:"format"`
+	Version    int         `json:"version"`
+	Scope      string      `json:"scope"`
+	KDF        envelopeKDF `json:"kdf"`
+	Ciphertext []byte      `json:"ciphertext"` // nonce || AES-256-GCM(bundle JSON)
+}
+
+type envelopeKDF struct {
+	Algorithm string `json:"algorithm"`
+	Salt      []byte `json:"salt"`
+	Time      uint32 `json:"time"`
+	MemoryKiB uint32 `json:"memory_kib"`
+	Threads   uint8  `json:"threads"`
+}
+
+// ExportCredential bundles a single credential, including disabled ones,
+// and optionally its full rotation history.
+func (d *Database) ExportCredential(name string, withHistory bool) (*Bundle, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	c, err := d.getCredentialV2(context.Background(), name, "")
+	if err != nil {
+		return nil, err
+	}
+	if c.Protected {
+		// Exporting would either drop the secret or strip its protection.
+		return nil, ErrPassphraseRequired
+	}
+	ec := exportedFrom(c)
+	if withHistory {
+		if ec.History, err = d.getRotationHistory(name, -1); err != nil {
+			return nil, fmt.Errorf("history: %w", err)
+		}
+	}
+	return &Bundle{Scope: ScopeCredential, ExportedAt: d.now().UTC(), Credentials: []ExportedCredential{ec}}, nil
+}
+
+// ExportVault bundles every credential with its full rotation history.
+// Passphrase-protected secrets stay wrapped under their passphrase.
+func (d *Database) ExportVault() (*Bundle, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	err := eachWhere(context.Background(), d.reader(), "", func(c Credential) error {
+		names = append(names, c.Name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{Scope: ScopeVault, ExportedAt: d.now().UTC(), Credentials: []ExportedCredential{}}
+	for _, name := range names {
+		c, err := d.getCredentialV2(context.Background(), name, "")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		ec := exportedFrom(c)
+		if c.Protected {
+			if ec.SealedSecret, ec.SecretSalt, err = d.sealedSecret(name); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		if ec.History, err = d.getRotationHistory(name, -1); err != nil {
+			return nil, fmt.Errorf("%s: history: %w", name, err)
+		}
+		b.Credentials = append(b.Credentials, ec)
+	}
+	return b, nil
+}
+
+// Export writes the whole vault to w as an envelope sealed under
+// passphrase, which is independent of the master password.
+func (d *Database) Export(w io.Writer, passphrase string) error {
+	b, err := d.ExportVault()
+	if err != nil {
+		return err
+	}
+	return SealBundle(w, b, passphrase)
+}
+
+// Import opens the envelope in r with passphrase and merges it into the
+// vault as ImportBundle does.
+func (d *Database) Import(r io.Reader, passphrase string, overwrite bool) error {
+	b, err := OpenBundle(r, passphrase)
+	if err != nil {
+		return err
+	}
+	return d.ImportBundle(b, overwrite)
+}
+
+// sealedSecret returns a protected credential's secret still wrapped under
+// its passphrase, and the passphrase salt. Callers must hold d.mu.
+func (d *Database) sealedSecret(name string) (sealed, salt []byte, err error) {
+	var blob []byte
+	err = d.db.QueryRow(`SELECT api_key, extra_salt FROM credentials WHERE name = ?`, name).Scan(&blob, &salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	sealed, err = d.decrypt(blob)
+	return sealed, salt, err
+}
+
+func exportedFrom(c *Credential) ExportedCredential {
+	return ExportedCredential{
+		Name:             c.Name,
+		APIType:          c.APIType,
+		Metadata:         c.Metadata,
+		Environment:      c.Environment,
+		SecretKey:        c.SecretKey,
+		PublicKey:        c.PublicKey,
+		URL:              c.URL,
+		URLs:             c.URLs,
+		Config:           c.Config,
+		KeyID:            c.KeyID,
+		LastRotated:      c.LastRotated,
+		ExpiresAt:        c.ExpiresAt,
+		RotationInterval: c.RotationInterval,
+		CreatedAt:        c.CreatedAt,
+		UpdatedAt:        c.UpdatedAt,
+		Disabled:         c.Disabled,
+		Tags:             c.Tags,
+	}
+}
+
+// ImportBundle merges every credential in b into the vault in one
+// transaction, keeping original timestamps and history. If any name already
+// exists and overwrite isn't set, nothing is written and the error wraps
+// ErrDuplicate and lists the conflicting names. With overwrite, existing
+// credentials are replaced by the bundle's, and history records the vault
+// doesn't already have are added to theirs.
+func (d *Database) ImportBundle(b *Bundle, overwrite bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return err
+	}
+
+	var conflicts []string
+	for _, ec := range b.Credentials {
+		var n int
+		if err := d.db.QueryRow(`SELECT COUNT(*) FROM credentials WHERE name = ?`, ec.Name).Scan(&n); err != nil {
+			return err
+		}
+		if n > 0 {
+			conflicts = append(conflicts, ec.Name)
+		}
+	}
+	if len(conflicts) > 0 && !overwrite {
+		sort.Strings(conflicts)
+		return fmt.Errorf("%w: %s", ErrDuplicate, strings.Join(conflicts, ", "))
+	}
+
+	return d.writeTx(func(tx *sql.Tx) error {
+		for _, ec := range b.Credentials {
+			if overwrite {
+				if _, err := tx.Exec(`DELETE FROM credentials WHERE name = ?`, ec.Name); err != nil {
+					return fmt.Errorf("%s: %w", ec.Name, err)
+				}
+			}
+			if err := d.importCredential(tx, ec); err != nil {
+				return fmt.Errorf("%s: %w", ec.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (d *Database) importCredential(tx *sql.Tx, ec ExportedCredential) error {
+	cred := &Credential{
+		Name:             ec.Name,
+		APIType:          ec.APIType,
+		Metadata:         ec.Metadata,
+		Environment:      ec.Environment,
+		SecretKey:        ec.SecretKey,
+		PublicKey:        ec.PublicKey,
+		URL:              ec.URL,
+		URLs:             ec.URLs,
+		Config:           ec.Config,
+		KeyID:            ec.KeyID,
+		ExpiresAt:        ec.ExpiresAt,
+		Tags:             ec.Tags,
+		RotationInterval: ec.RotationInterval,
+	}
+	sealed := ec.SealedSecret != nil
+	if sealed {
+		if ec.Name == "" || ec.SecretKey != nil || len(ec.SecretSalt) != saltLen {
+			return errors.New("malformed protected secret")
+		}
+	} else if err := cred.Validate(); err != nil {
+		return err
+	}
+	if err := d.insertCredential(tx, cred, ec.CreatedAt.Unix(), ec.UpdatedAt.Unix()); err != nil {
+		return err
+	}
+	if sealed {
+		blob, err := d.encrypt(ec.SealedSecret)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE credentials SET api_key = ?, extra_salt = ? WHERE name = ?`,
+			blob, ec.SecretSalt, ec.Name); err != nil {
+			return err
+		}
+	}
+
+	var lastRotated *int64
+	if ec.LastRotated != nil {
+		t := ec.LastRotated.Unix()
+		lastRotated = &t
+	}
+	if _, err := tx.Exec(`UPDATE credentials SET last_rotated = ?, disabled = ? WHERE name = ?`,
+		lastRotated, ec.Disabled, ec.Name); err != nil {
+		return err
+	}
+
+	for _, r := range ec.History {
+		// A record already logged (same time, plugin and actor) isn't
+		// repeated when a bundle is imported over its own source.
+		var n int
+		if err := tx.QueryRow(
+			`SELECT COUNT(*) FROM rotations WHERE credential_name = ? AND rotated_at = ? AND plugin_name = ? AND rotated_by = ?`,
+			ec.Name, r.RotatedAt.Unix(), r.PluginName, r.RotatedBy,
+		).Scan(&n); err != nil {
+			return fmt.Errorf("history: %w", err)
+		}
+		if n > 0 {
+			continue
+		}
+		fieldsJSON, _ := json.Marshal(r.RotatedFields)
+		metaJSON, err := d.encodeRotationMeta(r.Metadata)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO rotations (id, credential_name, rotated_fields, old_key_id, new_key_id, plugin_name, rotated_at, rotated_by, metadata)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			newID(), ec.Name, string(fieldsJSON), nil, r.NewKeyID, r.PluginName, r.RotatedAt.Unix(), r.RotatedBy, metaJSON,
+		); err != nil {
+			return fmt.Errorf("history: %w", err)
+		}
+	}
+	return nil
+}
+
+// SealBundle encrypts b under passphrase and writes the envelope to w.
+func SealBundle(w io.Writer, b *Bundle, passphrase string) error {
+	plain, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+
+	env := envelope{
+		Format:  exportFormat,
+		Version: exportVersion,
+		Scope:   b.Scope,
+		KDF: envelopeKDF{
+			Algorithm: "argon2id",
+			Salt:      make([]byte, saltLen),
+			Time:      argonTime,
+			MemoryKiB: argonMemory,
+			Threads:   argonThreads,
+		},
+	}
+	if _, err := rand.Read(env.KDF.Salt); err != nil {
+		return err
+	}
+
+	gcm, err := env.aead(passphrase)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, nonceLen)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	env.Ciphertext = gcm.Seal(nonce, nonce, plain, env.header())
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(env)
+}
+
+// OpenBundle reads an envelope from r and decrypts it with passphrase.
+func OpenBundle(r io.Reader, passphrase string) (*Bundle, error) {
+	var env envelope
+	if err := json.NewDecoder(r).Decode(&env); err != nil {
+		return nil, fmt.Errorf("read export: %w", err)
+	}
+	if env.Format != exportFormat {
+		return nil, fmt.Errorf("not an api-vault export")
+	}
+	if env.Version != exportVersion {
+		return nil, fmt.Errorf("unsupported export version %d", env.Version)
+	}
+	if k := env.KDF; k.Algorithm != "argon2id" || k.Time < 1 || k.Time > 64 ||
+		k.Threads < 1 || k.MemoryKiB < 8*uint32(k.Threads) || k.MemoryKiB > 4<<20 {
+		// Bounded so a crafted file can't make argon2 panic or eat all memory.
+		return nil, fmt.Errorf("unsupported export kdf parameters")
+	}
+	if len(env.Ciphertext) < nonceLen {
+		return nil, ErrBadPassphrase
+	}
+
+	gcm, err := env.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, env.Ciphertext[:nonceLen], env.Ciphertext[nonceLen:], env.header())
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+
+	var b Bundle
+	if err := json.Unmarshal(plain, &b); err != nil {
+		return nil, fmt.Errorf("decode export: %w", err)
+	}
+	return &b, nil
+}
+
+// IsExport reports whether data looks like an api-vault export envelope.
+func IsExport(data []byte) bool {
+	var env struct {
+		Format string `json:"format"`
+	}
+	return json.Unmarshal(data, &env) == nil && env.Format == exportFormat
+}
+
+func (e *envelope) aead(passphrase string) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(passphrase), e.KDF.Salt, e.KDF.Time, e.KDF.MemoryKiB, e.KDF.Threads, argonKeyLen)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// header is the associated data: everything in the envelope except the
+// ciphertext, so the scope and KDF parameters can't be swapped.
+func (e *envelope) header() []byte {
+	h := *e
+	h.Ciphertext = nil
+	b, _ := json.Marshal(h)
+	return b
+}
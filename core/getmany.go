@@ -0,0 +1,88 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MissingError is returned by GetMany when some of the requested names
+// don't exist. It matches ErrNotFound with errors.Is.
+type MissingError struct {
+	Names []string // in the order they were requested
+}
+
+func (e *MissingError) Error() string {
+	return fmt.Sprintf("credentials not found: %s", strings.Join(e.Names, ", "))
+}
+
+func (e *MissingError) Is(target error) bool { return target == ErrNotFound }
+
+// GetMany returns the decrypted secrets of names, keyed by name, fetched
+// with one query. Names that don't exist are reported together in a
+// *MissingError, returned alongside the secrets that were found. A disabled
+// or passphrase-protected credential fails the whole call with ErrDisabled
+// or ErrPassphraseRequired, as GetCredential would. Each secret returned is
+// logged as a GetCredential read.
+func (d *Database) GetMany(names []string) (map[string]string, error) {
+	ctx := context.Background()
+	var secrets map[string]string
+	err := d.read(ctx, func(rs *readState) error {
+		secrets = make(map[string]string, len(names))
+		if len(names) == 0 {
+			return nil
+		}
+		args := make([]any, len(names))
+		for i, n := range names {
+			args[i] = n
+		}
+		rows, err := rs.db.QueryContext(ctx,
+			`SELECT name, api_key, disabled, extra_salt FROM credentials
+			 WHERE name IN (?`+strings.Repeat(",?", len(names)-1)+`)`, args...,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var name string
+			var blob, extraSalt []byte
+			var disabled bool
+			if err := rows.Scan(&name, &blob, &disabled, &extraSalt); err != nil {
+				return err
+			}
+			if disabled {
+				return fmt.Errorf("%s: %w", name, ErrDisabled)
+			}
+			if extraSalt != nil {
+				return fmt.Errorf("%s: %w", name, ErrPassphraseRequired)
+			}
+			plain, err := decryptWith(rs.aead, blob)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			secrets[name] = string(plain)
+			zero(plain)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, n := range names {
+		if _, ok := secrets[n]; !ok && !slices.Contains(missing, n) {
+			missing = append(missing, n)
+		}
+	}
+	for name := range secrets {
+		d.recordAccess(ctx, name, AccessGet)
+	}
+	if missing != nil {
+		return secrets, &MissingError{Names: missing}
+	}
+	return secrets, nil
+}
@@ -0,0 +1,251 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// integrityConfigKey is the config row holding the HMAC over all
+// credential rows.
+const integrityConfigKey = "integrity"
+
+// integrityVersionKey records which digest layout integrityConfigKey holds.
+// Vaults without it carry a version 1 digest.
+const integrityVersionKey = "integrity_version"
+
+// integrityVersion is the digest layout written by updateIntegrity. Version
+// 1 covered only the columns a secret resolves through; version 2 covers
+// every credential column and the tag table. Adding a column to credentials
+// needs a new version, or existing digests stop matching.
+const integrityVersion = 2
+
+// dbtx is the subset of *sql.DB and *sql.Tx used by helpers that run either
+// standalone or inside a write transaction.
+type dbtx interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// deriveMACKey separates the integrity key from the field encryption key so
+// the AES key is never used directly as an HMAC key.
+func deriveMACKey(key []byte) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte("api-vault integrity v1"))
+	return m.Sum(nil)
+}
+
+// IntegrityDigest returns an HMAC-SHA256, keyed from the master password,
+// over every credential row in id order and every tag. Any change made
+// without the password (edited blobs, swapped names, deleted rows, moved
+// expiry dates, dropped tags) changes the digest.
+func (d *Database) IntegrityDigest() ([]byte, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	return d.integrityDigest(d.db)
+}
+
+// VerifyIntegrity compares the stored digest with the current contents and
+// returns ErrIntegrity on mismatch. SQLCipher's page MACs catch random
+// corruption; this catches whole-row edits made through a second copy of
+// the key, or rows restored from another vault file.
+func (d *Database) VerifyIntegrity() error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return err
+	}
+
+	err := d.verifyIntegrity()
+	if errors.Is(err, ErrIntegrity) {
+		d.log.Warn("integrity check failed", "path", d.path)
+	}
+	return err
+}
+
+// verifyIntegrity is VerifyIntegrity for callers holding d.mu.
+func (d *Database) verifyIntegrity() error {
+	var stored []byte
+	err := d.db.QueryRow(`SELECT value FROM config WHERE key = ?`, integrityConfigKey).Scan(&stored)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrIntegrity
+	}
+	if err != nil {
+		return err
+	}
+	version, err := storedIntegrityVersion(d.db)
+	if err != nil {
+		return err
+	}
+	sum, err := d.integrityDigestVersion(d.db, version)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(stored, sum) {
+		return ErrIntegrity
+	}
+	return nil
+}
+
+// writeTx runs fn in a transaction and refreshes the integrity digest before
+// committing, so the digest never lags the data. Callers must hold d.mu.
+func (d *Database) writeTx(fn func(tx *sql.Tx) error) error {
+	return d.writeTxContext(context.Background(), fn)
+}
+
+// writeTxContext is writeTx with the transaction bound to ctx: if ctx ends
+// first, the transaction is rolled back and the error returned.
+func (d *Database) writeTxContext(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := d.updateIntegrity(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (d *Database) updateIntegrity(q dbtx) error {
+	sum, err := d.integrityDigest(q)
+	if err != nil {
+		return err
+	}
+	if _, err := q.Exec(`INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)`, integrityConfigKey, sum); err != nil {
+		return err
+	}
+	_, err = q.Exec(`INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)`, integrityVersionKey, integrityVersion)
+	return err
+}
+
+// storedIntegrityVersion returns the layout of the stored digest.
+func storedIntegrityVersion(q dbtx) (int, error) {
+	var v int
+	err := q.QueryRow(`SELECT value FROM config WHERE key = ?`, integrityVersionKey).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 1, nil
+	}
+	return v, err
+}
+
+// initIntegrity stores a digest for vaults created before integrity checks
+// existed, trusting the current contents, and moves older digests to the
+// current layout. A digest that no longer matches under its own layout is
+// left alone so VerifyIntegrity keeps reporting it.
+func (d *Database) initIntegrity() error {
+	var n int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM config WHERE key = ?`, integrityConfigKey).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return d.updateIntegrity(d.db)
+	}
+	version, err := storedIntegrityVersion(d.db)
+	if err != nil {
+		return err
+	}
+	if version >= integrityVersion || d.readOnly {
+		return nil
+	}
+	if err := d.verifyIntegrity(); err != nil {
+		if errors.Is(err, ErrIntegrity) {
+			return nil
+		}
+		return err
+	}
+	if err := d.updateIntegrity(d.db); err != nil {
+		return err
+	}
+	d.log.Info("vault migrated", "migration", "integrity_digest", "version", integrityVersion)
+	return nil
+}
+
+// integrityDigest hashes the vault under the current layout.
+func (d *Database) integrityDigest(q dbtx) ([]byte, error) {
+	return d.integrityDigestVersion(q, integrityVersion)
+}
+
+// integrityDigestVersion hashes the vault under the given layout. Each value
+// is length-prefixed, with NULL distinct from empty; version 2 separates the
+// credential rows from the tag rows with a marker byte no value starts with.
+func (d *Database) integrityDigestVersion(q dbtx, version int) ([]byte, error) {
+	m := hmac.New(sha256.New, d.macKey)
+	switch version {
+	case 1:
+		if err := hashRows(m, q, `SELECT id, name, api_type, api_key, public_key, url, config
+			FROM credentials ORDER BY id`); err != nil {
+			return nil, err
+		}
+	case 2:
+		if err := hashRows(m, q, `SELECT id, name, api_key, api_type, metadata, environment,
+			public_key, url, urls, config, key_id, last_rotated, expires_at, rotation_interval,
+			disabled, extra_salt, created_at, updated_at
+			FROM credentials ORDER BY id`); err != nil {
+			return nil, err
+		}
+		m.Write([]byte{2})
+		if err := hashRows(m, q, `SELECT credential_name, tag, tag_key
+			FROM credential_tags ORDER BY credential_name, tag_key`); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown integrity digest version %d", version)
+	}
+	return m.Sum(nil), nil
+}
+
+// hashRows writes every value query returns to m, length-prefixed.
+func hashRows(m hash.Hash, q dbtx, query string) error {
+	rows, err := q.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	names, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	var lenBuf [9]byte
+	for rows.Next() {
+		cols := make([][]byte, len(names))
+		ptrs := make([]any, len(cols))
+		for i := range cols {
+			ptrs[i] = &cols[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		for _, c := range cols {
+			if c == nil {
+				m.Write([]byte{0})
+				continue
+			}
+			lenBuf[0] = 1
+			binary.BigEndian.PutUint64(lenBuf[1:], uint64(len(c)))
+			m.Write(lenBuf[:])
+			m.Write(c)
+		}
+	}
+	return rows.Err()
+}
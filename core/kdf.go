@@ -0,0 +1,170 @@
+package core
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// KeyDeriver derives the field key from the master password and the
+// vault's salt. ID names the algorithm; it is stored in the vault so an
+// existing vault always opens with the KDF it was created with. The
+// implementations are Argon2Params (the default) and ScryptParams.
+type KeyDeriver interface {
+	ID() string
+	Derive(password string, salt []byte) []byte
+}
+
+// KDF ids, as stored under kdfIDConfigKey.
+const (
+	KDFArgon2id = "argon2id"
+	KDFScrypt   = "scrypt"
+)
+
+// kdfConfigKey is the config row holding the vault's KDF parameters as
+// JSON, and kdfIDConfigKey the row naming the KDF, both written when the
+// vault is created. Older vaults without them use DefaultArgon2Params.
+const (
+	kdfConfigKey   = "kdf"
+	kdfIDConfigKey = "kdf_id"
+)
+
+// WithKDF sets the key derivation a new vault's field key is derived with:
+// Argon2Params or ScryptParams. An existing vault keeps the KDF stored in
+// it; change it with UpgradeKDF.
+func WithKDF(k KeyDeriver) Option {
+	return func(o *options) { o.kdf = k }
+}
+
+// WithArgon2Params is WithKDF for Argon2id, e.g. a lighter set on a small
+// device.
+func WithArgon2Params(p Argon2Params) Option {
+	return WithKDF(p)
+}
+
+// NewDatabaseWithParams is NewDatabase with WithArgon2Params(params).
+func NewDatabaseWithParams(path, password string, params Argon2Params, opts ...Option) (*Database, error) {
+	return NewDatabase(path, password, append(opts, WithArgon2Params(params))...)
+}
+
+// ID implements KeyDeriver.
+func (p Argon2Params) ID() string { return KDFArgon2id }
+
+// Derive implements KeyDeriver.
+func (p Argon2Params) Derive(password string, salt []byte) []byte {
+	return argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+}
+
+// Validate bounds the parameters: the field key is AES-256, and an absurd
+// memory cost would take the process down rather than just being slow.
+func (p Argon2Params) Validate() error {
+	switch {
+	case p.KeyLen != argonKeyLen:
+		return fmt.Errorf("argon2 key length must be %d", argonKeyLen)
+	case p.Time < 1 || p.Time > 64:
+		return fmt.Errorf("argon2 time must be 1-64, got %d", p.Time)
+	case p.Threads < 1:
+		return fmt.Errorf("argon2 threads must be at least 1")
+	case p.Memory < 8*uint32(p.Threads) || p.Memory > 4<<20:
+		return fmt.Errorf("argon2 memory must be %d KiB-4 GiB, got %d KiB", 8*uint32(p.Threads), p.Memory)
+	}
+	return nil
+}
+
+// Cost times one key derivation under p, i.e. what each unlock will take.
+func (p Argon2Params) Cost() time.Duration {
+	start := time.Now()
+	p.Derive("cost-probe", make([]byte, saltLen))
+	return time.Since(start)
+}
+
+// WeakerThan reports whether p costs less than base in time or memory, e.g.
+// a vault created before DefaultArgon2Params were raised.
+func (p Argon2Params) WeakerThan(base Argon2Params) bool {
+	return p.Time < base.Time || p.Memory < base.Memory
+}
+
+// KDF returns the key derivation the vault's field key is derived with.
+func (d *Database) KDF() KeyDeriver {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.kdf
+}
+
+// Argon2Params returns the parameters the vault's field key is derived
+// with, or the zero value if the vault uses another KDF (see KDF).
+func (d *Database) Argon2Params() Argon2Params {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	p, _ := d.kdf.(Argon2Params)
+	return p
+}
+
+// validateKDF checks k's parameters. Only the KDFs loadKDF can read back
+// are accepted, since a vault must be reopenable.
+func validateKDF(k KeyDeriver) error {
+	switch k := k.(type) {
+	case Argon2Params:
+		return k.Validate()
+	case ScryptParams:
+		return k.Validate()
+	case nil:
+		return errors.New("no key derivation given")
+	}
+	return fmt.Errorf("unsupported key derivation %q", k.ID())
+}
+
+func loadKDF(q dbtx) (KeyDeriver, error) {
+	var id string
+	err := q.QueryRow(`SELECT value FROM config WHERE key = ?`, kdfIDConfigKey).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		id = KDFArgon2id
+	} else if err != nil {
+		return nil, err
+	}
+
+	var raw []byte
+	err = q.QueryRow(`SELECT value FROM config WHERE key = ?`, kdfConfigKey).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) && id == KDFArgon2id {
+		return DefaultArgon2Params(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var k KeyDeriver
+	switch id {
+	case KDFArgon2id:
+		var p Argon2Params
+		if err = json.Unmarshal(raw, &p); err == nil {
+			k = p
+		}
+	case KDFScrypt:
+		var p ScryptParams
+		if err = json.Unmarshal(raw, &p); err == nil {
+			k = p
+		}
+	default:
+		return nil, fmt.Errorf("unknown key derivation %q", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return k, validateKDF(k)
+}
+
+func storeKDF(q dbtx, k KeyDeriver) error {
+	b, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	if _, err := q.Exec(`INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)`, kdfConfigKey, b); err != nil {
+		return err
+	}
+	_, err = q.Exec(`INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)`, kdfIDConfigKey, k.ID())
+	return err
+}
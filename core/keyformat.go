@@ -0,0 +1,79 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// keyFormat holds the expected shape of a provider's secret and public
+// keys. A nil pattern means that field isn't checked.
+type keyFormat struct {
+	secret, public *regexp.Regexp
+	hint           string // expected secret shape, for messages
+}
+
+// keyFormats are the per-api_type validators used by CheckKeyFormat. Types
+// not listed here are not checked.
+var keyFormats = map[string]keyFormat{
+	"openai": {
+		secret: regexp.MustCompile(`^sk-(proj-|svcacct-|admin-)?[A-Za-z0-9_-]{20,}$`),
+		hint:   "sk-...",
+	},
+	"anthropic": {
+		secret: regexp.MustCompile(`^sk-ant-[A-Za-z0-9_-]{20,}$`),
+		hint:   "sk-ant-...",
+	},
+	"stripe": {
+		secret: regexp.MustCompile(`^(sk|rk)_(live|test)_[A-Za-z0-9]{10,}$`),
+		public: regexp.MustCompile(`^pk_(live|test)_[A-Za-z0-9]{10,}$`),
+		hint:   "sk_live_... or sk_test_...",
+	},
+	"github": {
+		secret: regexp.MustCompile(`^(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})$`),
+		hint:   "ghp_... or github_pat_...",
+	},
+	"supabase": {
+		secret: regexp.MustCompile(`^(sb_secret_[A-Za-z0-9_-]{10,}|eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+)$`),
+		public: regexp.MustCompile(`^(sb_publishable_[A-Za-z0-9_-]{10,}|eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+)$`),
+		hint:   "sb_secret_... or a service_role JWT",
+	},
+}
+
+// HasKeyFormat reports whether CheckKeyFormat knows apiType.
+func HasKeyFormat(apiType string) bool {
+	_, ok := keyFormats[strings.ToLower(strings.TrimSpace(apiType))]
+	return ok
+}
+
+// CheckKeyFormat checks a credential's decrypted keys against the expected
+// format for its api_type and returns one message per problem. Surrounding
+// whitespace is flagged for every type; pattern checks only run for types
+// HasKeyFormat knows. Messages never include the key itself.
+func CheckKeyFormat(apiType string, secret, public *string) []string {
+	f := keyFormats[strings.ToLower(strings.TrimSpace(apiType))]
+
+	var problems []string
+	check := func(field string, value *string, want, other *regexp.Regexp, otherField, hint string) {
+		if value == nil || *value == "" {
+			return
+		}
+		v := *value
+		if strings.TrimSpace(v) != v {
+			problems = append(problems, field+" has leading or trailing whitespace")
+			v = strings.TrimSpace(v)
+		}
+		switch {
+		case want == nil || want.MatchString(v):
+		case other != nil && other.MatchString(v):
+			problems = append(problems, fmt.Sprintf("%s looks like a %s", field, otherField))
+		case hint != "":
+			problems = append(problems, fmt.Sprintf("%s doesn't match the %s format (want %s, got %d chars)", field, apiType, hint, len(v)))
+		default:
+			problems = append(problems, fmt.Sprintf("%s doesn't match the %s format (%d chars)", field, apiType, len(v)))
+		}
+	}
+	check("secret", secret, f.secret, f.public, "public key", f.hint)
+	check("public key", public, f.public, f.secret, "secret key", "")
+	return problems
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// LockRotation takes the advisory rotation lock for name, so one rotation
+// (plugin call plus RotateCredential) runs at a time per credential, across
+// processes sharing the vault. It fails fast with ErrRotationInProgress if
+// another holder's lock hasn't expired. The lock expires after ttl in case
+// the holder dies; unlock releases it early.
+//
+// While a lock is held, RotateCredential from any other handle fails with
+// ErrRotationInProgress.
+func (d *Database) LockRotation(name string, ttl time.Duration) (unlock func() error, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	if d.readOnly {
+		return nil, ErrReadOnly
+	}
+	owner := newID()
+	now := d.now()
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM locks WHERE name = ? AND expires_at <= ?`, name, now.Unix()); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`INSERT INTO locks (name, owner, expires_at) VALUES (?, ?, ?)`,
+		name, owner, now.Add(ttl).Unix()); err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrRotationInProgress
+		}
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	d.locks[name] = owner
+
+	return func() error {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		if err := d.use(); err != nil {
+			return err
+		}
+		if d.locks[name] != owner {
+			return nil
+		}
+		delete(d.locks, name)
+		_, err := d.db.Exec(`DELETE FROM locks WHERE name = ? AND owner = ?`, name, owner)
+		return err
+	}, nil
+}
+
+// checkRotationLock returns ErrRotationInProgress if a live lock on name is
+// held by someone other than this handle. Callers must hold d.mu.
+func (d *Database) checkRotationLock(tx *sql.Tx, name string) error {
+	var owner string
+	err := tx.QueryRow(`SELECT owner FROM locks WHERE name = ? AND expires_at > ?`, name, d.now().Unix()).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if owner != d.locks[name] {
+		return ErrRotationInProgress
+	}
+	return nil
+}
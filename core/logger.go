@@ -0,0 +1,32 @@
+package core
+
+import "log/slog"
+
+// A Database reports what it does to a *slog.Logger, for embedders that
+// want vault activity in their own log pipeline:
+//
+//   - Info: the vault was opened or migrated, a credential was rotated,
+//     the master password or KDF changed, the vault was locked or unlocked.
+//   - Debug: each secret read the access log covers (see accesslog.go),
+//     logged whether or not WithAccessLog records it.
+//   - Warn: a wrong master password, a failed integrity check, an open
+//     that failed.
+//
+// Records carry credential names, API types, plugin and KDF ids, field
+// names and counts. No secret, key, password, passphrase or rotation
+// metadata value is ever passed to the logger, at any level. Without
+// WithLogger everything is discarded.
+
+// WithLogger sends the vault's events to l. A nil l discards them, as
+// does leaving the option out.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) { o.logger = l }
+}
+
+// log returns o's logger, or one that discards everything.
+func (o *options) log() *slog.Logger {
+	if o.logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return o.logger
+}
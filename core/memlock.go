@@ -0,0 +1,61 @@
+package core
+
+// With WithMemoryLock, the field and MAC keys are copied into their own
+// page-aligned anonymous mapping, which is mlock'ed so the kernel never
+// swaps them to disk, and munlock'ed and unmapped when they are wiped
+// (Relock, Close, a re-key). A mapping of their own means locking never
+// pins, and unlocking never releases, pages shared with unrelated heap
+// objects. Locking is best effort: without mmap/mlock (Windows, Plan 9) or
+// over RLIMIT_MEMLOCK the keys stay on the heap, the vault opens anyway and
+// MemoryLocked reports false. The AES key schedule inside the cached AEAD lives in
+// ordinary memory either way.
+//
+// Independently of the option, decrypted plaintext is zeroed once it has
+// been copied into the string handed to the caller. Go strings can't be
+// wiped, so the returned secrets themselves stay until collected.
+
+// WithMemoryLock mlocks the in-memory keys; see MemoryLocked.
+func WithMemoryLock() Option {
+	return func(o *options) { o.memLock = true }
+}
+
+// MemoryLocked reports whether the field key is mlock'ed: WithMemoryLock
+// was given and the platform allowed it.
+func (d *Database) MemoryLocked() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.memLocked
+}
+
+// lockKeys moves key and mac into a locked region, zeroing the heap
+// copies. It returns the keys to use from now on and whether they are
+// locked; on failure they are key and mac unchanged.
+func lockKeys(key, mac []byte) (lockedKey, lockedMAC []byte, ok bool) {
+	region, err := lockedRegion(len(key) + len(mac))
+	if err != nil {
+		return key, mac, false
+	}
+	n := copy(region, key)
+	copy(region[n:], mac)
+	zero(key)
+	zero(mac)
+	// lockedKey keeps the region's full capacity so releaseKeys can find it.
+	return region[:n], region[n : n+len(mac) : n+len(mac)], true
+}
+
+// releaseKeys zeroes key and mac and, if lockKeys placed them, unlocks and
+// unmaps their region.
+func releaseKeys(key, mac []byte, locked bool) {
+	zero(key)
+	zero(mac)
+	if locked {
+		freeRegion(key[:cap(key)])
+	}
+}
+
+// zero overwrites b, e.g. a decrypted plaintext once it has been copied.
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
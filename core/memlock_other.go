@@ -0,0 +1,11 @@
+//go:build !unix
+
+package core
+
+import "errors"
+
+func lockedRegion(int) ([]byte, error) {
+	return nil, errors.New("mlock is not supported on this platform")
+}
+
+func freeRegion([]byte) {}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package core
+
+import "golang.org/x/sys/unix"
+
+// lockedRegion maps at least n bytes of private anonymous memory, rounded up
+// to whole pages, and mlocks it.
+func lockedRegion(n int) ([]byte, error) {
+	size := (n + unix.Getpagesize() - 1) &^ (unix.Getpagesize() - 1)
+	b, err := unix.Mmap(-1, 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANON)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.Mlock(b); err != nil {
+		unix.Munmap(b)
+		return nil, err
+	}
+	return b, nil
+}
+
+// freeRegion zeroes, unlocks and unmaps a region from lockedRegion.
+func freeRegion(b []byte) {
+	zero(b)
+	unix.Munlock(b)
+	unix.Munmap(b)
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"context"
+	"crypto/cipher"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Reads that don't need to see an open write transaction (Get, List,
+// Search and friends) go through a second pool opened with mode=ro, so a
+// long-running read never holds a connection a writer is waiting for and
+// can't write by accident. It is keyed and configured exactly like the
+// primary pool.
+//
+// Every read starts its own transaction, so a commit on the primary pool is
+// visible to the next read here in either journal mode (see wal.go), with
+// no shared cache.
+//
+// In WAL mode the hot reads (GetCredential, GetCredentialV2, GetMetadata,
+// ListCredentials, GetMany) don't take d.mu at all: they work from a
+// readState, the pool plus the field cipher, published atomically whenever
+// either changes and cleared by Relock. A read that races a rekey or relock
+// may see the new rows with the old state; any failure other than the
+// expected sentinels is therefore retried once under d.mu.RLock, which
+// waits for the change to finish. BenchmarkParallelReads compares this with
+// every read going through d.mu and the primary pool.
+//
+// With the rollback journal a writer locks readers out of the file anyway,
+// and readers spinning on SQLITE_BUSY are far slower than readers queued on
+// d.mu, so there reads keep taking d.mu.RLock.
+
+// A vault opened with OpenReadOnly (or WithReadOnly) is locked instead:
+// every write, including rotation, rekeying and Compact, fails with
+// ErrReadOnly before touching the file. It is meant for agent processes
+// that only fetch keys, so a compromised one can't alter or delete them.
+// Opening doesn't write either: a vault whose schema is out of date is
+// refused with ErrReadOnly until a read-write open migrates it, and
+// WithWAL is ignored unless the vault already uses WAL.
+
+// WithReadOnly opens the vault locked against writes. The vault must exist.
+func WithReadOnly() Option {
+	return func(o *options) { o.readOnly = true }
+}
+
+// OpenReadOnly is NewDatabase with WithReadOnly.
+func OpenReadOnly(path, password string, opts ...Option) (*Database, error) {
+	return NewDatabase(path, password, append(opts, WithReadOnly())...)
+}
+
+// ReadOnly reports whether d rejects writes.
+func (d *Database) ReadOnly() bool { return d.readOnly }
+
+// openReadOnlyDB opens a read-only pool on the vault at path. mode=ro is a
+// SQLite URI parameter, so the path is passed as an escaped file: URI.
+func openReadOnlyDB(path, password string, cs CipherSettings) *sql.DB {
+	uri := (&url.URL{Path: path}).EscapedPath()
+	return sql.OpenDB(newConnector(fmt.Sprintf("file:%s?mode=ro&_pragma_key=%s", uri, dsnKey(password)), cs))
+}
+
+// readState is everything a read needs without d.mu. It is never modified
+// after it's published in d.rd.
+type readState struct {
+	db   *sql.DB     // the read-only pool
+	aead cipher.AEAD // the field cipher the pool's rows are sealed with
+}
+
+// reader returns the pool read-only queries should use. Callers must hold
+// d.mu.
+func (d *Database) reader() *sql.DB {
+	return d.state().db
+}
+
+// state is the readState for callers holding d.mu: the published one, or
+// the primary pool if the read-only pool couldn't be opened.
+func (d *Database) state() *readState {
+	if rs := d.rd.Load(); rs != nil {
+		return rs
+	}
+	return &readState{db: d.db, aead: d.aead}
+}
+
+// openReader (re)opens the read-only pool with password, closing any
+// previous one, and publishes it with the current field cipher. Callers
+// must hold d.mu or own d exclusively.
+func (d *Database) openReader(password string) error {
+	d.closeReader()
+	ro := openReadOnlyDB(d.path, password, d.cipher)
+	if err := ro.Ping(); err != nil {
+		ro.Close()
+		return fmt.Errorf("read-only connection: %w", err)
+	}
+	d.rd.Store(&readState{db: ro, aead: d.aead})
+	return nil
+}
+
+// refreshReader republishes the read-only pool with the current field
+// cipher, after the field key changed without a new SQLCipher key.
+// Callers must hold d.mu.
+func (d *Database) refreshReader() {
+	if rs := d.rd.Load(); rs != nil {
+		d.rd.Store(&readState{db: rs.db, aead: d.aead})
+	}
+}
+
+// closeReader unpublishes and closes the read-only pool. sql.DB.Close lets
+// queries already running on it finish. Callers must hold d.mu.
+func (d *Database) closeReader() {
+	if rs := d.rd.Swap(nil); rs != nil {
+		rs.db.Close()
+	}
+}
+
+// read runs fn with the published readState, without d.mu, in WAL mode.
+// If there is none (the vault is locked) or fn fails with anything but an
+// expected outcome, fn runs again under d.mu.RLock, which reports ErrLocked
+// or waits out a concurrent rekey. d.wal is fixed once d is open.
+func (d *Database) read(ctx context.Context, fn func(*readState) error) error {
+	if rs := d.rd.Load(); rs != nil && d.wal {
+		d.lastUse.Store(time.Now().UnixNano())
+		err := fn(rs)
+		if err == nil || errors.Is(err, ErrNotFound) || errors.Is(err, ErrDisabled) ||
+			errors.Is(err, ErrPassphraseRequired) || ctx.Err() != nil {
+			return err
+		}
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return err
+	}
+	return fn(d.state())
+}
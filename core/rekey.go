@@ -0,0 +1,372 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// RekeyReport is the result of a dry-run password change.
+type RekeyReport struct {
+	Credentials int           // rows that would be re-encrypted
+	Blobs       int           // encrypted fields across those rows
+	Failed      []string      // credentials with a field that won't decrypt
+	FileSize    int64         // bytes SQLCipher would rewrite
+	Estimate    time.Duration // rough wall time for the rekey
+}
+
+// OK reports whether every blob decrypted.
+func (r *RekeyReport) OK() bool { return len(r.Failed) == 0 }
+
+// ChangeMasterPassword re-keys the vault from old to new. The SQLCipher key
+// is changed with PRAGMA rekey, and the field key is re-derived from new
+// with a fresh salt; every secret and public key blob is re-encrypted.
+//
+// The blob updates, new salt and integrity digest are written on one
+// connection inside the transaction that PRAGMA rekey commits, so the file
+// is either entirely old or entirely new. Every blob is decrypted before
+// anything is written; an undecryptable one aborts with ErrDecryptFail.
+// A wrong old password returns ErrWrongPassword. Any characters are allowed
+// in new; SQLCipher is keyed with it verbatim (see dsnKey), which also
+// moves a vault off a legacy mangled key (legacyDSNKey).
+func (d *Database) ChangeMasterPassword(old, new string) error {
+	if new == "" {
+		return fmt.Errorf("new password cannot be empty")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return err
+	}
+
+	if err := d.checkPassword(old); err != nil {
+		return err
+	}
+	if err := d.reencrypt(new, d.kdf, true); err != nil {
+		return err
+	}
+	d.log.Info("master password changed", "path", d.path)
+	return nil
+}
+
+// UpgradeKDFParams is UpgradeKDF to new Argon2id parameters, e.g. to
+// bring an old vault up to DefaultArgon2Params.
+func (d *Database) UpgradeKDFParams(password string, newParams Argon2Params) error {
+	return d.UpgradeKDF(password, newParams)
+}
+
+// UpgradeKDF re-derives the field key from password under k (new Argon2id
+// parameters, or another KDF altogether) and a fresh salt, re-encrypts
+// every blob, and stores k, all in one transaction. The SQLCipher key is
+// unchanged. A wrong password returns ErrWrongPassword.
+func (d *Database) UpgradeKDF(password string, k KeyDeriver) error {
+	if err := validateKDF(k); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return err
+	}
+
+	if err := d.checkPassword(password); err != nil {
+		return err
+	}
+	if err := d.reencrypt(password, k, false); err != nil {
+		return err
+	}
+	d.log.Info("kdf upgraded", "path", d.path, "kdf", k.ID())
+	return nil
+}
+
+// reencrypt moves every blob to a key derived from password under k with a
+// fresh salt, storing the salt and k alongside. With rekey, the SQLCipher
+// key changes to password too and the connection pool is reopened.
+// Callers must hold d.mu and have checked the current password.
+func (d *Database) reencrypt(password string, k KeyDeriver, rekey bool) error {
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	rows, err := d.decryptAll()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, r := range rows {
+			zero(r.secret)
+			zero(r.public)
+		}
+	}()
+	for _, r := range rows {
+		if r.err != nil {
+			return fmt.Errorf("%s: %w", r.name, r.err)
+		}
+	}
+	sealed, err := d.sealedRotationMeta()
+	if err != nil {
+		return err
+	}
+	attestSeed, err := d.attestSeed(false)
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	oldKey, oldMAC, oldAEAD, oldLocked := d.key, d.macKey, d.aead, d.memLocked
+	if err := d.setKey(k.Derive(password, salt)); err != nil {
+		return err
+	}
+	restore := true
+	defer func() {
+		if restore {
+			releaseKeys(d.key, d.macKey, d.memLocked)
+			d.key, d.macKey, d.aead, d.memLocked = oldKey, oldMAC, oldAEAD, oldLocked
+		}
+	}()
+
+	ctx := context.Background()
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, r := range rows {
+		secret := []byte{}
+		if r.hasSecret {
+			if secret, err = d.encrypt(r.secret); err != nil {
+				return err
+			}
+		}
+		var public []byte
+		if r.hasPublic {
+			if public, err = d.encrypt(r.public); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(`UPDATE credentials SET api_key = ?, public_key = ? WHERE id = ?`, secret, public, r.id); err != nil {
+			return err
+		}
+	}
+	for id, meta := range sealed {
+		enc, err := d.encodeRotationMeta(meta)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE rotations SET metadata = ? WHERE id = ?`, enc, id); err != nil {
+			return err
+		}
+	}
+	if attestSeed != nil {
+		if err := d.storeAttestSeed(tx, attestSeed); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO config (key, value) VALUES ('salt', ?)`, salt); err != nil {
+		return err
+	}
+	if err := storeKDF(tx, k); err != nil {
+		return err
+	}
+	if err := d.updateIntegrity(tx); err != nil {
+		return err
+	}
+	if rekey {
+		// rekey rewrites every page and commits the open transaction with it.
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA rekey = '%s'`, strings.ReplaceAll(password, "'", "''"))); err != nil {
+			return fmt.Errorf("rekey: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	restore = false
+	d.kdf = k
+	releaseKeys(oldKey, oldMAC, oldLocked)
+	d.refreshReader()
+	conn.Close()
+	if !rekey {
+		return nil
+	}
+
+	// Pooled connections still hold the old key; start a fresh pool.
+	d.db.Close()
+	d.db = openDB(d.path, password, d.cipher)
+	if err := d.db.Ping(); err != nil {
+		return fmt.Errorf("password changed, but reopening failed: %w", err)
+	}
+	if err := d.openReader(password); err != nil {
+		return fmt.Errorf("password changed, but reopening failed: %w", err)
+	}
+	return nil
+}
+
+// CheckRekey is a dry run of ChangeMasterPassword: it verifies old, tries
+// to decrypt every blob, and estimates how long the rekey would take.
+// Nothing is written.
+func (d *Database) CheckRekey(old string) (*RekeyReport, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	if err := d.checkPassword(old); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	rows, err := d.decryptAll()
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &RekeyReport{Credentials: len(rows)}
+	for _, r := range rows {
+		if r.hasSecret {
+			rep.Blobs++
+		}
+		if r.hasPublic {
+			rep.Blobs++
+		}
+		if r.err != nil {
+			rep.Failed = append(rep.Failed, r.name)
+		}
+	}
+
+	// quick_check reads every page once; rekey reads and rewrites each, so
+	// double it and add the blob pass.
+	scan := time.Now()
+	if _, err := d.db.Exec(`PRAGMA quick_check`); err != nil {
+		return nil, fmt.Errorf("quick_check: %w", err)
+	}
+	rep.Estimate = 2*time.Since(scan) + scan.Sub(start)
+	if fi, err := os.Stat(d.path); err == nil {
+		rep.FileSize = fi.Size()
+	}
+	return rep, nil
+}
+
+// checkPassword verifies password against the key the vault was opened
+// with. Callers must hold d.mu.
+func (d *Database) checkPassword(password string) error {
+	salt, err := loadOrCreateSalt(d.db)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(d.kdf.Derive(password, salt), d.key) != 1 {
+		d.log.Warn("wrong master password", "path", d.path)
+		return ErrWrongPassword
+	}
+	return nil
+}
+
+// plainRow is one credential's decrypted blobs.
+type plainRow struct {
+	id, name             string
+	secret, public       []byte
+	hasSecret, hasPublic bool
+	err                  error
+}
+
+// decryptAll decrypts every blob with the current key. Per-row decrypt
+// failures are recorded in plainRow.err rather than returned. Callers must
+// hold d.mu.
+func (d *Database) decryptAll() ([]plainRow, error) {
+	rows, err := d.db.Query(`SELECT id, name, api_key, public_key FROM credentials ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []plainRow
+	for rows.Next() {
+		var r plainRow
+		var secretBlob, publicBlob []byte
+		if err := rows.Scan(&r.id, &r.name, &secretBlob, &publicBlob); err != nil {
+			return nil, err
+		}
+		r.hasSecret, r.hasPublic = len(secretBlob) > 0, len(publicBlob) > 0
+		if r.hasSecret {
+			r.secret, r.err = d.decrypt(secretBlob)
+		}
+		if r.hasPublic && r.err == nil {
+			r.public, r.err = d.decrypt(publicBlob)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// RotateFieldKey re-derives the field key from password with a fresh salt
+// and re-encrypts every blob, keeping the current Argon2id parameters and
+// the SQLCipher key. Blobs left behind in free pages stay under the old
+// key; Compact afterwards drops them.
+func (d *Database) RotateFieldKey(password string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return err
+	}
+
+	if err := d.checkPassword(password); err != nil {
+		return err
+	}
+	return d.reencrypt(password, d.kdf, false)
+}
+
+// CompactStats reports the vault file size around a Compact.
+type CompactStats struct {
+	Before, After int64
+}
+
+// Reclaimed is how many bytes Compact freed.
+func (s CompactStats) Reclaimed() int64 { return s.Before - s.After }
+
+// Compact rebuilds the vault file with VACUUM. Deleted rows leave their
+// (encrypted) bytes in free pages until then; VACUUM writes a fresh file
+// without them and returns the space.
+func (d *Database) Compact() (CompactStats, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return CompactStats{}, err
+	}
+
+	var s CompactStats
+	if d.readOnly {
+		return s, ErrReadOnly
+	}
+	fi, err := os.Stat(d.path)
+	if err != nil {
+		return s, err
+	}
+	s.Before = fi.Size()
+	if _, err := d.db.Exec(`VACUUM`); err != nil {
+		return s, fmt.Errorf("vacuum: %w", err)
+	}
+	if fi, err = os.Stat(d.path); err != nil {
+		return s, err
+	}
+	s.After = fi.Size()
+	return s, nil
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// RenameCredential renames oldName to newName in one transaction, carrying
+// its rotation history, tags and access log along. created_at,
+// updated_at and last_rotated are left as they were. It returns
+// ErrNotFound if oldName doesn't exist, ErrDuplicate if newName does, and
+// ErrRotationInProgress while another handle holds oldName's rotation lock.
+func (d *Database) RenameCredential(oldName, newName string) error {
+	if newName == "" {
+		return errors.New("new name is required")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return err
+	}
+
+	return d.writeTx(func(tx *sql.Tx) error {
+		if err := d.checkRotationLock(tx, oldName); err != nil {
+			return err
+		}
+		res, err := tx.Exec(`UPDATE credentials SET name = ? WHERE name = ?`, newName, oldName)
+		if err != nil && isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrNotFound
+		}
+
+		// Foreign keys aren't enforced on the connection, so every table
+		// keyed by the name follows by hand.
+		for _, table := range []string{"rotations", "credential_tags", "access_log"} {
+			if _, err := tx.Exec(`UPDATE `+table+` SET credential_name = ? WHERE credential_name = ?`, newName, oldName); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
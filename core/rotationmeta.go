@@ -0,0 +1,170 @@
+package core
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sealMetaConfigKey is the config row recording whether rotation metadata
+// is written encrypted. Absent means off, the pre-existing behaviour.
+const sealMetaConfigKey = "encrypt_rotation_metadata"
+
+// sealedMetaPrefix marks a rotations.metadata value holding
+// base64(d.encrypt(json)) rather than plain JSON. Values are tagged per
+// row, so history written before the setting changed still reads back.
+const sealedMetaPrefix = "enc:"
+
+// RotationMetadataEncrypted reports whether new rotation records store
+// their metadata under the field key.
+func (d *Database) RotationMetadataEncrypted() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.sealMeta
+}
+
+// SetRotationMetadataEncryption turns the field-key layer for rotation
+// metadata on or off for this vault. Existing records are converted in the
+// same transaction, so the whole history follows the setting. SQLCipher
+// already encrypts the file; this is defense in depth for plugins that put
+// sensitive context (internal URLs, partial key IDs) in metadata.
+func (d *Database) SetRotationMetadataEncryption(on bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return err
+	}
+
+	prev := d.sealMeta
+	d.sealMeta = on
+	err := d.writeTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`SELECT id, metadata FROM rotations WHERE metadata IS NOT NULL`)
+		if err != nil {
+			return err
+		}
+		type rec struct {
+			id   string
+			meta map[string]string
+		}
+		var recs []rec
+		for rows.Next() {
+			var r rec
+			var raw string
+			if err := rows.Scan(&r.id, &raw); err != nil {
+				rows.Close()
+				return err
+			}
+			if r.meta, err = d.decodeRotationMeta(raw); err != nil {
+				rows.Close()
+				return fmt.Errorf("rotation %s: %w", r.id, err)
+			}
+			recs = append(recs, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, r := range recs {
+			enc, err := d.encodeRotationMeta(r.meta)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`UPDATE rotations SET metadata = ? WHERE id = ?`, enc, r.id); err != nil {
+				return err
+			}
+		}
+		return storeSealMeta(tx, on)
+	})
+	if err != nil {
+		d.sealMeta = prev
+	}
+	return err
+}
+
+// encodeRotationMeta renders meta for the rotations.metadata column:
+// nil for an empty map, sealed JSON when d.sealMeta is set, plain JSON
+// otherwise. Callers must hold d.mu.
+func (d *Database) encodeRotationMeta(meta map[string]string) (*string, error) {
+	if len(meta) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	s := string(b)
+	if d.sealMeta {
+		blob, err := d.encrypt(b)
+		if err != nil {
+			return nil, err
+		}
+		s = sealedMetaPrefix + base64.StdEncoding.EncodeToString(blob)
+	}
+	return &s, nil
+}
+
+// decodeRotationMeta is the inverse of encodeRotationMeta. Plain JSON that
+// doesn't parse is ignored, as it always has been; a sealed value that
+// doesn't decrypt is an error.
+func (d *Database) decodeRotationMeta(raw string) (map[string]string, error) {
+	b := []byte(raw)
+	if sealed, ok := strings.CutPrefix(raw, sealedMetaPrefix); ok {
+		blob, err := base64.StdEncoding.DecodeString(sealed)
+		if err != nil {
+			return nil, ErrDecryptFail
+		}
+		if b, err = d.decrypt(blob); err != nil {
+			return nil, err
+		}
+	}
+	meta := make(map[string]string)
+	json.Unmarshal(b, &meta)
+	return meta, nil
+}
+
+// sealedRotationMeta decrypts every sealed rotation metadata value with the
+// current key, keyed by rotation id, so a re-key can seal them again.
+// Callers must hold d.mu.
+func (d *Database) sealedRotationMeta() (map[string]map[string]string, error) {
+	rows, err := d.db.Query(`SELECT id, metadata FROM rotations WHERE metadata LIKE ?`, sealedMetaPrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]map[string]string)
+	for rows.Next() {
+		var id, raw string
+		if err := rows.Scan(&id, &raw); err != nil {
+			return nil, err
+		}
+		if out[id], err = d.decodeRotationMeta(raw); err != nil {
+			return nil, fmt.Errorf("rotation %s metadata: %w", id, err)
+		}
+	}
+	return out, rows.Err()
+}
+
+func loadSealMeta(q dbtx) (bool, error) {
+	var v []byte
+	err := q.QueryRow(`SELECT value FROM config WHERE key = ?`, sealMetaConfigKey).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return string(v) == "1", err
+}
+
+func storeSealMeta(q dbtx, on bool) error {
+	v := "0"
+	if on {
+		v = "1"
+	}
+	_, err := q.Exec(`INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)`, sealMetaConfigKey, []byte(v))
+	return err
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// NextRotation returns when c is due for rotation: its interval after the
+// last rotation, or after creation if it was never rotated. ok is false
+// when c has no RotationInterval.
+func (c *Credential) NextRotation() (due time.Time, ok bool) {
+	if c.RotationInterval == nil {
+		return time.Time{}, false
+	}
+	base := c.CreatedAt
+	if c.LastRotated != nil {
+		base = *c.LastRotated
+	}
+	return base.Add(*c.RotationInterval), true
+}
+
+// RotationDue reports whether c has a rotation interval and now is at or
+// past its NextRotation.
+func (c *Credential) RotationDue(now time.Time) bool {
+	due, ok := c.NextRotation()
+	return ok && !now.Before(due)
+}
+
+// ListDueForRotation returns the credentials whose rotation interval has
+// run out by now, counting from last_rotated or, for credentials never
+// rotated, created_at. Disabled credentials are left out, since they can't
+// be rotated. No secrets are included.
+func (d *Database) ListDueForRotation(now time.Time) ([]Credential, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	return d.listWhere(context.Background(), `WHERE rotation_interval IS NOT NULL AND disabled = 0
+		AND COALESCE(last_rotated, created_at) + rotation_interval <= ?`, now.Unix())
+}
+
+// intervalSeconds encodes a rotation interval for the rotation_interval
+// column, in whole seconds.
+func intervalSeconds(d *time.Duration) *int64 {
+	if d == nil {
+		return nil
+	}
+	s := int64(*d / time.Second)
+	return &s
+}
+
+func intervalPtr(v sql.NullInt64) *time.Duration {
+	if !v.Valid {
+		return nil
+	}
+	d := time.Duration(v.Int64) * time.Second
+	return &d
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// TableSchema describes one table as stored on disk.
+type TableSchema struct {
+	Name    string
+	SQL     string   // CREATE TABLE statement
+	Indexes []string // CREATE INDEX statements
+	Columns []ColumnInfo
+}
+
+// ColumnInfo is one row of PRAGMA table_info.
+type ColumnInfo struct {
+	Name    string
+	Type    string
+	NotNull bool
+	Default *string
+	PK      bool
+}
+
+// schemaTables are the tables Schema reports, in order.
+var schemaTables = []string{"credentials", "rotations"}
+
+// Schema returns the on-disk layout of the credential tables, read from the
+// decrypted connection. It contains no credential data.
+func (d *Database) Schema() ([]TableSchema, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	var out []TableSchema
+	for _, name := range schemaTables {
+		t := TableSchema{Name: name}
+		err := d.db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&t.SQL)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		rows, err := d.db.Query(`SELECT sql FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL ORDER BY name`, name)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var s string
+			if err := rows.Scan(&s); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			t.Indexes = append(t.Indexes, s)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		if t.Columns, err = tableInfo(d.db, name); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+func tableInfo(db *sql.DB, table string) ([]ColumnInfo, error) {
+	rows, err := db.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []ColumnInfo
+	for rows.Next() {
+		var cid int
+		var c ColumnInfo
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &c.Name, &c.Type, &c.NotNull, &dflt, &c.PK); err != nil {
+			return nil, err
+		}
+		if dflt.Valid {
+			c.Default = &dflt.String
+		}
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ScryptParams are scrypt cost parameters for the field key, an
+// alternative to Argon2id where its memory cost is too much per open.
+// Memory use is about 128 * N * R bytes.
+type ScryptParams struct {
+	N      int
+	R      int
+	P      int
+	KeyLen int
+}
+
+// DefaultScryptParams returns scrypt parameters for interactive use:
+// N=2^15, r=8, p=1, about 32 MiB per derivation.
+func DefaultScryptParams() ScryptParams {
+	return ScryptParams{N: 1 << 15, R: 8, P: 1, KeyLen: argonKeyLen}
+}
+
+// ID implements KeyDeriver.
+func (p ScryptParams) ID() string { return KDFScrypt }
+
+// Derive implements KeyDeriver. p must be valid.
+func (p ScryptParams) Derive(password string, salt []byte) []byte {
+	k, err := scrypt.Key([]byte(password), salt, p.N, p.R, p.P, p.KeyLen)
+	if err != nil {
+		panic("scrypt: " + err.Error()) // unreachable after Validate
+	}
+	return k
+}
+
+// Validate bounds the parameters as Argon2Params.Validate does: a 32-byte
+// key, and a memory cost between 1 MiB and 4 GiB.
+func (p ScryptParams) Validate() error {
+	switch {
+	case p.KeyLen != argonKeyLen:
+		return fmt.Errorf("scrypt key length must be %d", argonKeyLen)
+	case p.N < 2 || p.N&(p.N-1) != 0:
+		return fmt.Errorf("scrypt N must be a power of two, got %d", p.N)
+	case p.R < 1 || p.P < 1 || p.R*p.P >= 1<<30:
+		return fmt.Errorf("scrypt r and p must be at least 1 with r*p < 2^30, got %d and %d", p.R, p.P)
+	case 128*int64(p.N)*int64(p.R) < 1<<20 || 128*int64(p.N)*int64(p.R) > 4<<30:
+		return fmt.Errorf("scrypt memory (128*N*r) must be 1 MiB-4 GiB, got %d bytes", 128*int64(p.N)*int64(p.R))
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// SearchOptions narrows Search. With none of the field flags set, every
+// field is searched.
+type SearchOptions struct {
+	Name, APIType, Environment, Metadata bool
+
+	// CaseSensitive matches case exactly. Otherwise matching ignores case
+	// for ASCII letters only, as SQLite's LIKE does.
+	CaseSensitive bool
+}
+
+// Search returns the credentials where query appears as a substring of any
+// selected field, in name order. query is literal: wildcards such as % and _
+// match only themselves. No secrets are included.
+func (d *Database) Search(query string, opts SearchOptions) ([]Credential, error) {
+	if query == "" {
+		return nil, errors.New("search query is empty")
+	}
+	cols := searchColumns(opts)
+
+	// LIKE folds ASCII case and GLOB doesn't; each mode uses the one that
+	// matches it.
+	cond, pattern := `%s LIKE ? ESCAPE '\'`, "%"+likeEscape(query)+"%"
+	if opts.CaseSensitive {
+		cond, pattern = "%s GLOB ?", "*"+globEscape(query)+"*"
+	}
+	where := make([]string, len(cols))
+	args := make([]any, len(cols))
+	for i, c := range cols {
+		where[i] = fmt.Sprintf(cond, c)
+		args[i] = pattern
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	return d.listWhere(context.Background(), "WHERE "+strings.Join(where, " OR "), args...)
+}
+
+func searchColumns(opts SearchOptions) []string {
+	var cols []string
+	for _, f := range []struct {
+		on  bool
+		col string
+	}{
+		{opts.Name, "name"},
+		{opts.APIType, "api_type"},
+		{opts.Environment, "environment"},
+		{opts.Metadata, "metadata"},
+	} {
+		if f.on {
+			cols = append(cols, f.col)
+		}
+	}
+	if len(cols) == 0 {
+		cols = []string{"name", "api_type", "environment", "metadata"}
+	}
+	return cols
+}
+
+// SearchNotes returns the credentials whose metadata contains every word of
+// term, matched whole and case-insensitively, in name order. No secrets are
+// included.
+//
+// Notes are stored as plain text inside the SQLCipher-encrypted file; only
+// secrets get the second, field-key layer. Anyone who can open the file can
+// read them, so there is nothing for a blind index to protect and this is
+// a plain scan: a LIKE prefilter per word, then whole-word matching.
+func (d *Database) SearchNotes(term string) ([]Credential, error) {
+	words := noteWords(term)
+	if len(words) == 0 {
+		return nil, errors.New("search term has no words")
+	}
+	where := make([]string, len(words))
+	args := make([]any, len(words))
+	for i, w := range words {
+		where[i] = `metadata LIKE ? ESCAPE '\'`
+		args[i] = "%" + likeEscape(w) + "%"
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	creds, err := d.listWhere(context.Background(), "WHERE "+strings.Join(where, " AND "), args...)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(creds, func(c Credential) bool {
+		have := noteWords(c.Metadata)
+		for _, w := range words {
+			if _, ok := slices.BinarySearch(have, w); !ok {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// noteWords splits s into distinct, sorted, lowercased words of letters and
+// digits.
+func noteWords(s string) []string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	slices.Sort(words)
+	return slices.Compact(words)
+}
+
+var (
+	likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	globEscaper = strings.NewReplacer(`[`, `[[]`, `*`, `[*]`, `?`, `[?]`)
+)
+
+// likeEscape makes s literal inside a LIKE pattern using ESCAPE '\'.
+func likeEscape(s string) string { return likeEscaper.Replace(s) }
+
+// globEscape makes s literal inside a GLOB pattern.
+func globEscape(s string) string { return globEscaper.Replace(s) }
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// SecretKind classifies a secret's format so it can be labelled and handled
+// appropriately. The detected kind is stored in Config under
+// SecretKindConfigKey when a credential is added.
+type SecretKind string
+
+const (
+	SecretOpaque         SecretKind = "opaque"          // API key, token, password
+	SecretJSON           SecretKind = "json"            // any other JSON document
+	SecretServiceAccount SecretKind = "service_account" // GCP-style service account JSON
+	SecretPEM            SecretKind = "pem"             // PEM block: private key, certificate
+	SecretJWT            SecretKind = "jwt"             // signed JSON Web Token
+)
+
+// SecretKindConfigKey is the Config entry holding a credential's SecretKind.
+const SecretKindConfigKey = "secret_kind"
+
+// Describe returns a short human label, e.g. "service account JSON". Opaque
+// secrets have none.
+func (k SecretKind) Describe() string {
+	switch k {
+	case SecretJSON:
+		return "JSON"
+	case SecretServiceAccount:
+		return "service account JSON"
+	case SecretPEM:
+		return "PEM"
+	case SecretJWT:
+		return "JWT"
+	}
+	return ""
+}
+
+// DetectSecretType classifies value by its shape. It never fails: anything
+// unrecognized is SecretOpaque.
+func DetectSecretType(value string) SecretKind {
+	v := strings.TrimSpace(value)
+	switch {
+	case strings.HasPrefix(v, "-----BEGIN ") && strings.Contains(v, "-----END "):
+		return SecretPEM
+	case strings.HasPrefix(v, "{"):
+		var obj map[string]any
+		if json.Unmarshal([]byte(v), &obj) != nil {
+			return SecretOpaque
+		}
+		if obj["type"] == "service_account" {
+			return SecretServiceAccount
+		}
+		return SecretJSON
+	case isJWT(v):
+		return SecretJWT
+	}
+	return SecretOpaque
+}
+
+// isJWT reports whether v is three base64url segments whose header decodes
+// to a JSON object naming an algorithm.
+func isJWT(v string) bool {
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 || parts[1] == "" {
+		return false
+	}
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+	var h struct {
+		Alg string `json:"alg"`
+	}
+	return json.Unmarshal(header, &h) == nil && h.Alg != ""
+}
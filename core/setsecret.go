@@ -0,0 +1,95 @@
+package core
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// SecretUpdate is one entry for SetSecrets.
+type SecretUpdate struct {
+	Name   string
+	Secret string
+}
+
+// SetSecret replaces the secret of an existing credential. Metadata, config,
+// key ID and rotation history are left alone; only secret_kind is
+// re-detected. Passphrase-protected credentials are refused with
+// ErrPassphraseRequired, since a plain write would strip the passphrase.
+func (d *Database) SetSecret(name, secret string) error {
+	errs, err := d.SetSecrets([]SecretUpdate{{Name: name, Secret: secret}})
+	if err != nil {
+		return err
+	}
+	return errs[0]
+}
+
+// SetSecrets applies SetSecret to each update in a single transaction.
+// errs[i] says why update i was skipped (nil if it was applied); the
+// applied ones commit together. err is set only when the transaction
+// itself fails, in which case nothing is written.
+func (d *Database) SetSecrets(updates []SecretUpdate) (errs []error, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	now := d.now().Unix()
+	err = d.writeTx(func(tx *sql.Tx) error {
+		errs = make([]error, len(updates))
+		for i, u := range updates {
+			var err error
+			if errs[i], err = d.setSecret(tx, u, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
+
+// setSecret applies one update. skip rejects just this update; err means
+// the transaction is unusable.
+func (d *Database) setSecret(tx *sql.Tx, u SecretUpdate, now int64) (skip, err error) {
+	if u.Secret == "" {
+		return errors.New("secret cannot be empty"), nil
+	}
+	if err := d.checkSize(u.Secret); err != nil {
+		return err, nil
+	}
+
+	var protected bool
+	var cfgJSON sql.NullString
+	err = tx.QueryRow(`SELECT extra_salt IS NOT NULL, config FROM credentials WHERE name = ?`, u.Name).Scan(&protected, &cfgJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if protected {
+		return ErrPassphraseRequired, nil
+	}
+
+	cfg := make(map[string]string)
+	if cfgJSON.Valid {
+		if err := json.Unmarshal([]byte(cfgJSON.String), &cfg); err != nil {
+			return nil, err
+		}
+	}
+	cfg[SecretKindConfigKey] = string(DetectSecretType(u.Secret))
+	b, _ := json.Marshal(cfg)
+
+	blob, err := d.encrypt([]byte(u.Secret))
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`UPDATE credentials SET api_key = ?, config = ?, updated_at = ? WHERE name = ?`,
+		blob, string(b), now, u.Name)
+	return nil, err
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TypeCount is one api_type and how many credentials have it.
+type TypeCount struct {
+	APIType string
+	Count   int
+}
+
+// VaultStats summarizes the vault for monitoring.
+type VaultStats struct {
+	Total         int
+	ByType        []TypeCount        // by api_type name; "" for untyped
+	ByEnvironment []EnvironmentCount // by name; "" for no environment
+	Oldest        *time.Time         // earliest created_at; nil for an empty vault
+	Newest        *time.Time         // latest created_at
+	Rotated       int                // credentials rotated at least once
+}
+
+// Stats counts the vault's credentials with a few aggregate queries.
+// Nothing is decrypted and no rows are loaded.
+func (d *Database) Stats() (VaultStats, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return VaultStats{}, err
+	}
+
+	var s VaultStats
+	var oldest, newest sql.NullInt64
+	err := d.reader().QueryRow(
+		`SELECT COUNT(*), MIN(created_at), MAX(created_at), COUNT(last_rotated) FROM credentials`,
+	).Scan(&s.Total, &oldest, &newest, &s.Rotated)
+	if err != nil {
+		return VaultStats{}, err
+	}
+	s.Oldest, s.Newest = unixPtr(oldest), unixPtr(newest)
+
+	groups := func(col string, add func(string, int)) error {
+		rows, err := d.reader().Query(
+			`SELECT COALESCE(` + col + `, ''), COUNT(*) FROM credentials GROUP BY 1 ORDER BY 1`,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var key string
+			var n int
+			if err := rows.Scan(&key, &n); err != nil {
+				return err
+			}
+			add(key, n)
+		}
+		return rows.Err()
+	}
+	if err := groups("api_type", func(k string, n int) { s.ByType = append(s.ByType, TypeCount{k, n}) }); err != nil {
+		return VaultStats{}, err
+	}
+	if err := groups("environment", func(k string, n int) { s.ByEnvironment = append(s.ByEnvironment, EnvironmentCount{k, n}) }); err != nil {
+		return VaultStats{}, err
+	}
+	return s, nil
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	_ "embed"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// StrongPasswordScore is the lowest EstimatePasswordStrength score that
+// needs no warning.
+const StrongPasswordScore = 3
+
+//go:embed commonpasswords.txt
+var commonPasswordList string
+
+var commonPasswords = func() map[string]bool {
+	m := make(map[string]bool)
+	for _, p := range strings.Fields(commonPasswordList) {
+		m[p] = true
+	}
+	return m
+}()
+
+// EstimatePasswordStrength scores a master password from 0 (trivially
+// guessable) to 4. Length counts most: a point each at 8, 12 and 16
+// characters, plus one for mixing at least three of lower case, upper case,
+// digits and symbols, minus one for a short password of a single kind of
+// character. Common passwords, and common ones with digits or symbols
+// tacked on, score 0 or 1, as do passwords that are mostly one repeated
+// character. Each reason the score is held down comes back as a short
+// warning, e.g. "shorter than 12 characters". It is a heuristic meant for a
+// nudge at init, not a guarantee.
+func EstimatePasswordStrength(pw string) (score int, warnings []string) {
+	n := utf8.RuneCountInString(pw)
+	for _, at := range []int{8, 12, 16} {
+		if n >= at {
+			score++
+		}
+	}
+	switch {
+	case n < 8:
+		warnings = append(warnings, "shorter than 8 characters")
+	case n < 12:
+		warnings = append(warnings, "shorter than 12 characters")
+	}
+
+	var lower, upper, digit, symbol bool
+	counts := make(map[rune]int)
+	for _, r := range pw {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+		counts[r]++
+	}
+	classes := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			classes++
+		}
+	}
+	if classes >= 3 {
+		score++
+	} else if classes == 1 && n < 20 {
+		warnings = append(warnings, "uses only one kind of character")
+		score = max(score-1, 0)
+	}
+
+	for _, c := range counts {
+		if n > 0 && c*2 > n {
+			warnings = append(warnings, "mostly one repeated character")
+			score = min(score, 1)
+			break
+		}
+	}
+
+	folded := strings.ToLower(pw)
+	stem := strings.TrimRightFunc(folded, func(r rune) bool { return !unicode.IsLetter(r) })
+	switch {
+	case commonPasswords[folded]:
+		warnings = append(warnings, "one of the most common passwords")
+		score = 0
+	case stem != folded && commonPasswords[stem]:
+		warnings = append(warnings, "a common password with a few characters added")
+		score = min(score, 1)
+	}
+
+	return min(score, 4), warnings
+}
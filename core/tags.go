@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Tags live in credential_tags, one row per credential and tag. tag keeps
+// the spelling it was added with; tag_key is its lowercase form, used for
+// lookups and to keep a credential from holding the same tag twice.
+
+// maxTagLen bounds a single tag.
+const maxTagLen = 64
+
+// tagsColumn selects a credential's tags as one string, joined with tagSep,
+// for list and get queries on the credentials table.
+const tagsColumn = `(SELECT group_concat(tag, char(31)) FROM credential_tags WHERE credential_name = credentials.name)`
+
+const tagSep = "\x1f"
+
+// ValidateTag reports whether tag can be stored: non-empty, at most 64
+// characters, and free of whitespace, commas and control characters.
+func ValidateTag(tag string) error {
+	if tag == "" {
+		return fmt.Errorf("tag cannot be empty")
+	}
+	if len(tag) > maxTagLen {
+		return fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLen)
+	}
+	if strings.ContainsFunc(tag, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) || r == ',' }) {
+		return fmt.Errorf("tag %q contains whitespace or a comma", tag)
+	}
+	return nil
+}
+
+// ListByTag returns the credentials carrying every one of tags, compared
+// case-insensitively, in name order. No secrets are included.
+func (d *Database) ListByTag(tags ...string) ([]Credential, error) {
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("at least one tag is required")
+	}
+	keys := make([]any, 0, len(tags)+1)
+	for _, t := range tags {
+		k := strings.ToLower(t)
+		if !slices.Contains(keys, any(k)) {
+			keys = append(keys, k)
+		}
+	}
+	n := len(keys)
+	keys = append(keys, n)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	return d.listWhere(context.Background(), fmt.Sprintf(
+		`WHERE name IN (SELECT credential_name FROM credential_tags WHERE tag_key IN (?%s)
+		 GROUP BY credential_name HAVING COUNT(*) = ?)`,
+		strings.Repeat(", ?", n-1)), keys...)
+}
+
+// storeTags replaces name's tags with tags, keeping the first spelling of
+// any repeated tag. Callers must hold d.mu.
+func storeTags(q dbtx, name string, tags []string) error {
+	if _, err := q.Exec(`DELETE FROM credential_tags WHERE credential_name = ?`, name); err != nil {
+		return err
+	}
+	for _, t := range tags {
+		if err := ValidateTag(t); err != nil {
+			return err
+		}
+		if _, err := q.Exec(`INSERT OR IGNORE INTO credential_tags (credential_name, tag, tag_key) VALUES (?, ?, ?)`,
+			name, t, strings.ToLower(t)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitTags parses a tagsColumn value into sorted tags.
+func splitTags(joined string) []string {
+	if joined == "" {
+		return nil
+	}
+	tags := strings.Split(joined, tagSep)
+	slices.SortFunc(tags, func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	})
+	return tags
+}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// CredentialPatch lists the fields UpdateCredentialV2 changes; nil leaves a
+// field as it is. A pointer to "" clears an optional field (environment,
+// URL, key ID, public key, metadata), ExpiresAt pointing at the zero
+// time removes the expiry, and RotationInterval pointing at 0 removes the
+// rotation schedule. URLs and Tags, when non-nil, replace the whole list,
+// and Config the whole map (empty removes it); merge into the stored
+// Config to change one entry.
+type CredentialPatch struct {
+	APIType     *string
+	Metadata    *string
+	Environment *string
+	URL         *string
+	URLs        []string
+	KeyID       *string
+	PublicKey   *string
+	SecretKey   *string
+	ExpiresAt   *time.Time
+	Tags        []string
+	Config      map[string]string
+
+	RotationInterval *time.Duration
+}
+
+// UpdateCredentialV2 applies patch to the named credential in place,
+// keeping its created_at, rotation history and disabled flag, and bumps
+// updated_at. Keys are re-encrypted only when patch provides them. A new
+// secret for a passphrase-protected credential is refused with
+// ErrPassphraseRequired, as in SetSecret.
+func (d *Database) UpdateCredentialV2(name string, patch *CredentialPatch) error {
+	return d.UpdateCredentialV2Context(context.Background(), name, patch)
+}
+
+// UpdateCredentialV2Context is UpdateCredentialV2 bound to ctx.
+func (d *Database) UpdateCredentialV2Context(ctx context.Context, name string, patch *CredentialPatch) error {
+	if patch.SecretKey != nil && *patch.SecretKey == "" {
+		return errors.New("secret cannot be empty")
+	}
+	for _, t := range patch.Tags {
+		if err := ValidateTag(t); err != nil {
+			return err
+		}
+	}
+	if r := patch.RotationInterval; r != nil && *r != 0 && *r < time.Second {
+		return errors.New("rotation interval must be at least a second")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return err
+	}
+
+	now := d.now().Unix()
+	return d.writeTxContext(ctx, func(tx *sql.Tx) error {
+		set := []string{"updated_at = ?"}
+		args := []any{now}
+		field := func(col string, v any) {
+			set = append(set, col+" = ?")
+			args = append(args, v)
+		}
+
+		if patch.APIType != nil {
+			field("api_type", *patch.APIType)
+		}
+		if patch.Metadata != nil {
+			field("metadata", nullIfEmpty(*patch.Metadata))
+		}
+		if patch.Environment != nil {
+			field("environment", nullIfEmpty(*patch.Environment))
+		}
+		if patch.KeyID != nil {
+			field("key_id", nullIfEmpty(*patch.KeyID))
+		}
+		switch {
+		case len(patch.URLs) > 0:
+			field("url", patch.URLs[0])
+			field("urls", marshalURLs(patch.URLs))
+		case patch.URLs != nil || patch.URL != nil && *patch.URL == "":
+			field("url", nil)
+			field("urls", nil)
+		case patch.URL != nil:
+			field("url", *patch.URL)
+			field("urls", marshalURLs([]string{*patch.URL}))
+		}
+		if patch.PublicKey != nil {
+			var blob []byte
+			if *patch.PublicKey != "" {
+				if err := d.checkSize(*patch.PublicKey); err != nil {
+					return err
+				}
+				var err error
+				if blob, err = d.encrypt([]byte(*patch.PublicKey)); err != nil {
+					return err
+				}
+			}
+			field("public_key", blob)
+		}
+		if patch.ExpiresAt != nil {
+			var expires *int64
+			if !patch.ExpiresAt.IsZero() {
+				t := patch.ExpiresAt.Unix()
+				expires = &t
+			}
+			field("expires_at", expires)
+		}
+		if patch.Config != nil {
+			var cfg *string
+			if len(patch.Config) > 0 {
+				b, err := json.Marshal(patch.Config)
+				if err != nil {
+					return err
+				}
+				s := string(b)
+				cfg = &s
+			}
+			field("config", cfg)
+		}
+		if patch.RotationInterval != nil {
+			var interval *int64
+			if *patch.RotationInterval != 0 {
+				interval = intervalSeconds(patch.RotationInterval)
+			}
+			field("rotation_interval", interval)
+		}
+
+		res, err := tx.ExecContext(ctx, `UPDATE credentials SET `+strings.Join(set, ", ")+` WHERE name = ?`, append(args, name)...)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrNotFound
+		}
+
+		if patch.SecretKey != nil {
+			skip, err := d.setSecret(tx, SecretUpdate{Name: name, Secret: *patch.SecretKey}, now)
+			if err != nil {
+				return err
+			}
+			if skip != nil {
+				return skip
+			}
+		}
+		if patch.Tags != nil {
+			return storeTags(tx, name, patch.Tags)
+		}
+		return nil
+	})
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
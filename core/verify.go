@@ -0,0 +1,110 @@
+package core
+
+import (
+	"errors"
+)
+
+// IntegrityReport is the result of Verify. It names credentials but never
+// holds key material.
+type IntegrityReport struct {
+	// SQLiteProblems lists what PRAGMA integrity_check found; empty when
+	// it reported ok.
+	SQLiteProblems []string
+	// DigestOK reports whether the integrity HMAC matches the rows (see
+	// VerifyIntegrity).
+	DigestOK bool
+
+	Credentials int      // rows checked
+	Decrypted   int      // rows whose every stored key decrypted
+	Failed      []string // names of rows with a key that didn't, in name order
+
+	// OrphanedRotations counts rotation records whose credential no
+	// longer exists, which the rotations foreign key forbids.
+	OrphanedRotations int
+}
+
+// OK reports whether every check passed.
+func (r *IntegrityReport) OK() bool {
+	return len(r.SQLiteProblems) == 0 && r.DigestOK && len(r.Failed) == 0 && r.OrphanedRotations == 0
+}
+
+// Verify checks the whole vault: SQLCipher's integrity_check over every
+// page, the integrity HMAC, a trial decryption of every secret and public
+// key, and that every rotation record belongs to a credential. A
+// passphrase-protected secret is only checked up to its outer layer. The
+// error is for checks that couldn't run; what they find is in the report.
+func (d *Database) Verify() (*IntegrityReport, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return nil, err
+	}
+
+	r := &IntegrityReport{}
+	rows, err := d.db.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if line != "ok" {
+			r.SQLiteProblems = append(r.SQLiteProblems, line)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := d.verifyIntegrity(); err == nil {
+		r.DigestOK = true
+	} else if !errors.Is(err, ErrIntegrity) {
+		return nil, err
+	}
+
+	rows, err = d.db.Query(`SELECT name, api_key, public_key FROM credentials ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var secret, public []byte
+		if err := rows.Scan(&name, &secret, &public); err != nil {
+			return nil, err
+		}
+		r.Credentials++
+		ok := true
+		for _, blob := range [][]byte{secret, public} {
+			if len(blob) > 0 {
+				plain, err := d.decrypt(blob)
+				if err != nil {
+					ok = false
+				}
+				zero(plain)
+			}
+		}
+		if ok {
+			r.Decrypted++
+		} else {
+			r.Failed = append(r.Failed, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = d.db.QueryRow(
+		`SELECT COUNT(*) FROM rotations
+		 WHERE credential_name NOT IN (SELECT name FROM credentials)`,
+	).Scan(&r.OrphanedRotations)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
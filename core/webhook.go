@@ -0,0 +1,74 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Config rows for the rotation webhook (see rotation.Webhook). The signing
+// secret is only as protected as the rest of config: SQLCipher, no field
+// key layer.
+const (
+	webhookURLConfigKey    = "rotation_webhook_url"
+	webhookSecretConfigKey = "rotation_webhook_secret"
+)
+
+// RotationWebhook returns the URL rotations are reported to and the secret
+// their payloads are signed with; both are "" when none is set.
+func (d *Database) RotationWebhook() (webhookURL, secret string, err error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := d.use(); err != nil {
+		return "", "", err
+	}
+
+	get := func(key string) (string, error) {
+		var v []byte
+		err := d.db.QueryRow(`SELECT value FROM config WHERE key = ?`, key).Scan(&v)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return string(v), err
+	}
+	if webhookURL, err = get(webhookURLConfigKey); err != nil {
+		return "", "", err
+	}
+	if secret, err = get(webhookSecretConfigKey); err != nil {
+		return "", "", err
+	}
+	return webhookURL, secret, nil
+}
+
+// SetRotationWebhook stores the webhook URL and signing secret. The URL
+// must be http or https; an empty URL removes both.
+func (d *Database) SetRotationWebhook(webhookURL, secret string) error {
+	if webhookURL != "" {
+		u, err := url.Parse(webhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook URL must be an http or https URL, got %q", webhookURL)
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.use(); err != nil {
+		return err
+	}
+
+	return d.writeTx(func(tx *sql.Tx) error {
+		if webhookURL == "" {
+			_, err := tx.Exec(`DELETE FROM config WHERE key IN (?, ?)`, webhookURLConfigKey, webhookSecretConfigKey)
+			return err
+		}
+		for key, v := range map[string]string{webhookURLConfigKey: webhookURL, webhookSecretConfigKey: secret} {
+			if _, err := tx.Exec(`INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)`, key, []byte(v)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
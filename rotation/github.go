@@ -0,0 +1,160 @@
+package rotation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// githubPlugin rotates GitHub tokens issued by an OAuth app or GitHub App
+// (gho_/ghu_) with the "reset a token" API, which returns a new token and
+// invalidates the old one in the same call. GitHub has no API for creating
+// personal access tokens, classic or fine-grained, so those can't be
+// rotated here; Validate rejects them.
+type githubPlugin struct {
+	client *http.Client // nil: githubHTTP
+}
+
+const githubAPI = "https://api.github.com"
+
+var githubHTTP = &http.Client{Timeout: 30 * time.Second}
+
+func init() { GetGlobalRegistry().Register(&githubPlugin{}) }
+
+func (p *githubPlugin) Name() string                      { return "github" }
+func (p *githubPlugin) RotatableFields() []RotatableField { return []RotatableField{FieldSecretKey} }
+
+func (p *githubPlugin) Validate(cred CredentialInfo) error {
+	if cred.APIType != "github" {
+		return fmt.Errorf("expected api_type github, got %q", cred.APIType)
+	}
+	if cred.SecretKey == nil || *cred.SecretKey == "" {
+		return fmt.Errorf("github credential requires a secret key")
+	}
+	if strings.HasPrefix(*cred.SecretKey, "ghp_") || strings.HasPrefix(*cred.SecretKey, "github_pat_") {
+		return fmt.Errorf("github personal access tokens can't be rotated through the API; only OAuth and GitHub App user tokens can")
+	}
+	return nil
+}
+
+func (p *githubPlugin) ConfigSchema() ConfigSchema {
+	return ConfigSchema{Fields: []ConfigField{
+		{Name: "client_id", Description: "Client ID of the OAuth app or GitHub App that issued the token", Required: true},
+		{Name: "client_secret", Description: "Client secret of that app", Required: true, Secret: true},
+		{Name: "api_url", Description: "API base URL for GitHub Enterprise Server (default " + githubAPI + ")"},
+	}}
+}
+
+// githubToken is the part of the reset response the plugin uses.
+type githubToken struct {
+	ID    int64  `json:"id"`
+	Token string `json:"token"`
+}
+
+func (p *githubPlugin) Rotate(ctx context.Context, cred CredentialInfo, cfg Config) (*Result, error) {
+	clientID, err := configString(cfg, "client_id", true)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := configString(cfg, "client_secret", true)
+	if err != nil {
+		return nil, err
+	}
+	base, err := configString(cfg, "api_url", false)
+	if err != nil {
+		return nil, err
+	}
+	if base == "" {
+		base = githubAPI
+	}
+
+	body, _ := json.Marshal(map[string]string{"access_token": *cred.SecretKey})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch,
+		strings.TrimSuffix(base, "/")+"/applications/"+url.PathEscape(clientID)+"/token", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(clientID, secret)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	var tok githubToken
+	if err := p.do(req, &tok); err != nil {
+		return nil, fmt.Errorf("reset token: %w", err)
+	}
+	if tok.Token == "" {
+		return nil, fmt.Errorf("reset token: response has no token")
+	}
+	return &Result{
+		NewSecretKey:  &tok.Token,
+		KeyID:         strconv.FormatInt(tok.ID, 10),
+		OldKeyRevoked: true,
+		Metadata:      map[string]string{"client_id": clientID},
+	}, nil
+}
+
+// Verify checks the token authenticates against the API. A GitHub
+// Enterprise host comes from the credential's own api_url config.
+func (p *githubPlugin) Verify(ctx context.Context, cred CredentialInfo) error {
+	if cred.SecretKey == nil {
+		return fmt.Errorf("github credential has no secret key")
+	}
+	base := githubAPI
+	if v := cred.Config["api_url"]; v != "" {
+		base = v
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(base, "/")+"/user", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+*cred.SecretKey)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	return p.do(req, nil)
+}
+
+// do sends req and decodes a 2xx JSON response into out (if non-nil).
+func (p *githubPlugin) do(req *http.Request, out any) error {
+	client := p.client
+	if client == nil {
+		client = githubHTTP
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("github api: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// configString reads a string value from cfg.
+func configString(cfg Config, key string, required bool) (string, error) {
+	v, ok := cfg[key]
+	if !ok || v == nil {
+		if required {
+			return "", fmt.Errorf("missing config %q", key)
+		}
+		return "", nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("config %q must be a string, got %T", key, v)
+	}
+	if s == "" && required {
+		return "", fmt.Errorf("missing config %q", key)
+	}
+	return s, nil
+}
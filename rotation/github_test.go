@@ -0,0 +1,82 @@
+package rotation
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func stubResponse(status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Status: http.StatusText(status), Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestGitHubValidate(t *testing.T) {
+	p := &githubPlugin{}
+	tok := func(s string) *string { return &s }
+	for _, tc := range []struct {
+		name string
+		cred CredentialInfo
+		ok   bool
+	}{
+		{"app token", CredentialInfo{APIType: "github", SecretKey: tok("ghu_abc")}, true},
+		{"wrong type", CredentialInfo{APIType: "gitlab", SecretKey: tok("ghu_abc")}, false},
+		{"no secret", CredentialInfo{APIType: "github"}, false},
+		{"empty secret", CredentialInfo{APIType: "github", SecretKey: tok("")}, false},
+		{"classic pat", CredentialInfo{APIType: "github", SecretKey: tok("ghp_abc")}, false},
+		{"fine-grained pat", CredentialInfo{APIType: "github", SecretKey: tok("github_pat_abc")}, false},
+	} {
+		if err := p.Validate(tc.cred); (err == nil) != tc.ok {
+			t.Errorf("%s: Validate = %v, want ok=%v", tc.name, err, tc.ok)
+		}
+	}
+	if _, ok := GetGlobalRegistry().Get("github"); !ok {
+		t.Error("github plugin not registered")
+	}
+}
+
+func TestGitHubRotate(t *testing.T) {
+	var got *http.Request
+	var sent map[string]string
+	p := &githubPlugin{client: &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		got = r
+		json.NewDecoder(r.Body).Decode(&sent)
+		return stubResponse(200, `{"id": 42, "token": "ghu_new"}`), nil
+	})}}
+
+	old := "ghu_old"
+	cred := CredentialInfo{Name: "gh", APIType: "github", SecretKey: &old}
+	if _, err := p.Rotate(context.Background(), cred, Config{"client_id": "Iv1.abc"}); err == nil {
+		t.Fatal("Rotate without client_secret succeeded")
+	}
+	if got != nil {
+		t.Fatal("request sent despite missing config")
+	}
+
+	res, err := p.Rotate(context.Background(), cred, Config{"client_id": "Iv1.abc", "client_secret": "s3cret"})
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if *res.NewSecretKey != "ghu_new" || res.KeyID != "42" || !res.OldKeyRevoked {
+		t.Errorf("result = %+v", res)
+	}
+	if got.Method != http.MethodPatch || got.URL.String() != "https://api.github.com/applications/Iv1.abc/token" {
+		t.Errorf("request = %s %s", got.Method, got.URL)
+	}
+	if user, pass, _ := got.BasicAuth(); user != "Iv1.abc" || pass != "s3cret" || sent["access_token"] != "ghu_old" {
+		t.Errorf("auth %q:%q, body %v", user, pass, sent)
+	}
+
+	p.client.Transport = roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return stubResponse(404, `{"message": "Not Found"}`), nil
+	})
+	if _, err := p.Rotate(context.Background(), cred, Config{"client_id": "x", "client_secret": "y"}); err == nil || !strings.Contains(err.Error(), "Not Found") {
+		t.Errorf("Rotate on 404 = %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package rotation
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"time"
 )
@@ -16,23 +17,53 @@ const (
 )
 
 // CredentialInfo is the read-only view a plugin receives. No DB dependency.
+// Environment and Metadata let a plugin vary its behaviour, e.g. a staging
+// API host or a region; plugins that don't care can ignore them.
 type CredentialInfo struct {
-	Name      string
-	APIType   string
-	SecretKey *string
-	PublicKey *string
-	URL       *string
-	Config    map[string]string
+	Name        string
+	APIType     string
+	Environment string
+	SecretKey   *string
+	PublicKey   *string
+	URL         *string
+	URLs        []string
+	Config      map[string]string
+	Metadata    string
 }
 
 // Result carries rotation output back to the caller.
+//
+// What happens to the old key is spelled out rather than inferred from
+// OldKeyGrace alone:
+//   - OldKeyRevoked: the plugin already revoked it during Rotate.
+//   - RevokeOldKey: it is still live and should be revoked now; the caller
+//     invokes the plugin's Finalizer right after saving the new key.
+//   - OldKeyGrace > 0: it stays valid for the grace window.
 type Result struct {
-	NewSecretKey *string
-	NewPublicKey *string
-	NewURL       *string
-	KeyID        string
-	OldKeyGrace  time.Duration
-	Metadata     map[string]string
+	NewSecretKey  *string
+	NewPublicKey  *string
+	NewURL        *string
+	NewURLs       []string // for multi-endpoint services; replaces the list
+	KeyID         string
+	OldKeyGrace   time.Duration
+	OldKeyRevoked bool
+	RevokeOldKey  bool
+	Metadata      map[string]string
+}
+
+// Fields lists the fields r carries a new value for.
+func (r *Result) Fields() []RotatableField {
+	var fields []RotatableField
+	if r.NewSecretKey != nil {
+		fields = append(fields, FieldSecretKey)
+	}
+	if r.NewPublicKey != nil {
+		fields = append(fields, FieldPublicKey)
+	}
+	if r.NewURL != nil || len(r.NewURLs) > 0 {
+		fields = append(fields, FieldURL)
+	}
+	return fields
 }
 
 // Config is the per-rotation configuration passed to a plugin.
@@ -60,7 +91,36 @@ type Plugin interface {
 	ConfigSchema() ConfigSchema
 }
 
-// Registry holds registered rotation plugins keyed by API type.
+// Finalizer is implemented by plugins that revoke the old key as a separate
+// step. FinalizeRotation runs after the new key has been saved, so a failure
+// there never loses the new key.
+type Finalizer interface {
+	FinalizeRotation(ctx context.Context, cred CredentialInfo, result *Result) error
+}
+
+// FieldRotator is implemented by plugins that can rotate some of their
+// RotatableFields at the provider and leave the rest untouched there.
+// RotateFields must return new values only for fields; callers refuse a
+// result carrying anything else.
+type FieldRotator interface {
+	RotateFields(ctx context.Context, cred CredentialInfo, cfg Config, fields []RotatableField) (*Result, error)
+}
+
+// Verifier is implemented by plugins that can check a key works against the
+// provider, e.g. with a cheap authenticated request.
+type Verifier interface {
+	Verify(ctx context.Context, cred CredentialInfo) error
+}
+
+// Revoker is implemented by plugins that can kill a key at the provider,
+// used when a credential is deleted rather than rotated.
+type Revoker interface {
+	Revoke(ctx context.Context, cred CredentialInfo) error
+}
+
+// Registry holds registered rotation plugins keyed by API type. Keys are
+// normalized (trimmed, lowercased), so a credential typed "OpenAI" finds the
+// "openai" plugin.
 type Registry struct {
 	mu      sync.RWMutex
 	plugins map[string]Plugin
@@ -73,13 +133,21 @@ func NewRegistry() *Registry {
 func (r *Registry) Register(p Plugin) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.plugins[p.Name()] = p
+	r.plugins[normalize(p.Name())] = p
+}
+
+// Unregister removes a plugin. Mainly useful for tests that register a
+// TestPlugin on the global registry.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.plugins, normalize(name))
 }
 
 func (r *Registry) Get(name string) (Plugin, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	p, ok := r.plugins[name]
+	p, ok := r.plugins[normalize(name)]
 	return p, ok
 }
 
@@ -87,12 +155,16 @@ func (r *Registry) List() []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	names := make([]string, 0, len(r.plugins))
-	for k := range r.plugins {
-		names = append(names, k)
+	for _, p := range r.plugins {
+		names = append(names, p.Name())
 	}
 	return names
 }
 
+func normalize(apiType string) string {
+	return strings.ToLower(strings.TrimSpace(apiType))
+}
+
 var globalRegistry = NewRegistry()
 
 func GetGlobalRegistry() *Registry { return globalRegistry }
@@ -0,0 +1,108 @@
+package rotation
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTestPluginRotate(t *testing.T) {
+	secret := "new-secret"
+	p := NewTestPlugin("fake", &Result{NewSecretKey: &secret, KeyID: "k1"})
+
+	reg := NewRegistry()
+	reg.Register(p)
+	got, ok := reg.Get("fake")
+	if !ok {
+		t.Fatal("plugin not registered")
+	}
+
+	res, err := got.Rotate(context.Background(), CredentialInfo{Name: "svc"}, nil)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if res.NewSecretKey == nil || *res.NewSecretKey != secret || res.KeyID != "k1" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if fields := p.RotatableFields(); len(fields) != 1 || fields[0] != FieldSecretKey {
+		t.Fatalf("inferred fields = %v", fields)
+	}
+	if calls := p.Calls(); len(calls) != 1 || calls[0].Name != "svc" {
+		t.Fatalf("calls = %+v", calls)
+	}
+
+	reg.Unregister("fake")
+	if _, ok := reg.Get("fake"); ok {
+		t.Fatal("plugin still registered after Unregister")
+	}
+}
+
+func TestTestPluginRotateCopiesResult(t *testing.T) {
+	secret, public, u := "sk", "pk", "https://a.example.com"
+	p := NewTestPlugin("fake", &Result{
+		NewSecretKey: &secret, NewPublicKey: &public, NewURL: &u,
+		NewURLs:  []string{u},
+		Metadata: map[string]string{"k": "v"},
+	})
+
+	// Callers that edit a result, as RotateFields does, must not reach
+	// Result or the next call's copy.
+	res, err := p.Rotate(context.Background(), CredentialInfo{Name: "svc"}, nil)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	*res.NewSecretKey, *res.NewPublicKey, *res.NewURL = "x", "x", "x"
+	res.NewURLs[0] = "x"
+	res.Metadata["k"] = "x"
+
+	if _, err := p.RotateFields(context.Background(), CredentialInfo{Name: "svc"}, nil, []RotatableField{FieldPublicKey}); err != nil {
+		t.Fatalf("RotateFields: %v", err)
+	}
+	again, err := p.Rotate(context.Background(), CredentialInfo{Name: "svc"}, nil)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	for _, r := range []*Result{p.Result, again} {
+		if *r.NewSecretKey != "sk" || *r.NewPublicKey != "pk" || *r.NewURL != u || r.NewURLs[0] != u || r.Metadata["k"] != "v" {
+			t.Fatalf("result changed through a copy: %+v", r)
+		}
+	}
+}
+
+func TestTestPluginError(t *testing.T) {
+	boom := errors.New("boom")
+	p := &TestPlugin{PluginName: "fake", RotateErr: boom}
+
+	if _, err := p.Rotate(context.Background(), CredentialInfo{}, nil); !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+}
+
+func TestRegistryLookupIgnoresCase(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(&TestPlugin{PluginName: "openai"})
+
+	for _, name := range []string{"openai", "OpenAI", " OPENAI "} {
+		if _, ok := reg.Get(name); !ok {
+			t.Errorf("Get(%q) found nothing", name)
+		}
+	}
+	if names := reg.List(); len(names) != 1 || names[0] != "openai" {
+		t.Fatalf("List() = %v, want [openai]", names)
+	}
+}
+
+func TestSupabaseRotateFields(t *testing.T) {
+	p, _ := GetGlobalRegistry().Get("supabase")
+	fr, ok := p.(FieldRotator)
+	if !ok {
+		t.Fatal("supabase plugin should rotate fields independently")
+	}
+	res, err := fr.RotateFields(context.Background(), CredentialInfo{Name: "svc"}, nil, []RotatableField{FieldPublicKey})
+	if err != nil {
+		t.Fatalf("RotateFields: %v", err)
+	}
+	if got := res.Fields(); len(got) != 1 || got[0] != FieldPublicKey {
+		t.Fatalf("fields = %v, want [public_key]", got)
+	}
+}
@@ -3,6 +3,7 @@ package rotation
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 )
 
@@ -36,15 +37,26 @@ func (p *supabasePlugin) ConfigSchema() ConfigSchema {
 	}}
 }
 
-func (p *supabasePlugin) Rotate(_ context.Context, cred CredentialInfo, _ Config) (*Result, error) {
+func (p *supabasePlugin) Rotate(ctx context.Context, cred CredentialInfo, cfg Config) (*Result, error) {
+	return p.RotateFields(ctx, cred, cfg, p.RotatableFields())
+}
+
+// RotateFields rotates the service role (secret) and anon (public) keys
+// independently.
+func (p *supabasePlugin) RotateFields(_ context.Context, cred CredentialInfo, _ Config, fields []RotatableField) (*Result, error) {
 	// Stub: real implementation would call Supabase management API
-	newSecret := "sbp_rotated-stub-" + cred.Name
-	newPublic := "eyJ-rotated-stub-" + cred.Name
-	return &Result{
-		NewSecretKey: &newSecret,
-		NewPublicKey: &newPublic,
-		KeyID:        "supa-" + cred.Name,
-		OldKeyGrace:  2 * time.Minute,
-		Metadata:     map[string]string{"stub": "true"},
-	}, nil
+	res := &Result{
+		KeyID:       "supa-" + cred.Name,
+		OldKeyGrace: 2 * time.Minute,
+		Metadata:    map[string]string{"stub": "true"},
+	}
+	if slices.Contains(fields, FieldSecretKey) {
+		newSecret := "sbp_rotated-stub-" + cred.Name
+		res.NewSecretKey = &newSecret
+	}
+	if slices.Contains(fields, FieldPublicKey) {
+		newPublic := "eyJ-rotated-stub-" + cred.Name
+		res.NewPublicKey = &newPublic
+	}
+	return res, nil
 }
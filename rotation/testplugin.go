@@ -0,0 +1,149 @@
+package rotation
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// TestPlugin is a deterministic Plugin for exercising the rotation path in
+// tests. It never touches the network: Rotate returns a deep copy of Result (or
+// RotateErr) and records every credential and config it was called with.
+// RotateFields returns only the requested fields of Result.
+type TestPlugin struct {
+	PluginName  string
+	Fields      []RotatableField
+	Result      *Result
+	RotateErr   error
+	ValidateErr error
+	FinalizeErr error
+	VerifyErr   error
+	RevokeErr   error
+	Schema      ConfigSchema
+
+	// UnrequestedFields makes RotateFields return all of Result rather
+	// than only the requested fields, as a misbehaving FieldRotator would.
+	UnrequestedFields bool
+
+	mu        sync.Mutex
+	calls     []CredentialInfo
+	configs   []Config
+	requested [][]RotatableField
+	finalized int
+}
+
+// NewTestPlugin returns a TestPlugin registered under name that yields result.
+func NewTestPlugin(name string, result *Result) *TestPlugin {
+	return &TestPlugin{PluginName: name, Result: result}
+}
+
+func (p *TestPlugin) Name() string { return p.PluginName }
+
+// RotatableFields returns Fields, or infers them from Result when unset.
+func (p *TestPlugin) RotatableFields() []RotatableField {
+	if p.Fields != nil || p.Result == nil {
+		return p.Fields
+	}
+	return p.Result.Fields()
+}
+
+func (p *TestPlugin) Validate(CredentialInfo) error { return p.ValidateErr }
+func (p *TestPlugin) ConfigSchema() ConfigSchema    { return p.Schema }
+
+func (p *TestPlugin) Rotate(ctx context.Context, cred CredentialInfo, cfg Config) (*Result, error) {
+	p.mu.Lock()
+	p.calls = append(p.calls, cred)
+	p.configs = append(p.configs, cfg)
+	p.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if p.RotateErr != nil {
+		return nil, p.RotateErr
+	}
+	if p.Result == nil {
+		return &Result{}, nil
+	}
+	res := *p.Result
+	res.NewSecretKey = clonePtr(res.NewSecretKey)
+	res.NewPublicKey = clonePtr(res.NewPublicKey)
+	res.NewURL = clonePtr(res.NewURL)
+	res.NewURLs = slices.Clone(res.NewURLs)
+	res.Metadata = maps.Clone(res.Metadata)
+	return &res, nil
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// RotateFields is Rotate limited to fields; see UnrequestedFields.
+func (p *TestPlugin) RotateFields(ctx context.Context, cred CredentialInfo, cfg Config, fields []RotatableField) (*Result, error) {
+	p.mu.Lock()
+	p.requested = append(p.requested, fields)
+	p.mu.Unlock()
+
+	res, err := p.Rotate(ctx, cred, cfg)
+	if err != nil || p.UnrequestedFields {
+		return res, err
+	}
+	if !slices.Contains(fields, FieldSecretKey) {
+		res.NewSecretKey = nil
+	}
+	if !slices.Contains(fields, FieldPublicKey) {
+		res.NewPublicKey = nil
+	}
+	if !slices.Contains(fields, FieldURL) {
+		res.NewURL, res.NewURLs = nil, nil
+	}
+	return res, nil
+}
+
+// FinalizeRotation counts the call and returns FinalizeErr.
+func (p *TestPlugin) FinalizeRotation(ctx context.Context, _ CredentialInfo, _ *Result) error {
+	p.mu.Lock()
+	p.finalized++
+	p.mu.Unlock()
+	return p.FinalizeErr
+}
+
+// Verify returns VerifyErr.
+func (p *TestPlugin) Verify(context.Context, CredentialInfo) error { return p.VerifyErr }
+
+// Revoke returns RevokeErr.
+func (p *TestPlugin) Revoke(context.Context, CredentialInfo) error { return p.RevokeErr }
+
+// Finalized returns how many times FinalizeRotation has been called.
+func (p *TestPlugin) Finalized() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.finalized
+}
+
+// Calls returns the credentials Rotate has been invoked with, in order.
+func (p *TestPlugin) Calls() []CredentialInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]CredentialInfo(nil), p.calls...)
+}
+
+// RequestedFields returns the fields passed to each RotateFields call, in
+// order.
+func (p *TestPlugin) RequestedFields() [][]RotatableField {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([][]RotatableField(nil), p.requested...)
+}
+
+// Configs returns the Config passed to each Rotate call, in order.
+func (p *TestPlugin) Configs() []Config {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Config(nil), p.configs...)
+}
@@ -0,0 +1,122 @@
+package rotation
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WebhookEvent is the JSON body a Webhook posts after a rotation. It names
+// the credential and what changed, never a key.
+type WebhookEvent struct {
+	Event         string    `json:"event"` // always "rotation"
+	Credential    string    `json:"credential"`
+	APIType       string    `json:"api_type"`
+	Plugin        string    `json:"plugin"`
+	RotatedFields []string  `json:"rotated_fields"`
+	RotatedBy     string    `json:"rotated_by"`
+	Timestamp     time.Time `json:"timestamp"`
+
+	// OldKey is what became of the replaced key: OldKeyRevoked,
+	// OldKeyRevokeFailed, OldKeyGrace or OldKeyActive.
+	OldKey string `json:"old_key"`
+}
+
+// WebhookEvent.OldKey values.
+const (
+	OldKeyRevoked      = "revoked"       // by the plugin or its Finalizer
+	OldKeyRevokeFailed = "revoke_failed" // the Finalizer returned an error
+	OldKeyGrace        = "grace"         // valid until the provider's grace period ends
+	OldKeyActive       = "active"        // still valid; revoke it by hand
+)
+
+// WebhookSignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the
+// request body under the webhook's secret. Receivers should recompute it
+// and compare in constant time; the event's timestamp lets them reject
+// replays.
+const WebhookSignatureHeader = "X-API-Vault-Signature"
+
+// Webhook posts rotation events to URL. Network errors, 429 and 5xx
+// responses are retried up to Attempts times in all, waiting Backoff,
+// then twice that, and so on; other responses fail at once.
+type Webhook struct {
+	URL      string
+	Secret   string       // HMAC key for WebhookSignatureHeader; "" sends no signature
+	Client   *http.Client // nil: webhookHTTP
+	Attempts int          // 0: 3
+	Backoff  time.Duration
+}
+
+var webhookHTTP = &http.Client{Timeout: 10 * time.Second}
+
+// Notify posts ev, retrying as described on Webhook. It gives up early when
+// ctx ends.
+func (w *Webhook) Notify(ctx context.Context, ev WebhookEvent) error {
+	ev.Event = "rotation"
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	client := w.Client
+	if client == nil {
+		client = webhookHTTP
+	}
+	attempts := w.Attempts
+	if attempts < 1 {
+		attempts = 3
+	}
+
+	wait := w.Backoff
+	for i := 1; ; i++ {
+		retry, err := w.post(ctx, client, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || i >= attempts {
+			return fmt.Errorf("webhook: %w (attempt %d of %d)", err, i, attempts)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("webhook: %w", ctx.Err())
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// post sends body once, reporting whether a failure is worth retrying.
+func (w *Webhook) post(ctx context.Context, client *http.Client, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(w.Secret, body))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+}
+
+// SignWebhook returns the WebhookSignatureHeader value for body.
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
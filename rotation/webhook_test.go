@@ -0,0 +1,53 @@
+package rotation
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotify(t *testing.T) {
+	var bodies []string
+	statuses := []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusOK}
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(r.Body)
+		if got, want := r.Header.Get(WebhookSignatureHeader), SignWebhook("s3cret", body); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		bodies = append(bodies, string(body))
+		return stubResponse(statuses[len(bodies)-1], ""), nil
+	})}
+
+	w := &Webhook{URL: "https://hooks.example.com/x", Secret: "s3cret", Client: client, Backoff: time.Millisecond}
+	ev := WebhookEvent{Credential: "svc", APIType: "github", Plugin: "github", RotatedFields: []string{"secret_key"}, RotatedBy: "ci", Timestamp: time.Unix(1700000000, 0).UTC()}
+	if err := w.Notify(context.Background(), ev); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(bodies) != 3 {
+		t.Fatalf("%d attempts, want 3 (two retried failures)", len(bodies))
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(bodies[2]), &got); err != nil {
+		t.Fatalf("body: %v", err)
+	}
+	if got["event"] != "rotation" || got["credential"] != "svc" || got["timestamp"] != "2023-11-14T22:13:20Z" {
+		t.Fatalf("body = %s", bodies[2])
+	}
+
+	// A 4xx other than 429 isn't retried.
+	bodies, statuses = nil, []int{http.StatusBadRequest, http.StatusOK}
+	if err := w.Notify(context.Background(), ev); err == nil || len(bodies) != 1 {
+		t.Fatalf("400: err %v after %d attempts, want an error after 1", err, len(bodies))
+	}
+
+	// Attempts caps the retries.
+	bodies, statuses = nil, []int{502, 502, 502, 502}
+	w.Attempts = 2
+	if err := w.Notify(context.Background(), ev); err == nil || !strings.Contains(err.Error(), "attempt 2 of 2") {
+		t.Fatalf("502s: %v", err)
+	}
+}
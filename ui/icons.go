@@ -0,0 +1,35 @@
+package ui
+
+import "strings"
+
+// DefaultIcon is shown for api_types without a known icon.
+const DefaultIcon = "🔑"
+
+// providerIcons maps api_type to the icon shown next to it in interactive
+// mode. Keys are lowercase.
+var providerIcons = map[string]string{
+	"openai":     "🤖",
+	"anthropic":  "🧠",
+	"stripe":     "💳",
+	"github":     "🐙",
+	"gitlab":     "🦊",
+	"supabase":   "⚡",
+	"aws":        "☁️",
+	"gcp":        "☁️",
+	"azure":      "☁️",
+	"cloudflare": "🌩️",
+	"slack":      "💬",
+	"twilio":     "📞",
+	"sendgrid":   "📧",
+	"resend":     "📧",
+	"postgres":   "🐘",
+	"database":   "🗄️",
+}
+
+// Icon returns the icon for apiType, or DefaultIcon if there isn't one.
+func Icon(apiType string) string {
+	if icon, ok := providerIcons[strings.ToLower(apiType)]; ok {
+		return icon
+	}
+	return DefaultIcon
+}